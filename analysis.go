@@ -5,45 +5,81 @@ import (
 	"strings"
 )
 
-// progressPrint prints progress messages, suppressed in report mode
-func progressPrint(format string, args ...interface{}) {
-	if !reportMode {
+// progress prints progress messages, suppressed in report mode and for quiet analyses
+func (a *ClusterAnalysis) progress(format string, args ...interface{}) {
+	if !reportMode && !a.quiet {
 		fmt.Printf(format, args...)
 	}
 }
 
+// parseClusterNodes extracts the node hosts from a gcomm:// cluster address
+func parseClusterNodes(clusterAddress string) []string {
+	if clusterAddress == "" {
+		return nil
+	}
+	address, err := parseGcommAddress(clusterAddress)
+	if err != nil {
+		logVerbose("Warning: %v", err)
+		return nil
+	}
+	return address.hosts()
+}
+
+// discoverClusterNodes connects to the initial node and returns the nodes listed in its gcomm:// address
+func discoverClusterNodes(config *Config) ([]string, error) {
+	nodeIP := promptUnlessGiven(cliOptions.Node, "Enter the Galera cluster node IP", config.LastNodeIP)
+	if nodeIP == "" {
+		return nil, fmt.Errorf("node IP is required")
+	}
+
+	var info *GaleraClusterInfo
+	var err error
+	if isLocalhost(nodeIP) {
+		info, err = getGaleraClusterInfoLocal(nodeIP)
+	} else {
+		executor, connErr := getNodeExecutor(nodeIP, config)
+		if connErr != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %v", nodeIP, connErr)
+		}
+		info, err = getGaleraClusterInfo(executor, nodeIP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain cluster information from %s: %v", nodeIP, err)
+	}
+
+	nodes := parseClusterNodes(info.ClusterAddress)
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no cluster nodes found in wsrep_cluster_address")
+	}
+
+	config.LastNodeIP = nodeIP
+	return nodes, nil
+}
+
 // performClusterAnalysis analyzes cluster coherence across all nodes
 func performClusterAnalysis(initialNode *GaleraClusterInfo, connInfo *SSHConnectionInfo, config *Config) (*ClusterAnalysis, string, error) {
+	return runClusterAnalysis(initialNode, connInfo, config, false)
+}
+
+// runClusterAnalysis is performClusterAnalysis without the per-node progress output when quiet is set
+func runClusterAnalysis(initialNode *GaleraClusterInfo, connInfo *SSHConnectionInfo, config *Config, quiet bool) (*ClusterAnalysis, string, error) {
 	analysis := &ClusterAnalysis{
 		InitialNode:  initialNode,
 		AllNodes:     []*GaleraClusterInfo{initialNode},
 		ConfigErrors: []string{},
 		IsCoherent:   true,
+		MaxClockSkew: getMaxClockSkew(config),
+		quiet:        quiet,
 	}
 
 	// Extract cluster nodes from wsrep_cluster_address
-	if initialNode.ClusterAddress != "" && strings.Contains(initialNode.ClusterAddress, "gcomm://") {
-		addresses := strings.TrimPrefix(initialNode.ClusterAddress, "gcomm://")
-		if addresses != "" {
-			nodes := strings.Split(addresses, ",")
-			for _, node := range nodes {
-				node = strings.TrimSpace(node)
-				if node != "" {
-					// Remove port if present (e.g., 192.168.1.100:4567 -> 192.168.1.100)
-					if colonIndex := strings.Index(node, ":"); colonIndex != -1 {
-						node = node[:colonIndex]
-					}
-					analysis.ClusterNodes = append(analysis.ClusterNodes, node)
-				}
-			}
-		}
-	}
+	analysis.ClusterNodes = parseClusterNodes(initialNode.ClusterAddress)
 
 	if len(analysis.ClusterNodes) == 0 {
 		return nil, "", fmt.Errorf("no cluster nodes found in wsrep_cluster_address")
 	}
 
-	progressPrint("📋 Found %d nodes in cluster configuration\n", len(analysis.ClusterNodes))
+	analysis.progress("📋 Found %d nodes in cluster configuration\n", len(analysis.ClusterNodes))
 
 	// If initial connection was localhost, try to identify which cluster node represents localhost
 	var localhostNodeIP string
@@ -53,134 +89,166 @@ func performClusterAnalysis(initialNode *GaleraClusterInfo, connInfo *SSHConnect
 		logVerbose("🏠 Identified localhost as %s (from wsrep_node_address)", localhostNodeIP)
 	}
 
+	// The running cluster's membership, as seen by the initial node
+	var initialExecutor NodeExecutor = &LocalExecutor{}
+	if !isLocalhost(initialNode.NodeIP) {
+		if executor, err := getNodeExecutor(initialNode.NodeIP, config); err == nil {
+			initialExecutor = executor
+		} else {
+			initialExecutor = nil
+		}
+	}
+	if initialExecutor != nil {
+		queryRuntimeMembership(initialExecutor, initialNode.NodeIP, config, initialNode)
+		initialNode.Clock = checkNodeClock(initialExecutor, initialNode.NodeIP)
+		initialNode.Resources = collectHostResources(initialExecutor, initialNode)
+		initialNode.Versions = collectNodeVersions(initialExecutor, initialNode.NodeIP)
+	}
+
 	// Check each node in the cluster
 	for i, nodeIP := range analysis.ClusterNodes {
-		if nodeIP == initialNode.NodeIP || isLocalhost(nodeIP) || nodeIP == localhostNodeIP {
+		isLocalNode := localhostNodeIP != "" && sameHost(nodeIP, localhostNodeIP)
+		if sameHost(nodeIP, initialNode.NodeIP) || isLocalhost(nodeIP) || isLocalNode {
 			// Skip initial node (already analyzed), localhost references, or identified localhost IP
-			if nodeIP == initialNode.NodeIP {
-				progressPrint("   %d. %s (initial node - already analyzed)\n", i+1, nodeIP)
+			if sameHost(nodeIP, initialNode.NodeIP) {
+				analysis.progress("   %d. %s (initial node - already analyzed)\n", i+1, nodeIP)
 			} else if isLocalhost(nodeIP) {
-				progressPrint("   %d. %s (localhost - skipping SSH)\n", i+1, nodeIP)
-			} else if nodeIP == localhostNodeIP {
-				progressPrint("   %d. %s (this is localhost %s - already analyzed)\n", i+1, nodeIP, initialNode.NodeIP)
+				analysis.progress("   %d. %s (localhost - skipping SSH)\n", i+1, nodeIP)
+			} else if isLocalNode {
+				analysis.progress("   %d. %s (this is localhost %s - already analyzed)\n", i+1, nodeIP, initialNode.NodeIP)
 			}
 			continue
 		}
 
-		progressPrint("   %d. %s - connecting...\n", i+1, nodeIP)
+		analyzeClusterNode(analysis, i, nodeIP, connInfo, config)
+	}
 
-		// Check if we have valid SSH connection info
-		var sshClient *SSHClient
-		var err error
+	// Nodes in the running cluster but missing from wsrep_cluster_address
+	skip := []string{initialNode.NodeIP, localhostNodeIP}
+	if extra := analysis.unconfiguredMembers(skip); len(extra) > 0 {
+		analysis.progress("🔎 Found %d more nodes in the running cluster (wsrep_incoming_addresses)\n", len(extra))
+		for i, nodeIP := range extra {
+			analyzeClusterNode(analysis, len(analysis.ClusterNodes)+i, nodeIP, connInfo, config)
+		}
+	}
+	analysis.Membership = analysis.compareMembership(skip)
 
-		if connInfo.Username == "local" {
-			// Initial connection was localhost, but we can still connect to remote nodes via SSH
-			logVerbose("      🌐 Initial node is localhost, attempting SSH connection to remote node %s", nodeIP)
-			var newConnInfo *SSHConnectionInfo
-			sshClient, newConnInfo, err = createSSHConnectionWithNodeCredentials(nodeIP, config)
-			if err != nil {
-				// Create a node info with error to include in analysis
-				nodeInfo := &GaleraClusterInfo{
-					NodeIP:      nodeIP,
-					StatusError: fmt.Sprintf("SSH connection failed: %v", err),
-				}
-				analysis.AllNodes = append(analysis.AllNodes, nodeInfo)
-				analysis.ConfigErrors = append(analysis.ConfigErrors, fmt.Sprintf("Failed to connect to remote node %s: %v", nodeIP, err))
-				analysis.IsCoherent = false
-				progressPrint("      ❌ SSH connection failed: %v\n", err)
-				continue
-			}
+	// Analyze configuration coherence
+	analysis.analyzeCoherence()
 
-			// Save the new connection info for this remote node
-			if newConnInfo != nil {
-				sshPassword := ""
-				if newConnInfo.HasPassword {
-					sshPassword = newConnInfo.Password
-				}
-				err = config.setNodeCredentials(nodeIP, newConnInfo.Username, "", sshPassword, "", newConnInfo.UsedKeys)
-				if err != nil {
-					progressPrint("      ⚠️  Warning: Could not save credentials for node %s: %v\n", nodeIP, err)
-				} else {
-					if newConnInfo.HasPassword {
-						progressPrint("      ✓ SSH password saved for node %s\n", nodeIP)
-					}
-					progressPrint("      ✓ SSH credentials saved for node %s\n", nodeIP)
-				}
-			}
-		} else {
-			// Use per-node credentials for connection
-			var newConnInfo *SSHConnectionInfo
-			sshClient, newConnInfo, err = createSSHConnectionWithNodeCredentials(nodeIP, config)
-			if err != nil {
-				// Create a node info with error to include in analysis
-				nodeInfo := &GaleraClusterInfo{
-					NodeIP:      nodeIP,
-					StatusError: fmt.Sprintf("SSH connection failed: %v", err),
-				}
-				analysis.AllNodes = append(analysis.AllNodes, nodeInfo)
-				analysis.ConfigErrors = append(analysis.ConfigErrors, fmt.Sprintf("Failed to connect to node %s: %v", nodeIP, err))
-				analysis.IsCoherent = false
-				progressPrint("      ❌ Connection failed: %v\n", err)
-				continue
-			}
+	return analysis, localhostNodeIP, nil
+}
 
-			// Save the new connection info for this node if we got new credentials
-			if newConnInfo != nil {
-				sshPassword := ""
-				if newConnInfo.HasPassword {
-					sshPassword = newConnInfo.Password
-				}
-				err = config.setNodeCredentials(nodeIP, newConnInfo.Username, "", sshPassword, "", newConnInfo.UsedKeys)
-				if err != nil {
-					progressPrint("      ⚠️  Warning: Could not save credentials for node %s: %v\n", nodeIP, err)
-				} else {
-					if newConnInfo.HasPassword {
-						progressPrint("      ✓ SSH password saved for node %s\n", nodeIP)
-					}
-					progressPrint("      ✓ SSH credentials saved for node %s\n", nodeIP)
-				}
-			}
-		}
+// analyzeClusterNode connects to a node and adds its configuration, or the connection error, to the analysis
+func analyzeClusterNode(analysis *ClusterAnalysis, i int, nodeIP string, connInfo *SSHConnectionInfo, config *Config) {
+	analysis.progress("   %d. %s - connecting...\n", i+1, nodeIP)
+
+	// Check if we have valid SSH connection info
+	var executor NodeExecutor
+	var err error
 
-		// Verify we have a valid SSH client
-		if sshClient == nil {
+	if connInfo.Username == "local" {
+		// Initial connection was localhost, but we can still connect to remote nodes via SSH
+		logVerbose("      🌐 Initial node is localhost, attempting SSH connection to remote node %s", nodeIP)
+		var newConnInfo *SSHConnectionInfo
+		executor, newConnInfo, err = openNodeExecutor(nodeIP, config)
+		if err != nil {
 			// Create a node info with error to include in analysis
 			nodeInfo := &GaleraClusterInfo{
 				NodeIP:      nodeIP,
-				StatusError: "SSH client is nil - connection failed",
+				StatusError: fmt.Sprintf("SSH connection failed: %v", err),
 			}
 			analysis.AllNodes = append(analysis.AllNodes, nodeInfo)
-			analysis.ConfigErrors = append(analysis.ConfigErrors, fmt.Sprintf("SSH client is nil for node %s", nodeIP))
+			analysis.ConfigErrors = append(analysis.ConfigErrors, fmt.Sprintf("Failed to connect to remote node %s: %v", nodeIP, err))
 			analysis.IsCoherent = false
-			progressPrint("      ❌ SSH client is nil\n")
-			continue
+			analysis.progress("      ❌ SSH connection failed: %v\n", err)
+			return
 		}
 
-		// Get cluster info from this node
-		nodeInfo, err := getGaleraClusterInfo(sshClient, nodeIP)
-		sshClient.Close()
-
+		// Save the new connection info for this remote node
+		if newConnInfo != nil {
+			savedPassword, err := config.saveSSHConnection(nodeIP, newConnInfo)
+			if err != nil {
+				analysis.progress("      ⚠️  Warning: Could not save credentials for node %s: %v\n", nodeIP, err)
+			} else {
+				if savedPassword {
+					analysis.progress("      ✓ SSH password saved for node %s\n", nodeIP)
+				}
+				analysis.progress("      ✓ SSH credentials saved for node %s\n", nodeIP)
+				config.markCredentialsUsed(nodeIP, credentialSSH)
+			}
+		}
+	} else {
+		// Use per-node credentials for connection
+		var newConnInfo *SSHConnectionInfo
+		executor, newConnInfo, err = openNodeExecutor(nodeIP, config)
 		if err != nil {
 			// Create a node info with error to include in analysis
-			nodeInfo = &GaleraClusterInfo{
+			nodeInfo := &GaleraClusterInfo{
 				NodeIP:      nodeIP,
-				StatusError: fmt.Sprintf("Failed to get cluster info: %v", err),
+				StatusError: fmt.Sprintf("SSH connection failed: %v", err),
 			}
 			analysis.AllNodes = append(analysis.AllNodes, nodeInfo)
-			analysis.ConfigErrors = append(analysis.ConfigErrors, fmt.Sprintf("Failed to get cluster info from node %s: %v", nodeIP, err))
+			analysis.ConfigErrors = append(analysis.ConfigErrors, fmt.Sprintf("Failed to connect to node %s: %v", nodeIP, err))
 			analysis.IsCoherent = false
-			progressPrint("      ❌ Failed to get cluster info: %v\n", err)
-			continue
+			analysis.progress("      ❌ Connection failed: %v\n", err)
+			return
 		}
 
+		// Save the new connection info for this node if we got new credentials
+		if newConnInfo != nil {
+			savedPassword, err := config.saveSSHConnection(nodeIP, newConnInfo)
+			if err != nil {
+				analysis.progress("      ⚠️  Warning: Could not save credentials for node %s: %v\n", nodeIP, err)
+			} else {
+				if savedPassword {
+					analysis.progress("      ✓ SSH password saved for node %s\n", nodeIP)
+				}
+				analysis.progress("      ✓ SSH credentials saved for node %s\n", nodeIP)
+				config.markCredentialsUsed(nodeIP, credentialSSH)
+			}
+		}
+	}
+
+	// Verify we have a valid SSH client
+	if executor == nil {
+		// Create a node info with error to include in analysis
+		nodeInfo := &GaleraClusterInfo{
+			NodeIP:      nodeIP,
+			StatusError: "SSH client is nil - connection failed",
+		}
 		analysis.AllNodes = append(analysis.AllNodes, nodeInfo)
-		progressPrint("      ✓ Configuration retrieved\n")
+		analysis.ConfigErrors = append(analysis.ConfigErrors, fmt.Sprintf("SSH client is nil for node %s", nodeIP))
+		analysis.IsCoherent = false
+		analysis.progress("      ❌ SSH client is nil\n")
+		return
 	}
 
-	// Analyze configuration coherence
-	analysis.analyzeCoherence()
+	// Get cluster info from this node
+	nodeInfo, err := getGaleraClusterInfo(executor, nodeIP)
 
-	return analysis, localhostNodeIP, nil
+	if err != nil {
+		// Create a node info with error to include in analysis
+		nodeInfo = &GaleraClusterInfo{
+			NodeIP:      nodeIP,
+			StatusError: fmt.Sprintf("Failed to get cluster info: %v", err),
+		}
+		analysis.AllNodes = append(analysis.AllNodes, nodeInfo)
+		analysis.ConfigErrors = append(analysis.ConfigErrors, fmt.Sprintf("Failed to get cluster info from node %s: %v", nodeIP, err))
+		analysis.IsCoherent = false
+		analysis.progress("      ❌ Failed to get cluster info: %v\n", err)
+		return
+	}
+
+	queryRuntimeMembership(executor, nodeIP, config, nodeInfo)
+	nodeInfo.Clock = checkNodeClock(executor, nodeIP)
+	if analysis.mergeDuplicateNode(nodeIP, nodeInfo) {
+		return
+	}
+	nodeInfo.Resources = collectHostResources(executor, nodeInfo)
+	nodeInfo.Versions = collectNodeVersions(executor, nodeIP)
+	analysis.AllNodes = append(analysis.AllNodes, nodeInfo)
+	analysis.progress("      ✓ Configuration retrieved\n")
 }
 
 // analyzeCoherence analyzes the coherence of cluster configuration across nodes
@@ -200,11 +268,11 @@ func (a *ClusterAnalysis) analyzeCoherence() {
 			a.IsCoherent = false
 		}
 
-		// Check cluster address consistency
-		if node.ClusterAddress != reference.ClusterAddress {
+		// Check cluster address consistency: same members, whatever the order or host spelling
+		if difference := compareClusterAddresses(node.ClusterAddress, reference.ClusterAddress); difference != "" {
 			a.ConfigErrors = append(a.ConfigErrors,
-				fmt.Sprintf("Node %s has different cluster address: '%s' vs '%s'",
-					node.NodeIP, node.ClusterAddress, reference.ClusterAddress))
+				fmt.Sprintf("Node %s has different cluster address: '%s' vs '%s' (%s)",
+					node.NodeIP, node.ClusterAddress, reference.ClusterAddress, difference))
 			a.IsCoherent = false
 		}
 
@@ -218,44 +286,94 @@ func (a *ClusterAnalysis) analyzeCoherence() {
 	}
 }
 
-// checkMySQLStatusOnAllNodes checks MySQL/MariaDB status on all nodes in the analysis
+// compareClusterAddresses compares two wsrep_cluster_address values as member sets and describes
+// the difference, or returns "" when they list the same nodes
+func compareClusterAddresses(address, reference string) string {
+	if address == reference {
+		return ""
+	}
+	parsed, err := parseGcommAddress(address)
+	if err != nil {
+		return err.Error()
+	}
+	parsedReference, err := parseGcommAddress(reference)
+	if err != nil {
+		return err.Error()
+	}
+
+	onlyNode, onlyReference := compareGcommMembers(parsed, parsedReference)
+	var parts []string
+	if len(onlyNode) > 0 {
+		parts = append(parts, "extra: "+strings.Join(onlyNode, ", "))
+	}
+	if len(onlyReference) > 0 {
+		parts = append(parts, "missing: "+strings.Join(onlyReference, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// checkMySQLStatusOnAllNodes checks MySQL/MariaDB status on all nodes in the analysis. Each node's own
+// credentials are tried first, then mysqlCreds (the cluster default), then the node's ~/.my.cnf.
 func checkMySQLStatusOnAllNodes(analysis *ClusterAnalysis, connInfo *SSHConnectionInfo, mysqlCreds *MySQLConnectionInfo, config *Config, localhostNodeIP string) error {
 	for i, node := range analysis.AllNodes {
-		progressPrint("   %d. %s - checking MySQL status...\n", i+1, node.NodeIP)
+		analysis.progress("   %d. %s - checking MySQL status...\n", i+1, node.NodeIP)
 
 		// Skip nodes that already have connection errors
 		if node.StatusError != "" && strings.Contains(node.StatusError, "SSH connection failed") {
-			progressPrint("      ❌ Skipping MySQL check due to SSH connection failure: %s\n", node.StatusError)
+			analysis.progress("      ❌ Skipping MySQL check due to SSH connection failure: %s\n", node.StatusError)
 			continue
 		}
 
 		// Check if this is localhost - use direct access instead of SSH
 		// Consider both localhost references and the identified localhost IP
+		var executor NodeExecutor
+		var service *ServiceBackend
 		if isLocalhost(node.NodeIP) || node.NodeIP == localhostNodeIP {
-			progressPrint("      🏠 Using local MySQL connection for localhost\n")
-			// Use nil SSH client for localhost - checkMySQLStatus will handle this
-			checkMySQLStatus(nil, node.NodeIP, mysqlCreds, node)
+			analysis.progress("      🏠 Using local MySQL connection for localhost\n")
+			executor = &LocalExecutor{}
+			service = resolveServiceBackend(node.NodeIP, config, executor)
 		} else {
 			// Connect to remote node using per-node credentials
-			sshClient, _, err := createSSHConnectionWithNodeCredentials(node.NodeIP, config)
+			var err error
+			executor, err = getNodeExecutor(node.NodeIP, config)
 			if err != nil {
 				node.StatusError = fmt.Sprintf("SSH connection failed: %v", err)
-				progressPrint("      ❌ SSH connection failed: %v\n", err)
+				analysis.progress("      ❌ SSH connection failed: %v\n", err)
 				continue
 			}
+			service = resolveServiceBackend(node.NodeIP, config, nil)
+		}
 
-			// Check MySQL status on remote node
-			checkMySQLStatus(sshClient, node.NodeIP, mysqlCreds, node)
-			sshClient.Close()
+		// Check MySQL status, asking for this node's credentials if every saved one was rejected
+		working := checkMySQLStatus(executor, node.NodeIP, mysqlCredentialCandidates(node.NodeIP, config, mysqlCreds), service, node)
+		if working == nil && isMySQLAccessDenied(node.StatusError) {
+			if nodeCreds := promptNodeMySQLCredentials(node.NodeIP, config); nodeCreds != nil {
+				node.StatusError = ""
+				working = checkMySQLStatus(executor, node.NodeIP, []*MySQLConnectionInfo{nodeCreds}, service, node)
+				if working != nil && promptForBoolWithDefault(fmt.Sprintf("Save these credentials for %s? (encrypted)", node.NodeIP), true) {
+					if err := config.setNodeMySQLCredentials(node.NodeIP, nodeCreds.Username, nodeCreds.Password); err != nil {
+						analysis.progress("      ⚠️  Warning: Could not save MySQL credentials for %s: %v\n", node.NodeIP, err)
+					} else {
+						analysis.progress("      ✓ MySQL credentials saved for node %s\n", node.NodeIP)
+					}
+				}
+			}
 		}
+		if working != nil {
+			workingMySQLCredentials[node.NodeIP] = working
+			config.markCredentialsUsed(node.NodeIP, credentialMySQL)
+		}
+
+		node.Transfer = detectNodeTransfer(executor, service, node)
 
 		if node.MySQLResponding {
-			progressPrint("      ✓ MySQL responding (Size: %d, Status: %s, Ready: %t, State: %s)\n",
+			analysis.progress("      ✓ MySQL responding (Size: %d, Status: %s, Ready: %t, State: %s)\n",
 				node.ClusterSize, node.ClusterStatus, node.IsReady, node.LocalStateComment)
 		} else {
-			progressPrint("      ❌ MySQL not responding: %s\n", node.StatusError)
+			analysis.progress("      ❌ MySQL not responding: %s\n", node.StatusError)
 		}
 	}
 
+	analysis.Transfers = analysis.pairStateTransfers()
 	return nil
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeCoherence(t *testing.T) {
+	tests := []struct {
+		name         string
+		nodes        []*GaleraClusterInfo
+		wantCoherent bool
+		wantErrors   []string
+	}{
+		{
+			name: "matching nodes",
+			nodes: []*GaleraClusterInfo{
+				{NodeIP: "10.0.0.1", ClusterName: "prod", ClusterAddress: "gcomm://10.0.0.1,10.0.0.2"},
+				{NodeIP: "10.0.0.2", ClusterName: "prod", ClusterAddress: "gcomm://10.0.0.1,10.0.0.2"},
+			},
+			wantCoherent: true,
+		},
+		{
+			name: "different cluster name",
+			nodes: []*GaleraClusterInfo{
+				{NodeIP: "10.0.0.1", ClusterName: "prod", ClusterAddress: "gcomm://10.0.0.1,10.0.0.2"},
+				{NodeIP: "10.0.0.2", ClusterName: "staging", ClusterAddress: "gcomm://10.0.0.1,10.0.0.2"},
+			},
+			wantCoherent: false,
+			wantErrors:   []string{"different cluster name"},
+		},
+		{
+			name: "different cluster address",
+			nodes: []*GaleraClusterInfo{
+				{NodeIP: "10.0.0.1", ClusterName: "prod", ClusterAddress: "gcomm://10.0.0.1,10.0.0.2"},
+				{NodeIP: "10.0.0.2", ClusterName: "prod", ClusterAddress: "gcomm://10.0.0.2"},
+			},
+			wantCoherent: false,
+			wantErrors:   []string{"different cluster address"},
+		},
+		{
+			name: "node address differs from connection IP",
+			nodes: []*GaleraClusterInfo{
+				{NodeIP: "10.0.0.1", ClusterName: "prod", ClusterAddress: "gcomm://10.0.0.1,10.0.0.2"},
+				{NodeIP: "10.0.0.2", NodeAddress: "192.168.1.2", ClusterName: "prod", ClusterAddress: "gcomm://10.0.0.1,10.0.0.2"},
+			},
+			wantCoherent: true,
+			wantErrors:   []string{"wsrep_node_address (192.168.1.2) differs"},
+		},
+		{
+			name: "single node",
+			nodes: []*GaleraClusterInfo{
+				{NodeIP: "10.0.0.1", ClusterName: "prod", ClusterAddress: "gcomm://10.0.0.1"},
+			},
+			wantCoherent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := &ClusterAnalysis{
+				InitialNode: tt.nodes[0],
+				AllNodes:    tt.nodes,
+				IsCoherent:  true,
+			}
+			analysis.analyzeCoherence()
+
+			if analysis.IsCoherent != tt.wantCoherent {
+				t.Errorf("IsCoherent = %t, want %t (errors: %v)", analysis.IsCoherent, tt.wantCoherent, analysis.ConfigErrors)
+			}
+			assertErrors(t, analysis.ConfigErrors, tt.wantErrors)
+		})
+	}
+}
+
+func TestPerformClusterAnalysis(t *testing.T) {
+	members := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip, clusterName string) *fakeNode {
+		return &fakeNode{
+			IP:      ip,
+			Service: "active",
+			Files: map[string]string{
+				"/etc/mysql/my.cnf":                       "[mysqld]\nbind-address = 0.0.0.0\n",
+				"/etc/mysql/mariadb.conf.d/60-galera.cnf": galeraConfig(clusterName, ip, members...),
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		nodes        []*fakeNode
+		wantNodes    int
+		wantCoherent bool
+		wantErrors   []string
+	}{
+		{
+			name:         "coherent cluster",
+			nodes:        []*fakeNode{node("10.0.0.1", "prod"), node("10.0.0.2", "prod"), node("10.0.0.3", "prod")},
+			wantNodes:    3,
+			wantCoherent: true,
+		},
+		{
+			name:         "node with a different cluster name",
+			nodes:        []*fakeNode{node("10.0.0.1", "prod"), node("10.0.0.2", "prod"), node("10.0.0.3", "staging")},
+			wantNodes:    3,
+			wantCoherent: false,
+			wantErrors:   []string{"Node 10.0.0.3 has different cluster name"},
+		},
+		{
+			name: "unreachable node",
+			nodes: []*fakeNode{node("10.0.0.1", "prod"), node("10.0.0.2", "prod"),
+				func() *fakeNode { n := node("10.0.0.3", "prod"); n.Unreachable = true; return n }()},
+			wantNodes:    3,
+			wantCoherent: false,
+			wantErrors:   []string{"Failed to connect to node 10.0.0.3"},
+		},
+		{
+			name: "configuration only in /etc/my.cnf",
+			nodes: []*fakeNode{node("10.0.0.1", "prod"), node("10.0.0.2", "prod"),
+				{IP: "10.0.0.3", Service: "active", Files: map[string]string{"/etc/my.cnf": galeraConfig("prod", "10.0.0.3", members...)}}},
+			wantNodes:    3,
+			wantCoherent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakeCluster(t, tt.nodes...)
+			config := newTestConfig(t)
+
+			executor, err := getNodeExecutor("10.0.0.1", config)
+			if err != nil {
+				t.Fatalf("connecting to initial node: %v", err)
+			}
+			initialNode, err := getGaleraClusterInfo(executor, "10.0.0.1")
+			if err != nil {
+				t.Fatalf("getGaleraClusterInfo: %v", err)
+			}
+
+			analysis, _, err := performClusterAnalysis(initialNode, &SSHConnectionInfo{Username: "root", UsedKeys: true}, config)
+			if err != nil {
+				t.Fatalf("performClusterAnalysis: %v", err)
+			}
+
+			if len(analysis.AllNodes) != tt.wantNodes {
+				t.Errorf("analyzed %d nodes, want %d", len(analysis.AllNodes), tt.wantNodes)
+			}
+			if analysis.IsCoherent != tt.wantCoherent {
+				t.Errorf("IsCoherent = %t, want %t (errors: %v)", analysis.IsCoherent, tt.wantCoherent, analysis.ConfigErrors)
+			}
+			assertErrors(t, analysis.ConfigErrors, tt.wantErrors)
+		})
+	}
+}
+
+// assertErrors checks that every wanted substring appears in one of the errors, and that there
+// are no errors when none are wanted
+func assertErrors(t *testing.T, errors []string, want []string) {
+	t.Helper()
+	if len(want) == 0 && len(errors) > 0 {
+		t.Errorf("unexpected errors: %v", errors)
+	}
+	for _, substring := range want {
+		found := false
+		for _, err := range errors {
+			if strings.Contains(err, substring) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("no error containing %q in %v", substring, errors)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/syslog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AuditEntry is a single record in the audit log of state-changing actions
+type AuditEntry struct {
+	Timestamp    string            `json:"timestamp"`
+	Operator     string            `json:"operator"`
+	OperatorHost string            `json:"operator_host"`
+	Action       string            `json:"action"`
+	TargetNode   string            `json:"target_node"`
+	Command      string            `json:"command"`
+	Output       string            `json:"output"`
+	ExitStatus   int               `json:"exit_status"`
+	Error        string            `json:"error,omitempty"`
+	Evidence     map[string]string `json:"evidence,omitempty"`
+}
+
+// auditSyslogWriter is the part of a syslog connection the audit log uses
+type auditSyslogWriter interface {
+	Notice(message string) error
+	Close() error
+}
+
+// dialAuditSyslog connects to the local syslog daemon; tests replace it
+var dialAuditSyslog = func() (auditSyslogWriter, error) {
+	return syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, "galerahealth")
+}
+
+// getAuditLogPath returns the path to the audit log file
+func getAuditLogPath(config *Config) string {
+	if config != nil && config.AuditLogPath != "" {
+		return config.AuditLogPath
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".galerahealth-audit.jsonl")
+}
+
+// getOperatorName returns the name of the person running galerahealth
+func getOperatorName() string {
+	name := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" && sudoUser != name {
+		name = fmt.Sprintf("%s (via sudo from %s)", name, sudoUser)
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	return name
+}
+
+// getExitStatus extracts the exit status of a command from its error
+func getExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *CommandExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode
+	}
+	return -1
+}
+
+// writeAuditEntry appends an entry to the audit log and, if enabled, to syslog
+func writeAuditEntry(entry *AuditEntry, config *Config) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not marshal audit entry: %v", err)
+	}
+
+	path := getAuditLogPath(config)
+	if path == "" {
+		return fmt.Errorf("could not determine audit log path")
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open audit log: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("could not write audit log: %v", err)
+	}
+
+	if config != nil && config.AuditSyslog {
+		writer, err := dialAuditSyslog()
+		if err != nil {
+			return fmt.Errorf("could not connect to syslog: %v", err)
+		}
+		defer writer.Close()
+		if err := writer.Notice(string(data)); err != nil {
+			return fmt.Errorf("could not write to syslog: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// executeAuditedCommandOnNode runs a state-changing command on a node and records it in the audit log
+func executeAuditedCommandOnNode(ip, action, command string, evidence map[string]string, config *Config) (string, error) {
+	output, err := executeCommandOnNode(ip, command, config)
+	recordAuditEntry(ip, action, command, output, err, evidence, config)
+	return output, err
+}
+
+// executeAuditedServiceCommandOnNode runs a state-changing service command and records it in the audit log
+func executeAuditedServiceCommandOnNode(ip string, service *ServiceBackend, action, command string, evidence map[string]string, config *Config) (string, error) {
+	renewHeldClusterLock(config)
+	output, err := executeServiceCommandOnNode(ip, service, command, config)
+	recordAuditEntry(ip, action, command, output, err, evidence, config)
+	return output, err
+}
+
+// recordAuditEntry builds and writes the audit entry for a command that has been run
+func recordAuditEntry(ip, action, command, output string, err error, evidence map[string]string, config *Config) {
+	hostname, _ := os.Hostname()
+	entry := &AuditEntry{
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Operator:     getOperatorName(),
+		OperatorHost: hostname,
+		Action:       action,
+		TargetNode:   ip,
+		Command:      command,
+		Output:       strings.TrimSpace(output),
+		ExitStatus:   getExitStatus(err),
+		Evidence:     evidence,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if auditErr := writeAuditEntry(entry, config); auditErr != nil {
+		logMinimal("⚠️  Warning: Could not write audit log entry: %v", auditErr)
+	} else {
+		logDebug("Audit log entry written for %s on %s", action, ip)
+	}
+}
+
+// clusterStateEvidence summarizes the cluster state used to decide on a recovery action
+func clusterStateEvidence(state *ClusterState) map[string]string {
+	evidence := make(map[string]string)
+	if state == nil {
+		return evidence
+	}
+	for _, node := range state.Nodes {
+		if node.IsUp {
+			evidence["state."+node.IP] = "up"
+			continue
+		}
+		evidence["state."+node.IP] = "down"
+		if node.HasGrastate {
+			evidence["seqno."+node.IP] = fmt.Sprintf("%d", node.SeqNo)
+		} else {
+			evidence["seqno."+node.IP] = "unavailable"
+		}
+		if !node.LatestIDB.IsZero() {
+			evidence["latest_ibd."+node.IP] = node.LatestIDB.UTC().Format(time.RFC3339)
+		}
+		if node.Clock != nil && node.Clock.Measured {
+			evidence["clock_offset."+node.IP] = formatOffset(node.Clock.Offset)
+		}
+	}
+	return evidence
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSyslog records the messages sent to syslog
+type fakeSyslog struct {
+	messages []string
+}
+
+func (s *fakeSyslog) Notice(message string) error {
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func (s *fakeSyslog) Close() error {
+	return nil
+}
+
+// readAuditLog returns the entries of a JSON-lines audit log
+func readAuditLog(t *testing.T, path string) []AuditEntry {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []AuditEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAuditLog(t *testing.T) {
+	failing := &fakeNode{IP: "10.0.0.2", Service: "inactive", FailStart: true}
+	newFakeCluster(t, &fakeNode{IP: "10.0.0.1", Service: "inactive"}, failing)
+	config := newTestConfig(t)
+	sink := &fakeSyslog{}
+	previousDial := dialAuditSyslog
+	dialAuditSyslog = func() (auditSyslogWriter, error) { return sink, nil }
+	t.Cleanup(func() { dialAuditSyslog = previousDial })
+
+	evidence := map[string]string{"seqno.10.0.0.1": "42", "method": "highest seqno"}
+	if err := bootstrapNode("10.0.0.1", evidence, config); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.messages) != 0 {
+		t.Errorf("sent to syslog while audit_syslog is off: %q", sink.messages)
+	}
+
+	config.AuditSyslog = true
+	if err := startMySQLService("10.0.0.2", nil, config); err == nil {
+		t.Fatal("start of a failing node succeeded")
+	}
+
+	entries := readAuditLog(t, config.AuditLogPath)
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2", len(entries))
+	}
+	bootstrap, start := entries[0], entries[1]
+	if bootstrap.Action != "bootstrap" || bootstrap.TargetNode != "10.0.0.1" || bootstrap.Command != "galera_new_cluster" ||
+		bootstrap.ExitStatus != 0 || bootstrap.Error != "" {
+		t.Errorf("bootstrap entry = %+v", bootstrap)
+	}
+	if bootstrap.Evidence["seqno.10.0.0.1"] != "42" || bootstrap.Evidence["method"] != "highest seqno" {
+		t.Errorf("bootstrap evidence = %v", bootstrap.Evidence)
+	}
+	if _, err := time.Parse(time.RFC3339, bootstrap.Timestamp); err != nil || bootstrap.Operator == "" {
+		t.Errorf("bootstrap timestamp %q (%v), operator %q", bootstrap.Timestamp, err, bootstrap.Operator)
+	}
+	if start.Action != "start" || start.TargetNode != "10.0.0.2" || start.Command != "systemctl start mariadb" ||
+		start.ExitStatus != 1 || !strings.Contains(start.Output, "failed") || start.Error == "" {
+		t.Errorf("failed start entry = %+v", start)
+	}
+
+	// With audit_syslog the same JSON line goes to syslog
+	if len(sink.messages) != 1 || !strings.Contains(sink.messages[0], `"action":"start"`) {
+		t.Errorf("syslog messages = %q", sink.messages)
+	}
+}
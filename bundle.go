@@ -0,0 +1,262 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Support bundle defaults
+const (
+	defaultBundleLogWindow = 24 * time.Hour
+	bundleLogLines         = 5000
+	redactedValue          = "<redacted>"
+)
+
+// secretPatterns match the secrets removed from the bundle; the first group is kept
+var secretPatterns = []*regexp.Regexp{
+	// password = x, wsrep_sst_auth = user:x, ssl-key-password: x (option files)
+	regexp.MustCompile(`(?im)^(\s*[\w.-]*(?:password|passwd|pwd|secret|token|_auth)[\w.-]*\s*[=:]\s*).+$`),
+	// wsrep_sst_auth<TAB>user:x (SHOW VARIABLES output)
+	regexp.MustCompile(`(?im)^(\s*[\w.-]*(?:password|passwd|secret|token|_auth)[\w.-]*\t).+$`),
+	// --password=x, --password 'x', --wsrep_sst_auth=user:x on command lines in logs and process trees
+	regexp.MustCompile(`(?i)(--[\w-]*(?:password|passwd|secret|token|[_-]auth)[\w-]*[= ])('[^']*'|"[^"]*"|\S+)`),
+	// IDENTIFIED BY 'x'
+	regexp.MustCompile(`(?i)(IDENTIFIED (?:BY|WITH \S+ BY) )'[^']*'`),
+}
+
+// BundleFile is a file of the support bundle listed in its manifest
+type BundleFile struct {
+	Path        string `json:"path"`
+	Node        string `json:"node,omitempty"`
+	Description string `json:"description"`
+	Bytes       int    `json:"bytes"`
+	Redacted    bool   `json:"redacted,omitempty"` // Secrets were removed from the content
+	Error       string `json:"error,omitempty"`    // Why the content is missing or incomplete
+}
+
+// BundleManifest describes the content of a support bundle
+type BundleManifest struct {
+	Cluster   string       `json:"cluster,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	LogWindow string       `json:"log_window"`
+	Nodes     []string     `json:"nodes"`
+	Files     []BundleFile `json:"files"`
+}
+
+// Bundle is a support bundle being assembled
+type Bundle struct {
+	Manifest BundleManifest
+	contents map[string][]byte
+}
+
+// bundleItem is a command whose output is saved in the bundle. Every output is redacted: status and
+// logs show process command lines, which can carry passwords.
+type bundleItem struct {
+	name        string
+	description string
+	command     string
+	service     bool // Run where the service runs (the container host for container backends)
+}
+
+// redactSecrets removes passwords and other secrets from a file content
+func redactSecrets(content string) (string, bool) {
+	redacted := content
+	for _, pattern := range secretPatterns {
+		redacted = pattern.ReplaceAllString(redacted, "${1}"+redactedValue)
+	}
+	return redacted, redacted != content
+}
+
+// add stores a file in the bundle and lists it in the manifest
+func (b *Bundle) add(file BundleFile, content string, redact bool) {
+	if redact {
+		content, file.Redacted = redactSecrets(content)
+	}
+	file.Bytes = len(content)
+	b.contents[file.Path] = []byte(content)
+	b.Manifest.Files = append(b.Manifest.Files, file)
+}
+
+// nodeBundleItems returns the commands collected from a node
+func nodeBundleItems(service *ServiceBackend, dataDir string, mysqlCmd string, window time.Duration) []bundleItem {
+	return []bundleItem{
+		{"effective-options.txt", "Options mysqld reads from the configuration files", "my_print_defaults --mysqld galera 2>&1", false},
+		{"grastate.dat", "Galera saved state", "cat " + shellQuote(path.Join(dataDir, "grastate.dat")), false},
+		{"gvwstate.dat", "Galera primary component view (present while the node runs or after a crash)", "cat " + shellQuote(path.Join(dataDir, "gvwstate.dat")), false},
+		{"wsrep-status.txt", "SHOW GLOBAL STATUS LIKE 'wsrep%'", mysqlCmd + " -e \"SHOW GLOBAL STATUS LIKE 'wsrep%';\" 2>&1", false},
+		{"wsrep-variables.txt", "SHOW GLOBAL VARIABLES LIKE 'wsrep%'", mysqlCmd + " -e \"SHOW GLOBAL VARIABLES LIKE 'wsrep%';\" 2>&1", false},
+		{"error-log.txt", "Recent error log or journal lines", service.logCommand(logSinceArgument(window), bundleLogLines), true},
+		{"service-status.txt", "Service status", service.statusDetailCommand() + " 2>&1", true},
+		{"disk-memory.txt", "Disk space, inodes, datadir size and memory",
+			fmt.Sprintf("df -P; echo; df -Pi; echo; du -sh %s 2>&1; echo; cat /proc/meminfo", shellQuote(dataDir)), false},
+	}
+}
+
+// collectNodeBundle adds the files of one node to the bundle
+func (b *Bundle) collectNodeBundle(ip string, window time.Duration, config *Config) {
+	prefix := strings.NewReplacer(":", "_", "/", "_").Replace(ip)
+	executor, err := getNodeExecutor(ip, config)
+	if err != nil {
+		logNormal("   ❌ %s: %v", ip, err)
+		b.add(BundleFile{Path: prefix + "/error.txt", Node: ip, Description: "Why the node could not be collected", Error: err.Error()}, err.Error()+"\n", false)
+		return
+	}
+
+	// Configuration files, with the datadir they set
+	dataDir := defaultDataDir
+	for _, configPath := range findConfigFiles(executor) {
+		content, err := runCommand(executor, "cat "+shellQuote(configPath))
+		file := BundleFile{Path: prefix + "/config" + configPath, Node: ip, Description: "Configuration file " + configPath}
+		if err != nil {
+			file.Error = err.Error()
+		}
+		if value := extractConfigValue(content, "datadir"); value != "" {
+			dataDir = value
+		}
+		b.add(file, content, true)
+	}
+
+	service := resolveServiceBackend(ip, config, nil)
+	mysqlCmd := buildMySQLClientCommand(getRecoveryMySQLCredentials(ip, config))
+	for _, item := range nodeBundleItems(service, dataDir, mysqlCmd, window) {
+		itemExecutor := executor
+		if item.service {
+			itemExecutor = serviceExecutor(executor, service)
+		}
+		output, err := runCommand(itemExecutor, item.command)
+		file := BundleFile{Path: prefix + "/" + item.name, Node: ip, Description: item.description}
+		if err != nil {
+			file.Error = err.Error()
+		}
+		b.add(file, output, true)
+	}
+	logNormal("   ✓ %s collected", ip)
+}
+
+// collectBundle gathers the support bundle of a cluster: the analysis, then the files of every node
+func collectBundle(ips []string, window time.Duration, config *Config) *Bundle {
+	bundle := &Bundle{
+		Manifest: BundleManifest{Cluster: config.activeCluster, CreatedAt: time.Now(), LogWindow: window.String(), Nodes: ips},
+		contents: make(map[string][]byte),
+	}
+
+	logNormal("🔍 Analyzing the cluster...")
+	analysis, err := checkCluster(config, true)
+	report := newHealthReport(config.activeCluster, analysis, err)
+	data, _ := json.MarshalIndent(report, "", "  ")
+	file := BundleFile{Path: "analysis.json", Description: "galerahealth analysis (same as serve's /health)"}
+	if err != nil {
+		file.Error = err.Error()
+	}
+	bundle.add(file, string(data)+"\n", true)
+
+	logNormal("📦 Collecting files from %d nodes...", len(ips))
+	for _, ip := range ips {
+		bundle.collectNodeBundle(ip, window, config)
+	}
+	return bundle
+}
+
+// write saves the bundle as a timestamped tar.gz in dir and returns its path
+func (b *Bundle) write(dir string) (string, error) {
+	name := "galerahealth-bundle"
+	if b.Manifest.Cluster != "" {
+		name += "-" + b.Manifest.Cluster
+	}
+	name += "-" + b.Manifest.CreatedAt.Format("20060102-150405")
+	bundlePath := filepath.Join(dir, name+".tar.gz")
+
+	// The bundle holds configuration and logs: readable by the owner only
+	f, err := os.OpenFile(bundlePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", bundlePath, err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the manifest: %v", err)
+	}
+	writeFile := func(file string, content []byte) error {
+		header := &tar.Header{Name: name + "/" + file, Mode: 0600, Size: int64(len(content)), ModTime: b.Manifest.CreatedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+	if err := writeFile("manifest.json", append(manifest, '\n')); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", bundlePath, err)
+	}
+	for _, file := range b.Manifest.Files {
+		if err := writeFile(file.Path, b.contents[file.Path]); err != nil {
+			return "", fmt.Errorf("failed to write %s: %v", bundlePath, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", bundlePath, err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", bundlePath, err)
+	}
+	return bundlePath, f.Close()
+}
+
+// runBundleCommand implements the "bundle" subcommand
+func runBundleCommand(args []string) error {
+	dir := "."
+	window := defaultBundleLogWindow
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return fmt.Errorf("unknown bundle option: %s", args[i])
+		}
+		switch args[i] {
+		case "--output":
+			dir = args[i+1]
+		case "--since":
+			var err error
+			if window, err = parseLogWindow(args[i+1]); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown bundle option: %s", args[i])
+		}
+		i++
+	}
+
+	logMinimal("=== GaleraHealth - Support Bundle ===")
+	// The bundle is collected with the saved settings, without prompting
+	useDefaults = true
+	config := loadClusterConfig()
+	ips, err := discoverClusterNodes(config)
+	if err != nil {
+		return err
+	}
+
+	bundle := collectBundle(ips, window, config)
+	bundlePath, err := bundle.write(dir)
+	if err != nil {
+		return err
+	}
+	problems := 0
+	for _, file := range bundle.Manifest.Files {
+		if file.Error != "" {
+			problems++
+			logVerbose("   ⚠️  %s: %s", file.Path, file.Error)
+		}
+	}
+	logMinimal("✅ Support bundle written to %s (%d files, %d incomplete)", bundlePath, len(bundle.Manifest.Files), problems)
+	logMinimal("   Passwords and other secrets were removed from the collected files; review it before sending")
+	return nil
+}
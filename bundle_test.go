@@ -0,0 +1,123 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"[client]\npassword = hunter2\nuser = root\n", "[client]\npassword = <redacted>\nuser = root\n"},
+		{"wsrep_sst_auth=sstuser:s3cret\n", "wsrep_sst_auth=<redacted>\n"},
+		{"wsrep_sst_auth\tsstuser:s3cret\nwsrep_sst_method\trsync\n", "wsrep_sst_auth\t<redacted>\nwsrep_sst_method\trsync\n"},
+		{"Running: 'wsrep_sst_mysqldump --user 'root' --password 'abc' --host '10.0.0.2''", "Running: 'wsrep_sst_mysqldump --user 'root' --password <redacted> --host '10.0.0.2''"},
+		{"├─1234 /usr/sbin/mariadbd --wsrep_sst_auth=sstuser:s3cret --port=3306", "├─1234 /usr/sbin/mariadbd --wsrep_sst_auth=<redacted> --port=3306"},
+		{"CREATE USER 'sst'@'%' IDENTIFIED BY 'pw';", "CREATE USER 'sst'@'%' IDENTIFIED BY <redacted>;"},
+		{"wsrep_cluster_name = prod\n", "wsrep_cluster_name = prod\n"},
+	}
+	for _, tt := range tests {
+		got, redacted := redactSecrets(tt.content)
+		if got != tt.want || redacted != (tt.content != tt.want) {
+			t.Errorf("redactSecrets(%q) = %q, %t, want %q", tt.content, got, redacted, tt.want)
+		}
+	}
+}
+
+func TestSupportBundle(t *testing.T) {
+	previous := useDefaults
+	useDefaults = true
+	t.Cleanup(func() { useDefaults = previous })
+
+	members := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip string) *fakeNode {
+		return &fakeNode{IP: ip, Service: "active", Synced: true,
+			Files: map[string]string{
+				"/etc/mysql/my.cnf":            galeraConfig("prod", ip, members...) + "wsrep_sst_auth = sstuser:s3cret\n",
+				"/etc/mysql/conf.d/client.cnf": "[client]\npassword = hunter2\n",
+				"/var/lib/mysql/grastate.dat":  grastate(-1),
+			},
+			Log: "2024-03-01 10:00:00 0 [Note] WSREP: Running: 'wsrep_sst_mysqldump --password 'abc''\n",
+			StatusTail: "   CGroup: /system.slice/mariadb.service\n" +
+				"           ├─1234 /usr/sbin/mariadbd --wsrep_sst_auth=sstuser:cgpass\n" +
+				"           └─1301 /bin/bash -ue /usr/bin/wsrep_sst_mysqldump --user 'root' --password 'dumppw' --host '10.0.0.3'\n"}
+	}
+	newFakeCluster(t, node("10.0.0.1"), node("10.0.0.2"), &fakeNode{IP: "10.0.0.3", Unreachable: true})
+	config := newTestConfig(t)
+	config.LastNodeIP = "10.0.0.1"
+
+	bundle := collectBundle(members, defaultBundleLogWindow, config)
+	bundlePath, err := bundle.write(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(bundlePath); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("bundle file: %v %v", info, err)
+	}
+
+	// Read the archive back
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(tr)
+		_, name, _ := strings.Cut(header.Name, "/")
+		files[name] = string(content)
+	}
+
+	for _, name := range []string{"manifest.json", "analysis.json", "10.0.0.1/config/etc/mysql/my.cnf", "10.0.0.1/config/etc/mysql/conf.d/client.cnf",
+		"10.0.0.1/wsrep-status.txt", "10.0.0.1/error-log.txt", "10.0.0.2/service-status.txt", "10.0.0.3/error.txt"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("bundle is missing %s", name)
+		}
+	}
+	for name, content := range files {
+		for _, secret := range []string{"s3cret", "hunter2", "'abc'", "cgpass", "dumppw"} {
+			if strings.Contains(content, secret) {
+				t.Errorf("%s holds the secret %s:\n%s", name, secret, content)
+			}
+		}
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != len(files)-1 || len(manifest.Nodes) != 3 {
+		t.Errorf("manifest lists %d files for %d in the bundle, nodes %v", len(manifest.Files), len(files)-1, manifest.Nodes)
+	}
+	for _, file := range manifest.Files {
+		switch file.Path {
+		case "10.0.0.1/config/etc/mysql/my.cnf", "10.0.0.1/error-log.txt", "10.0.0.2/service-status.txt":
+			if !file.Redacted {
+				t.Errorf("%s not marked redacted", file.Path)
+			}
+		case "10.0.0.3/error.txt":
+			if file.Error == "" {
+				t.Errorf("%s has no error", file.Path)
+			}
+		}
+	}
+}
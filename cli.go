@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CLIOptions holds answers given on the command line. A prompt whose answer was given is skipped,
+// so a first run can be fully scripted.
+type CLIOptions struct {
+	Node              string // --node: initial node IP
+	SSHUser           string // --ssh-user: SSH username for nodes without saved credentials
+	SSHPasswordFile   string // --ssh-password-file: SSH password used instead of prompting
+	MySQLUser         string // --mysql-user
+	MySQLPasswordFile string // --mysql-password-file: MySQL password used instead of prompting
+	CheckCoherence    *bool  // --coherence / --no-coherence
+	CheckMySQL        *bool  // --mysql / --no-mysql
+	BootstrapTimeout  int    // --bootstrap-timeout seconds
+	JoinTimeout       int    // --join-timeout seconds
+}
+
+// External variable for command line answers (set by parseCommandLine)
+var cliOptions CLIOptions
+
+// Subcommands that run the cluster health check
+const (
+	commandCheck   = "check"   // Interactive check, the default
+	commandStatus  = "status"  // Non-interactive, summary only (same as -y -s)
+	commandRecover = "recover" // Check, then attempt recovery (same as -r)
+)
+
+// parseCommandLine parses the global flags, which may appear anywhere on the command line, and
+// returns the remaining arguments and the verbosity given with -v (-1 if none)
+func parseCommandLine(argv []string) ([]string, int, error) {
+	var args []string
+	verbosityCount := -1
+	enabled, disabled := true, false
+
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		value := func() (string, error) {
+			if i+1 >= len(argv) || argv[i+1] == "" {
+				return "", fmt.Errorf("%s requires a value", arg)
+			}
+			i++
+			return argv[i], nil
+		}
+		seconds := func() (int, error) {
+			v, err := value()
+			if err != nil {
+				return 0, fmt.Errorf("%s requires a value in seconds", arg)
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid value for %s: %s", arg, v)
+			}
+			return n, nil
+		}
+
+		var err error
+		switch {
+		case arg == "-v":
+			verbosityCount = 1
+		case arg == "-vv":
+			verbosityCount = 2
+		case arg == "-vvv":
+			verbosityCount = 3
+		case strings.HasPrefix(arg, "-v") && strings.Trim(arg[1:], "v") == "":
+			// Count consecutive 'v's for -vvv style
+			verbosityCount = len(arg) - 1
+		case arg == "-y", arg == "--yes":
+			useDefaults = true
+		case arg == "-s", arg == "--summary":
+			reportMode = true
+		case arg == "-r", arg == "--recovery":
+			runMode = true
+		case arg == "--break-lock":
+			breakLock = true
+		case arg == "--cluster":
+			clusterName, err = value()
+		case arg == "--node":
+			cliOptions.Node, err = value()
+		case arg == "--ssh-user":
+			cliOptions.SSHUser, err = value()
+		case arg == "--ssh-password-file":
+			cliOptions.SSHPasswordFile, err = value()
+		case arg == "--mysql-user":
+			cliOptions.MySQLUser, err = value()
+		case arg == "--mysql-password-file":
+			cliOptions.MySQLPasswordFile, err = value()
+		case arg == "--coherence":
+			cliOptions.CheckCoherence = &enabled
+		case arg == "--no-coherence":
+			cliOptions.CheckCoherence = &disabled
+		case arg == "--mysql":
+			cliOptions.CheckMySQL = &enabled
+		case arg == "--no-mysql":
+			cliOptions.CheckMySQL = &disabled
+		case arg == "--bootstrap-timeout":
+			cliOptions.BootstrapTimeout, err = seconds()
+		case arg == "--join-timeout":
+			cliOptions.JoinTimeout, err = seconds()
+		case arg == "--lock-wait":
+			var n int
+			n, err = seconds()
+			lockWait = time.Duration(n) * time.Second
+		default:
+			args = append(args, arg)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return args, verbosityCount, nil
+}
+
+// applyCLIOptions applies the command line answers on top of the file and environment settings
+func applyCLIOptions(config *Config) {
+	if cliOptions.Node != "" {
+		config.LastNodeIP = cliOptions.Node
+	}
+	if cliOptions.SSHUser != "" {
+		config.LastSSHUsername = cliOptions.SSHUser
+	}
+	if cliOptions.MySQLUser != "" {
+		config.LastMySQLUsername = cliOptions.MySQLUser
+	}
+	if cliOptions.CheckCoherence != nil {
+		config.LastCheckCoherence = *cliOptions.CheckCoherence
+	}
+	if cliOptions.CheckMySQL != nil {
+		config.LastCheckMySQL = *cliOptions.CheckMySQL
+	}
+	if cliOptions.BootstrapTimeout > 0 {
+		config.BootstrapTimeoutSeconds = cliOptions.BootstrapTimeout
+	}
+	if cliOptions.JoinTimeout > 0 {
+		config.JoinTimeoutSeconds = cliOptions.JoinTimeout
+	}
+}
+
+// clusterMySQLPasswordRef returns the reference of the cluster-wide MySQL password, if any
+func (c *Config) clusterMySQLPasswordRef() string {
+	if cliOptions.MySQLPasswordFile != "" {
+		return "file:" + cliOptions.MySQLPasswordFile
+	}
+	return c.MySQLPasswordRef
+}
+
+// printUsage prints the command line help
+func printUsage() {
+	fmt.Println("GaleraHealth - Galera Cluster Monitor")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  galerahealth [check]              Run the cluster monitor (interactive unless answered by flags or -y)")
+	fmt.Println("  galerahealth status               Check with saved settings and print only the summary (same as -y -s)")
+	fmt.Println("  galerahealth recover              Check, then attempt cluster recovery if needed (same as -r)")
+	fmt.Println("  galerahealth serve [--listen <addr> [--expose]] [--interval <sec>]")
+	fmt.Println("                                    Check periodically and serve /health over HTTP (127.0.0.1:19567)")
+	fmt.Println("  galerahealth rolling-restart      Restart all nodes one at a time without losing quorum")
+	fmt.Println("  galerahealth network              Test every node's Galera ports (gmcast, IST, SST) from every other node")
+	fmt.Println("  galerahealth logs [--since <6h>]  Merge the Galera events of every node's error log into one timeline")
+	fmt.Println("  galerahealth bundle [--output <dir>] [--since <24h>]")
+	fmt.Println("                                    Write a support bundle (configs, state files, status, logs, analysis) as tar.gz")
+	fmt.Println("  galerahealth config validate [file] Check the configuration file for errors")
+	fmt.Println("  galerahealth cluster list         List cluster profiles")
+	fmt.Println("  galerahealth cluster add <name> [--seed ip1,ip2] [--ssh-user u] [--mysql-user u]")
+	fmt.Println("                                    Add a cluster profile (without --seed: import the saved settings)")
+	fmt.Println("  galerahealth cluster remove <name> Remove a cluster profile")
+	fmt.Println("  galerahealth creds list           List saved node credentials and when they last worked")
+	fmt.Println("  galerahealth creds set <node> [--ssh-user u] [--mysql-user u] [--keys|--no-keys] [--ssh-password] [--mysql-password]")
+	fmt.Println("                                    Add or change a node's credentials (also --ssh-password-ref, --mysql-password-ref)")
+	fmt.Println("  galerahealth creds remove <node> [--ssh-password] [--mysql-password]")
+	fmt.Println("                                    Forget a node's credentials, or only one of its passwords")
+	fmt.Println("  galerahealth creds test [node]    Try each saved SSH and MySQL credential against the nodes")
+	fmt.Println("  galerahealth secrets status       Show how saved passwords are encrypted")
+	fmt.Println("  galerahealth secrets migrate --backend <b>  Re-encrypt saved passwords (passphrase, keyring, env, file)")
+	fmt.Println("  galerahealth secrets generate-key Print a random key for the env/file backends")
+	fmt.Println("  galerahealth --clear-config       Clear saved configuration")
+	fmt.Println("  galerahealth --help               Show this help")
+	fmt.Println()
+	fmt.Printf("Configuration file: %s\n", getConfigPath())
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -y, --yes     - Use saved defaults without prompting")
+	fmt.Println("  -s, --summary - Show only final summary (requires -y)")
+	fmt.Println("  -r, --recovery - Attempt cluster recovery if nodes are down")
+	fmt.Println("  --node <ip>               - Initial cluster node")
+	fmt.Println("  --ssh-user <user>         - SSH username for nodes without saved credentials")
+	fmt.Println("  --ssh-password-file <f>   - Read the SSH password from a file instead of prompting")
+	fmt.Println("  --mysql-user <user>       - MySQL/MariaDB username")
+	fmt.Println("  --mysql-password-file <f> - Read the MySQL password from a file instead of prompting")
+	fmt.Println("  --coherence, --no-coherence - Check (or skip) configuration coherence across all nodes")
+	fmt.Println("  --mysql, --no-mysql       - Check (or skip) MySQL/MariaDB status on all nodes")
+	fmt.Println("  --cluster <name|all>      - Use a named cluster profile, or check all of them")
+	fmt.Println("  --bootstrap-timeout <sec> - Max wait for the bootstrap node to reach Synced (default 120)")
+	fmt.Println("  --join-timeout <sec>      - Max wait for each joining node to reach Synced (default 1800)")
+	fmt.Println("  --order <ip1,ip2,...>     - Node order for rolling-restart (default: configured order, wsrep_sst_donor nodes last)")
+	fmt.Println("  --lock-wait <sec>         - Wait for another run's recovery lock instead of refusing")
+	fmt.Println("  --break-lock              - Remove a stale recovery lock from all nodes (asks for confirmation)")
+	fmt.Println("  --kdf <argon2id|scrypt>   - Key derivation for secrets migrate --backend passphrase (default argon2id)")
+	fmt.Println("  --key-file <path>         - Key file for secrets migrate --backend file (default ~/.galerahealth.key)")
+	fmt.Println()
+	fmt.Println("Verbosity levels:")
+	fmt.Println("  (none) - Minimal output (default)")
+	fmt.Println("  -v     - Normal operations + warnings")
+	fmt.Println("  -vv    - Detailed operations + debug info")
+	fmt.Println("  -vvv   - Full debug output + raw data")
+	fmt.Println()
+	fmt.Println("Recovery actions always ask for confirmation, even with -y.")
+}
+
+// promptUnlessGiven returns the value given on the command line, or prompts for it
+func promptUnlessGiven(given, message, defaultValue string) string {
+	if given != "" {
+		logVerbose("Using command line value for '%s': %s", message, given)
+		return given
+	}
+	return promptForInputWithDefault(message, defaultValue)
+}
+
+// promptBoolUnlessGiven returns the answer given on the command line, or prompts for it
+func promptBoolUnlessGiven(given *bool, message string, defaultValue bool) bool {
+	if given != nil {
+		logVerbose("Using command line value for '%s': %t", message, *given)
+		return *given
+	}
+	return promptForBoolWithDefault(message, defaultValue)
+}
+
+// readPasswordFile reads a password given with --ssh-password-file or --mysql-password-file
+func readPasswordFile(path string) (string, error) {
+	password, err := resolveSecretRef("file:" + path)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+		logNormal("⚠️  Password file %s is readable by other users", path)
+	}
+	return password, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// resetCLIState restores the globals set by parseCommandLine when the test ends
+func resetCLIState(t *testing.T) {
+	t.Helper()
+	saved := struct {
+		options                                  CLIOptions
+		useDefaults, reportMode, runMode, breakL bool
+		cluster                                  string
+		lockWait                                 time.Duration
+	}{cliOptions, useDefaults, reportMode, runMode, breakLock, clusterName, lockWait}
+	cliOptions = CLIOptions{}
+	t.Cleanup(func() {
+		cliOptions = saved.options
+		useDefaults, reportMode, runMode, breakLock = saved.useDefaults, saved.reportMode, saved.runMode, saved.breakL
+		clusterName, lockWait = saved.cluster, saved.lockWait
+	})
+}
+
+func TestParseCommandLine(t *testing.T) {
+	resetCLIState(t)
+
+	args, verbosity, err := parseCommandLine([]string{
+		"check", "--node", "10.0.0.1", "--ssh-user", "deploy", "-vv", "--mysql-user", "monitor",
+		"--mysql-password-file", "/run/secrets/mysql", "--no-mysql", "--coherence", "--join-timeout", "600", "-y",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 1 || args[0] != "check" {
+		t.Errorf("args = %v, want [check]", args)
+	}
+	if verbosity != 2 || !useDefaults {
+		t.Errorf("verbosity = %d, useDefaults = %t", verbosity, useDefaults)
+	}
+	want := CLIOptions{Node: "10.0.0.1", SSHUser: "deploy", MySQLUser: "monitor", MySQLPasswordFile: "/run/secrets/mysql", JoinTimeout: 600}
+	got := cliOptions
+	got.CheckCoherence, got.CheckMySQL = nil, nil
+	if got != want {
+		t.Errorf("options = %+v, want %+v", got, want)
+	}
+	if cliOptions.CheckMySQL == nil || *cliOptions.CheckMySQL || cliOptions.CheckCoherence == nil || !*cliOptions.CheckCoherence {
+		t.Errorf("check flags not parsed: coherence %v, mysql %v", cliOptions.CheckCoherence, cliOptions.CheckMySQL)
+	}
+
+	// Flags override the file and environment settings
+	config := &Config{LastNodeIP: "10.0.0.9", LastSSHUsername: "root", LastCheckMySQL: true, JoinTimeoutSeconds: 60}
+	applyCLIOptions(config)
+	if config.LastNodeIP != "10.0.0.1" || config.LastSSHUsername != "deploy" || config.LastCheckMySQL || config.JoinTimeoutSeconds != 600 {
+		t.Errorf("options not applied: %+v", config)
+	}
+	if ref := config.clusterMySQLPasswordRef(); ref != "file:/run/secrets/mysql" {
+		t.Errorf("MySQL password reference = %q", ref)
+	}
+
+	// Subcommand options are passed through
+	args, verbosity, err = parseCommandLine([]string{"rolling-restart", "--order", "10.0.0.2,10.0.0.1"})
+	if err != nil || len(args) != 3 || verbosity != -1 {
+		t.Errorf("rolling-restart: args %v, verbosity %d, err %v", args, verbosity, err)
+	}
+
+	for _, bad := range [][]string{{"--node"}, {"--join-timeout", "soon"}, {"--lock-wait", "-5"}} {
+		if _, _, err := parseCommandLine(bad); err == nil {
+			t.Errorf("expected an error for %v", bad)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxClockSkew is the clock offset above which a node is reported
+const defaultMaxClockSkew = time.Second
+
+// clockSamples is the number of round trips used to measure a node's clock offset; the one with
+// the shortest round trip gives the most precise offset
+const clockSamples = 3
+
+// Time synchronization states
+const (
+	timeSyncSynced   = "synced"
+	timeSyncUnsynced = "unsynchronized"
+	timeSyncUnknown  = "unknown"
+)
+
+// ClockStatus is a node's clock compared with the machine running galerahealth
+type ClockStatus struct {
+	Offset     time.Duration // Node clock minus local clock
+	RTT        time.Duration // Round trip of the best sample, the offset is accurate to half of it
+	Measured   bool
+	SyncDaemon string // chrony, ntpd or timedatectl ("" if none was found)
+	SyncState  string // synced, unsynchronized or unknown
+}
+
+// getMaxClockSkew returns the configured clock skew threshold
+func getMaxClockSkew(config *Config) time.Duration {
+	if config != nil && config.MaxClockSkewMs > 0 {
+		return time.Duration(config.MaxClockSkewMs) * time.Millisecond
+	}
+	return defaultMaxClockSkew
+}
+
+// measureClockOffset estimates the node's clock offset from SSH round trips: the remote time is
+// assumed to be read halfway through the round trip
+func measureClockOffset(executor NodeExecutor) (time.Duration, time.Duration, error) {
+	var bestOffset, bestRTT time.Duration
+	var lastErr error
+	measured := false
+	for i := 0; i < clockSamples; i++ {
+		start := time.Now()
+		output, err := runCommand(executor, "date +%s%N")
+		end := time.Now()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		nanos, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+		if err != nil {
+			lastErr = fmt.Errorf("unexpected date output %q", strings.TrimSpace(output))
+			continue
+		}
+		rtt := end.Sub(start)
+		offset := time.Unix(0, nanos).Sub(start.Add(rtt / 2))
+		if !measured || rtt < bestRTT {
+			bestOffset, bestRTT, measured = offset, rtt, true
+		}
+	}
+	if !measured {
+		return 0, 0, lastErr
+	}
+	return bestOffset, bestRTT, nil
+}
+
+// checkTimeSync returns the time synchronization daemon of a node and whether it is synchronized,
+// asking chrony, then ntpd (ntpstat), then timedatectl
+func checkTimeSync(executor NodeExecutor) (string, string) {
+	output, _ := runCommand(executor, "chronyc tracking 2>/dev/null | grep -i '^Leap status'; "+
+		"ntpstat >/dev/null 2>&1; echo \"ntpstat $?\"; "+
+		"timedatectl show -p NTPSynchronized --value 2>/dev/null | sed 's/^/timedatectl /'")
+
+	var ntpstat, timedatectl string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(strings.ToLower(line), "leap status"):
+			if strings.Contains(line, "Not synchronised") {
+				return "chrony", timeSyncUnsynced
+			}
+			return "chrony", timeSyncSynced
+		case strings.HasPrefix(line, "ntpstat "):
+			ntpstat = strings.TrimPrefix(line, "ntpstat ")
+		case strings.HasPrefix(line, "timedatectl "):
+			timedatectl = strings.TrimPrefix(line, "timedatectl ")
+		}
+	}
+
+	switch ntpstat {
+	case "0":
+		return "ntpd", timeSyncSynced
+	case "1":
+		return "ntpd", timeSyncUnsynced
+	}
+	switch timedatectl {
+	case "yes":
+		return "timedatectl", timeSyncSynced
+	case "no":
+		return "timedatectl", timeSyncUnsynced
+	}
+	return "", timeSyncUnknown
+}
+
+// checkNodeClock measures a node's clock offset and time synchronization status
+func checkNodeClock(executor NodeExecutor, nodeIP string) *ClockStatus {
+	status := &ClockStatus{}
+	offset, rtt, err := measureClockOffset(executor)
+	if err != nil {
+		logVerbose("      ⚠️  Could not measure the clock of %s: %v", nodeIP, err)
+	} else {
+		status.Offset, status.RTT, status.Measured = offset, rtt, true
+	}
+	status.SyncDaemon, status.SyncState = checkTimeSync(executor)
+	logVerbose("      🕒 Clock of %s: %s", nodeIP, status.describe())
+	return status
+}
+
+// describe returns the offset and sync state for display
+func (c *ClockStatus) describe() string {
+	offset := "offset unknown"
+	if c.Measured {
+		offset = fmt.Sprintf("%s (±%.1fms)", formatOffset(c.Offset), float64(c.RTT/2)/float64(time.Millisecond))
+	}
+	if c.SyncDaemon == "" {
+		return offset + ", no time synchronization found"
+	}
+	return fmt.Sprintf("%s, %s %s", offset, c.SyncDaemon, c.SyncState)
+}
+
+// formatOffset formats a signed clock offset in milliseconds
+func formatOffset(offset time.Duration) string {
+	return fmt.Sprintf("%+.1fms", float64(offset)/float64(time.Millisecond))
+}
+
+// clockSkew returns the spread between the earliest and the latest of the measured clocks
+func clockSkew(clocks []*ClockStatus) (time.Duration, bool) {
+	var minOffset, maxOffset time.Duration
+	count := 0
+	for _, clock := range clocks {
+		if clock == nil || !clock.Measured {
+			continue
+		}
+		if count == 0 || clock.Offset < minOffset {
+			minOffset = clock.Offset
+		}
+		if count == 0 || clock.Offset > maxOffset {
+			maxOffset = clock.Offset
+		}
+		count++
+	}
+	return maxOffset - minOffset, count > 1
+}
+
+// clockWarnings returns the warnings for clocks beyond the threshold or not synchronized
+func clockWarnings(nodeIP string, clock *ClockStatus, maxSkew time.Duration) []string {
+	if clock == nil {
+		return nil
+	}
+	var warnings []string
+	if clock.Measured && absDuration(clock.Offset) > maxSkew {
+		warnings = append(warnings, fmt.Sprintf("Clock of %s is off by %s (threshold %s)", nodeIP, formatOffset(clock.Offset), maxSkew))
+	}
+	if clock.SyncState == timeSyncUnsynced {
+		warnings = append(warnings, fmt.Sprintf("Time on %s is not synchronized (%s)", nodeIP, clock.SyncDaemon))
+	}
+	return warnings
+}
+
+// absDuration returns the absolute value of d
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
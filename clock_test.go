@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckTimeSync(t *testing.T) {
+	tests := []struct {
+		output     string
+		wantDaemon string
+		wantState  string
+	}{
+		{"Leap status     : Normal\nntpstat 127\ntimedatectl yes\n", "chrony", timeSyncSynced},
+		{"Leap status     : Not synchronised\nntpstat 127\n", "chrony", timeSyncUnsynced},
+		{"ntpstat 0\ntimedatectl yes\n", "ntpd", timeSyncSynced},
+		{"ntpstat 127\ntimedatectl no\n", "timedatectl", timeSyncUnsynced},
+		{"ntpstat 127\n", "", timeSyncUnknown},
+	}
+	for _, tt := range tests {
+		executor := &FakeExecutor{Handler: func(command, stdin string) *CommandResult { return &CommandResult{Stdout: tt.output} }}
+		if daemon, state := checkTimeSync(executor); daemon != tt.wantDaemon || state != tt.wantState {
+			t.Errorf("checkTimeSync(%q) = %s %s, want %s %s", tt.output, daemon, state, tt.wantDaemon, tt.wantState)
+		}
+	}
+}
+
+func TestClockChecksInAnalysis(t *testing.T) {
+	members := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip string, offset time.Duration, timeSync string) *fakeNode {
+		return &fakeNode{IP: ip, Service: "active", ClockOffset: offset, TimeSync: timeSync,
+			Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...)}}
+	}
+	newFakeCluster(t,
+		node("10.0.0.1", 0, "Normal"),
+		node("10.0.0.2", 20*time.Millisecond, "Not synchronised"),
+		node("10.0.0.3", 3*time.Second, "Normal"),
+	)
+	config := newTestConfig(t)
+	config.MaxClockSkewMs = 500
+
+	executor, err := getNodeExecutor("10.0.0.1", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialNode, err := getGaleraClusterInfo(executor, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, _, err := performClusterAnalysis(initialNode, &SSHConnectionInfo{Username: "root", UsedKeys: true}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, node := range analysis.AllNodes {
+		if node.Clock == nil || !node.Clock.Measured {
+			t.Fatalf("clock of %s not measured: %+v", node.NodeIP, node.Clock)
+		}
+	}
+	if offset := analysis.AllNodes[2].Clock.Offset; offset < 2900*time.Millisecond || offset > 3100*time.Millisecond {
+		t.Errorf("offset of 10.0.0.3 = %s, want about 3s", offset)
+	}
+
+	health := evaluateClusterHealth(analysis)
+	assertErrors(t, health.Warnings, []string{
+		"Clock of 10.0.0.3 is off by +30",
+		"Clock skew between nodes is 3",
+		"Time on 10.0.0.2 is not synchronized (chrony)",
+	})
+	for _, warning := range health.Warnings {
+		if strings.Contains(warning, "Clock of 10.0.0.2") {
+			t.Errorf("unexpected warning: %s", warning)
+		}
+	}
+}
+
+func TestTimestampSelectionDoubt(t *testing.T) {
+	base := time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)
+	clock := func(offset time.Duration) *ClockStatus {
+		return &ClockStatus{Offset: offset, Measured: true, SyncDaemon: "chrony", SyncState: timeSyncSynced}
+	}
+	nodes := func(offset time.Duration) []NodeState {
+		return []NodeState{
+			{IP: "10.0.0.1", SeqNo: -1, LatestIDB: base.Add(time.Minute), Clock: clock(0)},
+			{IP: "10.0.0.2", SeqNo: -1, LatestIDB: base, Clock: clock(offset)},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   string
+	}{
+		{"clocks in sync", 10 * time.Millisecond, ""},
+		{"skew above the threshold", 5 * time.Second, "clock skew between nodes is 5s (threshold 3s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, method, err := selectBootstrapNode(&ClusterState{Nodes: nodes(tt.offset), MaxClockSkew: 3 * time.Second})
+			if err != nil || ip != "10.0.0.1" {
+				t.Fatalf("selected %s (%v)", ip, err)
+			}
+			if tt.want == "" && strings.Contains(method, "UNTRUSTWORTHY") || tt.want != "" && !strings.Contains(method, tt.want) {
+				t.Errorf("method = %q, want %q", method, tt.want)
+			}
+		})
+	}
+
+	// Within the threshold but larger than the lead of the chosen node
+	near := nodes(90 * time.Second)
+	if reason := timestampSelectionDoubt(near, near[0], 2*time.Minute); !strings.Contains(reason, "larger than the lead of 10.0.0.1 (1m0s)") {
+		t.Errorf("reason = %q", reason)
+	}
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// External variable for --cluster option (named cluster profile, or "all" for the whole fleet)
+var clusterName string
+
+// fleetClusterName selects every cluster profile for a combined fleet summary
+const fleetClusterName = "all"
+
+// ClusterProfile holds the saved settings of one named cluster
+type ClusterProfile struct {
+	Name                    string            `json:"name" yaml:"name"`
+	SeedNodes               []string          `json:"seed_nodes" yaml:"seed_nodes"` // Nodes tried in order to reach the cluster
+	LastSSHUsername         string            `json:"last_ssh_username" yaml:"ssh_user,omitempty"`
+	LastMySQLUsername       string            `json:"last_mysql_username" yaml:"mysql_user,omitempty"`
+	LastCheckCoherence      bool              `json:"last_check_coherence" yaml:"check_coherence"`
+	LastCheckMySQL          bool              `json:"last_check_mysql" yaml:"check_mysql"`
+	EncryptedMySQLPassword  string            `json:"encrypted_mysql_password,omitempty" yaml:"encrypted_mysql_password,omitempty"`
+	HasSavedPassword        bool              `json:"has_saved_password" yaml:"has_saved_password,omitempty"`
+	MySQLPasswordRef        string            `json:"mysql_password_ref,omitempty" yaml:"mysql_password_ref,omitempty"`
+	MySQLLoginPath          string            `json:"mysql_login_path,omitempty" yaml:"mysql_login_path,omitempty"`
+	MySQLOptionFile         bool              `json:"mysql_option_file,omitempty" yaml:"mysql_option_file,omitempty"`
+	NodeCredentials         []NodeCredentials `json:"node_credentials" yaml:"nodes,omitempty"`
+	BootstrapTimeoutSeconds int               `json:"bootstrap_timeout_seconds,omitempty" yaml:"bootstrap_timeout_seconds,omitempty"`
+	JoinTimeoutSeconds      int               `json:"join_timeout_seconds,omitempty" yaml:"join_timeout_seconds,omitempty"`
+	LockTTLSeconds          int               `json:"lock_ttl_seconds,omitempty" yaml:"lock_ttl_seconds,omitempty"`
+}
+
+// getClusterProfile returns the profile with the given name, or nil
+func (c *Config) getClusterProfile(name string) *ClusterProfile {
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == name {
+			return &c.Clusters[i]
+		}
+	}
+	return nil
+}
+
+// captureClusterProfile builds a profile from the top-level settings; the node last used goes first
+func (c *Config) captureClusterProfile(name string, seeds []string) ClusterProfile {
+	if c.LastNodeIP != "" {
+		ordered := []string{c.LastNodeIP}
+		for _, seed := range seeds {
+			if seed != c.LastNodeIP {
+				ordered = append(ordered, seed)
+			}
+		}
+		seeds = ordered
+	}
+
+	return ClusterProfile{
+		Name:                    name,
+		SeedNodes:               seeds,
+		LastSSHUsername:         c.LastSSHUsername,
+		LastMySQLUsername:       c.LastMySQLUsername,
+		LastCheckCoherence:      c.LastCheckCoherence,
+		LastCheckMySQL:          c.LastCheckMySQL,
+		EncryptedMySQLPassword:  c.EncryptedMySQLPassword,
+		HasSavedPassword:        c.HasSavedPassword,
+		MySQLPasswordRef:        c.MySQLPasswordRef,
+		MySQLLoginPath:          c.MySQLLoginPath,
+		MySQLOptionFile:         c.MySQLOptionFile,
+		NodeCredentials:         append([]NodeCredentials(nil), c.NodeCredentials...),
+		BootstrapTimeoutSeconds: c.BootstrapTimeoutSeconds,
+		JoinTimeoutSeconds:      c.JoinTimeoutSeconds,
+		LockTTLSeconds:          c.LockTTLSeconds,
+	}
+}
+
+// applyClusterProfile replaces the top-level settings with those of the profile
+func (c *Config) applyClusterProfile(profile *ClusterProfile) {
+	c.LastNodeIP = ""
+	if len(profile.SeedNodes) > 0 {
+		c.LastNodeIP = profile.SeedNodes[0]
+	}
+	c.LastSSHUsername = profile.LastSSHUsername
+	c.LastMySQLUsername = profile.LastMySQLUsername
+	c.LastCheckCoherence = profile.LastCheckCoherence
+	c.LastCheckMySQL = profile.LastCheckMySQL
+	c.EncryptedMySQLPassword = profile.EncryptedMySQLPassword
+	c.HasSavedPassword = profile.HasSavedPassword
+	c.MySQLPasswordRef = profile.MySQLPasswordRef
+	c.MySQLLoginPath = profile.MySQLLoginPath
+	c.MySQLOptionFile = profile.MySQLOptionFile
+	c.NodeCredentials = append([]NodeCredentials(nil), profile.NodeCredentials...)
+	c.BootstrapTimeoutSeconds = profile.BootstrapTimeoutSeconds
+	c.JoinTimeoutSeconds = profile.JoinTimeoutSeconds
+	c.LockTTLSeconds = profile.LockTTLSeconds
+}
+
+// storeActiveCluster writes the top-level settings back into the selected profile
+func (c *Config) storeActiveCluster() {
+	profile := c.getClusterProfile(c.activeCluster)
+	if profile == nil {
+		return
+	}
+	*profile = c.captureClusterProfile(profile.Name, profile.SeedNodes)
+}
+
+// selectCluster makes the named profile the working settings; the rest of the program is unaware of profiles
+func (c *Config) selectCluster(name string) error {
+	if name == "" {
+		return nil
+	}
+	profile := c.getClusterProfile(name)
+	if profile == nil {
+		return fmt.Errorf("unknown cluster %q (see 'galerahealth cluster list')", name)
+	}
+
+	if c.activeCluster != "" {
+		c.storeActiveCluster()
+	} else {
+		defaults := c.captureClusterProfile("", nil)
+		c.defaultSettings = &defaults
+	}
+	c.applyClusterProfile(profile)
+	c.activeCluster = name
+	return nil
+}
+
+// forSaving returns the config as it is written to disk, with the selected profile stored back
+// and the top-level settings restored
+func (c *Config) forSaving() *Config {
+	if c.activeCluster == "" {
+		return c
+	}
+	saved := *c
+	saved.Clusters = append([]ClusterProfile(nil), c.Clusters...)
+	saved.storeActiveCluster()
+	saved.applyClusterProfile(c.defaultSettings)
+	return &saved
+}
+
+// loadClusterConfig loads the configuration, selects the --cluster profile and applies the environment
+// and command line overrides, exiting on error
+func loadClusterConfig() *Config {
+	config := loadConfig()
+	if clusterName == fleetClusterName {
+		logMinimal("❌ --cluster %s is only supported for health checks", fleetClusterName)
+		os.Exit(1)
+	}
+	if err := config.selectCluster(clusterName); err != nil {
+		logMinimal("❌ %v", err)
+		os.Exit(1)
+	}
+	if clusterName != "" {
+		logNormal("📂 Using cluster profile %s", clusterName)
+	}
+	if err := applyEnvOverrides(config); err != nil {
+		logMinimal("❌ %v", err)
+		os.Exit(1)
+	}
+	applyCLIOptions(config)
+	return config
+}
+
+// validateClusterName rejects names that can't be used as a --cluster value
+func validateClusterName(name string) error {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid cluster name %q", name)
+	}
+	if name == fleetClusterName {
+		return fmt.Errorf("cluster name %q is reserved for checking every cluster", fleetClusterName)
+	}
+	return nil
+}
+
+// FleetResult is the outcome of checking one cluster profile
+type FleetResult struct {
+	Name   string
+	Health *ClusterHealth
+	Err    error
+}
+
+// seedNodes returns the nodes a non-interactive check starts from: the node given or last used,
+// then the seed nodes of the selected profile
+func (c *Config) seedNodes() []string {
+	var seeds []string
+	if c.LastNodeIP != "" {
+		seeds = append(seeds, c.LastNodeIP)
+	}
+	if profile := c.getClusterProfile(c.activeCluster); profile != nil {
+		for _, seed := range profile.SeedNodes {
+			if seed != c.LastNodeIP {
+				seeds = append(seeds, seed)
+			}
+		}
+	}
+	return seeds
+}
+
+// checkCluster runs a non-interactive health check, trying each seed node in turn. A quiet check
+// prints no per-node progress.
+func checkCluster(config *Config, quiet bool) (*ClusterAnalysis, error) {
+	seeds := config.seedNodes()
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("no seed nodes configured")
+	}
+
+	var initialNode *GaleraClusterInfo
+	var connInfo *SSHConnectionInfo
+	var lastErr error
+	for _, seed := range seeds {
+		var info *GaleraClusterInfo
+		var err error
+		if isLocalhost(seed) {
+			connInfo = &SSHConnectionInfo{Username: "local"}
+			info, err = getGaleraClusterInfoLocal(seed)
+		} else {
+			var executor NodeExecutor
+			executor, connInfo, err = openNodeExecutor(seed, config)
+			if err == nil {
+				info, err = getGaleraClusterInfo(executor, seed)
+			}
+		}
+		if err != nil {
+			logVerbose("Seed node %s unavailable: %v", seed, err)
+			lastErr = fmt.Errorf("%s: %v", seed, err)
+			continue
+		}
+		initialNode = info
+		config.LastNodeIP = seed
+		break
+	}
+	if initialNode == nil {
+		return nil, fmt.Errorf("no seed node reachable (last error: %v)", lastErr)
+	}
+	if connInfo == nil {
+		connInfo = &SSHConnectionInfo{Username: config.LastSSHUsername}
+	}
+
+	analysis, localhostNodeIP, err := runClusterAnalysis(initialNode, connInfo, config, quiet)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.LastCheckMySQL {
+		mysqlCreds := getMySQLCredentialsWithDefault(config.LastMySQLUsername, config, config.LastNodeIP)
+		if err := checkMySQLStatusOnAllNodes(analysis, connInfo, mysqlCreds, config, localhostNodeIP); err != nil {
+			logNormal("Warning: Error checking MySQL status: %v", err)
+		}
+	}
+
+	return analysis, nil
+}
+
+// runFleetCheck checks every cluster profile with saved settings and returns the result of each
+func runFleetCheck(config *Config) []FleetResult {
+	// Per-cluster details would drown the combined summary
+	previousReportMode, previousUseDefaults := reportMode, useDefaults
+	reportMode, useDefaults = true, true
+	defer func() { reportMode, useDefaults = previousReportMode, previousUseDefaults }()
+
+	var results []FleetResult
+	for _, profile := range config.Clusters {
+		logMinimal("🔍 Checking cluster %s...", profile.Name)
+		result := FleetResult{Name: profile.Name}
+		if err := config.selectCluster(profile.Name); err != nil {
+			result.Err = err
+		} else if analysis, err := checkCluster(config, true); err != nil {
+			result.Err = err
+		} else {
+			result.Health = evaluateClusterHealth(analysis)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// displayFleetSummary prints one line per cluster followed by the problems found
+func displayFleetSummary(results []FleetResult) {
+	fmt.Println()
+	fmt.Println("=== FLEET HEALTH SUMMARY ===")
+	fmt.Println()
+	fmt.Printf("%-20s %-7s %-8s %s\n", "CLUSTER", "NODES", "ACTIVE", "STATUS")
+
+	healthy := 0
+	for _, result := range results {
+		nodes, active, status := "-", "-", ""
+		switch {
+		case result.Err != nil:
+			status = "❌ Unreachable"
+		case len(result.Health.Issues) > 0:
+			status = fmt.Sprintf("❌ %d critical issue(s)", len(result.Health.Issues))
+		case len(result.Health.Warnings) > 0:
+			status = fmt.Sprintf("⚠️  %d warning(s)", len(result.Health.Warnings))
+		default:
+			status = "✅ Healthy"
+			healthy++
+		}
+		if result.Health != nil {
+			nodes = fmt.Sprintf("%d", result.Health.TotalNodes)
+			if result.Health.HasMySQLData {
+				active = fmt.Sprintf("%d/%d", result.Health.RespondingNodes, result.Health.TotalNodes)
+			}
+		}
+		fmt.Printf("%-20s %-7s %-8s %s\n", result.Name, nodes, active, status)
+	}
+
+	for _, result := range results {
+		if result.Err == nil && len(result.Health.Issues) == 0 && len(result.Health.Warnings) == 0 {
+			continue
+		}
+		fmt.Println()
+		fmt.Printf("%s:\n", result.Name)
+		if result.Err != nil {
+			fmt.Printf("   ❌ %v\n", result.Err)
+			continue
+		}
+		for _, issue := range result.Health.Issues {
+			fmt.Printf("   ❌ %s\n", issue)
+		}
+		for _, warning := range result.Health.Warnings {
+			fmt.Printf("   ⚠️  %s\n", warning)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("📊 %d/%d clusters healthy\n", healthy, len(results))
+}
+
+// runClusterCommand implements the "cluster" subcommand
+func runClusterCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: galerahealth cluster <list|add|remove>")
+	}
+
+	config := loadConfig()
+	switch args[0] {
+	case "list":
+		if len(config.Clusters) == 0 {
+			logMinimal("No cluster profiles configured (add one with 'galerahealth cluster add <name>')")
+			return nil
+		}
+		fmt.Printf("%-20s %-10s %-6s %s\n", "CLUSTER", "SSH USER", "NODES", "SEED NODES")
+		for _, profile := range config.Clusters {
+			fmt.Printf("%-20s %-10s %-6d %s\n", profile.Name, profile.LastSSHUsername,
+				len(profile.NodeCredentials), strings.Join(profile.SeedNodes, ","))
+		}
+		return nil
+
+	case "add":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: galerahealth cluster add <name> [--seed ip1,ip2] [--ssh-user user] [--mysql-user user]")
+		}
+		name := args[1]
+		if err := validateClusterName(name); err != nil {
+			return err
+		}
+		if config.getClusterProfile(name) != nil {
+			return fmt.Errorf("cluster %q already exists", name)
+		}
+
+		// --ssh-user and --mysql-user are global flags, parsed into cliOptions
+		var seeds []string
+		for i := 2; i < len(args); i++ {
+			if args[i] != "--seed" || i+1 >= len(args) {
+				return fmt.Errorf("unknown cluster add option: %s", args[i])
+			}
+			i++
+			for _, ip := range strings.Split(args[i], ",") {
+				if ip = strings.TrimSpace(ip); ip != "" {
+					seeds = append(seeds, ip)
+				}
+			}
+		}
+
+		var profile ClusterProfile
+		if len(seeds) == 0 {
+			// Import the settings used so far, so existing single-cluster setups keep their credentials
+			if config.LastNodeIP == "" {
+				return fmt.Errorf("no --seed nodes given and no saved node to import")
+			}
+			profile = config.captureClusterProfile(name, nil)
+			logMinimal("📥 Imported saved settings for node %s into cluster %s", config.LastNodeIP, name)
+		} else {
+			profile = ClusterProfile{Name: name, SeedNodes: seeds, LastCheckCoherence: true}
+		}
+		if cliOptions.SSHUser != "" {
+			profile.LastSSHUsername = cliOptions.SSHUser
+		}
+		if cliOptions.MySQLUser != "" {
+			profile.LastMySQLUsername = cliOptions.MySQLUser
+		}
+		config.Clusters = append(config.Clusters, profile)
+		if err := saveConfig(config); err != nil {
+			return err
+		}
+		logMinimal("✅ Added cluster %s (seed nodes: %s)", name, strings.Join(profile.SeedNodes, ","))
+		return nil
+
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: galerahealth cluster remove <name>")
+		}
+		for i := range config.Clusters {
+			if config.Clusters[i].Name == args[1] {
+				config.Clusters = append(config.Clusters[:i], config.Clusters[i+1:]...)
+				if err := saveConfig(config); err != nil {
+					return err
+				}
+				logMinimal("✅ Removed cluster %s", args[1])
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown cluster %q", args[1])
+
+	default:
+		return fmt.Errorf("unknown cluster command: %s", args[0])
+	}
+}
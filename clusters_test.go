@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectClusterAndSave(t *testing.T) {
+	config := &Config{
+		LastNodeIP:      "192.168.1.10",
+		LastSSHUsername: "admin",
+		Clusters: []ClusterProfile{
+			{Name: "prod", SeedNodes: []string{"10.0.0.1", "10.0.0.2"}, LastSSHUsername: "root", JoinTimeoutSeconds: 600},
+			{Name: "staging", SeedNodes: []string{"10.1.0.1"}, LastSSHUsername: "deploy"},
+		},
+	}
+
+	if err := config.selectCluster("dr"); err == nil {
+		t.Error("expected an error selecting an unknown cluster")
+	}
+	if err := config.selectCluster("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if config.LastNodeIP != "10.0.0.1" || config.LastSSHUsername != "root" || config.JoinTimeoutSeconds != 600 {
+		t.Errorf("profile not applied: node %s, user %s, join timeout %d", config.LastNodeIP, config.LastSSHUsername, config.JoinTimeoutSeconds)
+	}
+
+	// The run connects through the second seed and saves its credentials
+	config.LastNodeIP = "10.0.0.2"
+	if err := config.setNodeCredentials("10.0.0.2", "root", "", "", "", true); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := config.forSaving()
+	if saved.LastNodeIP != "192.168.1.10" || saved.LastSSHUsername != "admin" || len(saved.NodeCredentials) != 0 {
+		t.Errorf("top-level settings not restored: %+v", saved)
+	}
+	prod := saved.getClusterProfile("prod")
+	if strings.Join(prod.SeedNodes, ",") != "10.0.0.2,10.0.0.1" {
+		t.Errorf("prod seed nodes = %v, want the node last used first", prod.SeedNodes)
+	}
+	if len(prod.NodeCredentials) != 1 || prod.NodeCredentials[0].NodeIP != "10.0.0.2" {
+		t.Errorf("prod credentials not stored: %+v", prod.NodeCredentials)
+	}
+	if len(config.getClusterProfile("prod").NodeCredentials) != 0 {
+		t.Error("forSaving modified the working config")
+	}
+
+	// Switching profiles keeps the changes made to the previous one
+	if err := config.selectCluster("staging"); err != nil {
+		t.Fatal(err)
+	}
+	if config.LastSSHUsername != "deploy" || len(config.NodeCredentials) != 0 {
+		t.Errorf("staging profile not applied: user %s, %d credentials", config.LastSSHUsername, len(config.NodeCredentials))
+	}
+	if len(config.getClusterProfile("prod").NodeCredentials) != 1 {
+		t.Error("prod credentials lost when switching clusters")
+	}
+	if saved := config.forSaving(); saved.LastNodeIP != "192.168.1.10" {
+		t.Errorf("top-level node after switching = %s, want 192.168.1.10", saved.LastNodeIP)
+	}
+}
+
+func TestFleetCheck(t *testing.T) {
+	node := func(ip, clusterName string, members ...string) *fakeNode {
+		return &fakeNode{
+			IP:      ip,
+			Service: "active",
+			Files:   map[string]string{"/etc/mysql/my.cnf": galeraConfig(clusterName, ip, members...)},
+		}
+	}
+	prod := []string{"10.0.0.1", "10.0.0.2"}
+	staging := []string{"10.1.0.1", "10.1.0.2"}
+	newFakeCluster(t,
+		node("10.0.0.1", "prod", prod...), node("10.0.0.2", "prod", prod...),
+		node("10.1.0.1", "staging", staging...), node("10.1.0.2", "other", staging...),
+	)
+
+	config := newTestConfig(t)
+	config.Clusters = []ClusterProfile{
+		// The first prod seed is down, the second one is used instead
+		{Name: "prod", SeedNodes: []string{"10.0.0.9", "10.0.0.2"}, LastSSHUsername: "root"},
+		{Name: "staging", SeedNodes: []string{"10.1.0.1"}, LastSSHUsername: "root"},
+		{Name: "dr", SeedNodes: []string{"10.2.0.1"}, LastSSHUsername: "root"},
+	}
+
+	results := runFleetCheck(config)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+
+	if results[0].Err != nil || len(results[0].Health.Issues) != 0 || results[0].Health.TotalNodes != 2 {
+		t.Errorf("prod: %+v (err %v)", results[0].Health, results[0].Err)
+	}
+	if results[1].Err != nil || len(results[1].Health.Issues) == 0 {
+		t.Errorf("staging: expected incoherent configuration, got %+v (err %v)", results[1].Health, results[1].Err)
+	}
+	if results[2].Err == nil || !strings.Contains(results[2].Err.Error(), "no seed node reachable") {
+		t.Errorf("dr: expected an unreachable error, got %v", results[2].Err)
+	}
+
+	if seeds := config.forSaving().getClusterProfile("prod").SeedNodes; seeds[0] != "10.0.0.2" {
+		t.Errorf("prod seed nodes = %v, want the reachable seed first", seeds)
+	}
+}
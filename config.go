@@ -7,12 +7,12 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // External variable for -y option (defined in main.go)
@@ -24,40 +24,84 @@ var reportMode bool
 // External variable for -r option (recovery mode - attempt cluster recovery)
 var runMode bool
 
+// External variable for --lock-wait option (how long to wait for another run's recovery lock)
+var lockWait time.Duration
+
+// External variable for --break-lock option (remove stale recovery locks)
+var breakLock bool
+
+// stdinScanner reads interactive answers; it is shared so buffered piped input is not lost between prompts
+var stdinScanner = bufio.NewScanner(os.Stdin)
+
 // NodeCredentials holds SSH and MySQL credentials for a specific node
 type NodeCredentials struct {
-	NodeIP                 string `json:"node_ip"`
-	SSHUsername            string `json:"ssh_username"`
-	MySQLUsername          string `json:"mysql_username"`
-	EncryptedSSHPassword   string `json:"encrypted_ssh_password,omitempty"`
-	EncryptedMySQLPassword string `json:"encrypted_mysql_password,omitempty"`
-	HasSSHPassword         bool   `json:"has_ssh_password"`
-	HasMySQLPassword       bool   `json:"has_mysql_password"`
-	UsesSSHKeys            bool   `json:"uses_ssh_keys"`
+	NodeIP                 string          `json:"node_ip" yaml:"ip"`
+	SSHUsername            string          `json:"ssh_username" yaml:"ssh_user,omitempty"`
+	MySQLUsername          string          `json:"mysql_username" yaml:"mysql_user,omitempty"`
+	EncryptedSSHPassword   string          `json:"encrypted_ssh_password,omitempty" yaml:"encrypted_ssh_password,omitempty"`
+	EncryptedMySQLPassword string          `json:"encrypted_mysql_password,omitempty" yaml:"encrypted_mysql_password,omitempty"`
+	HasSSHPassword         bool            `json:"has_ssh_password" yaml:"has_ssh_password,omitempty"`
+	HasMySQLPassword       bool            `json:"has_mysql_password" yaml:"has_mysql_password,omitempty"`
+	UsesSSHKeys            bool            `json:"uses_ssh_keys" yaml:"uses_ssh_keys,omitempty"`
+	SSHPasswordRef         string          `json:"ssh_password_ref,omitempty" yaml:"ssh_password_ref,omitempty"`     // SSH password reference (env:NAME or file:/path)
+	MySQLPasswordRef       string          `json:"mysql_password_ref,omitempty" yaml:"mysql_password_ref,omitempty"` // MySQL password reference (env:NAME or file:/path)
+	MySQLLoginPath         string          `json:"mysql_login_path,omitempty" yaml:"mysql_login_path,omitempty"`     // Login path on the node (mysql_config_editor), tried first
+	MySQLOptionFile        bool            `json:"mysql_option_file,omitempty" yaml:"mysql_option_file,omitempty"`   // Use ~/.my.cnf on the node, tried first
+	Service                *ServiceBackend `json:"service,omitempty" yaml:"service,omitempty"`                       // How MySQL/MariaDB is started/stopped on this node
+	Transport              *NodeTransport  `json:"transport,omitempty" yaml:"transport,omitempty"`                   // How commands reach this node (SSH or docker/podman exec)
+	LastSSHSuccess         time.Time       `json:"last_ssh_success,omitempty" yaml:"last_ssh_success,omitempty"`     // Last time the saved SSH credentials worked
+	LastMySQLSuccess       time.Time       `json:"last_mysql_success,omitempty" yaml:"last_mysql_success,omitempty"` // Last time the MySQL credentials worked on this node
+}
+
+// OutputSettings controls how much is printed
+type OutputSettings struct {
+	Verbosity int  `json:"verbosity,omitempty" yaml:"verbosity,omitempty"` // 0-3, same as -v/-vv/-vvv
+	Summary   bool `json:"summary,omitempty" yaml:"summary,omitempty"`     // Only print the final summary in automated (-y) runs
 }
 
 // Config represents the application configuration
 type Config struct {
-	LastNodeIP             string            `json:"last_node_ip"`
-	LastSSHUsername        string            `json:"last_ssh_username"`
-	LastMySQLUsername      string            `json:"last_mysql_username"`
-	LastCheckCoherence     bool              `json:"last_check_coherence"`
-	LastCheckMySQL         bool              `json:"last_check_mysql"`
-	EncryptedMySQLPassword string            `json:"encrypted_mysql_password,omitempty"` // Deprecated, kept for backward compatibility
-	HasSavedPassword       bool              `json:"has_saved_password"`                 // Deprecated, kept for backward compatibility
-	NodeCredentials        []NodeCredentials `json:"node_credentials"`                   // New: per-node credentials
+	LastNodeIP              string            `json:"last_node_ip" yaml:"node,omitempty"`
+	LastSSHUsername         string            `json:"last_ssh_username" yaml:"ssh_user,omitempty"`
+	LastMySQLUsername       string            `json:"last_mysql_username" yaml:"mysql_user,omitempty"`
+	LastCheckCoherence      bool              `json:"last_check_coherence" yaml:"check_coherence"`
+	LastCheckMySQL          bool              `json:"last_check_mysql" yaml:"check_mysql"`
+	EncryptedMySQLPassword  string            `json:"encrypted_mysql_password,omitempty" yaml:"encrypted_mysql_password,omitempty"`   // Deprecated, kept for backward compatibility
+	HasSavedPassword        bool              `json:"has_saved_password" yaml:"has_saved_password,omitempty"`                         // Deprecated, kept for backward compatibility
+	MySQLPasswordRef        string            `json:"mysql_password_ref,omitempty" yaml:"mysql_password_ref,omitempty"`               // Cluster-wide MySQL password reference (env:NAME or file:/path)
+	MySQLLoginPath          string            `json:"mysql_login_path,omitempty" yaml:"mysql_login_path,omitempty"`                   // Cluster-wide login path on the nodes (mysql_config_editor)
+	MySQLOptionFile         bool              `json:"mysql_option_file,omitempty" yaml:"mysql_option_file,omitempty"`                 // Use ~/.my.cnf on the nodes as the cluster default
+	NodeCredentials         []NodeCredentials `json:"node_credentials" yaml:"nodes,omitempty"`                                        // New: per-node credentials
+	BootstrapTimeoutSeconds int               `json:"bootstrap_timeout_seconds,omitempty" yaml:"bootstrap_timeout_seconds,omitempty"` // Max wait for the bootstrap node to reach Synced
+	JoinTimeoutSeconds      int               `json:"join_timeout_seconds,omitempty" yaml:"join_timeout_seconds,omitempty"`           // Max wait for each joiner to reach Synced (includes SST)
+	AuditLogPath            string            `json:"audit_log_path,omitempty" yaml:"audit_log_path,omitempty"`                       // Audit log of state-changing actions (default ~/.galerahealth-audit.jsonl)
+	AuditSyslog             bool              `json:"audit_syslog,omitempty" yaml:"audit_syslog,omitempty"`                           // Also send audit entries to syslog
+	LockTTLSeconds          int               `json:"lock_ttl_seconds,omitempty" yaml:"lock_ttl_seconds,omitempty"`                   // Expiry of the cluster-wide recovery lock
+	MaxClockSkewMs          int               `json:"max_clock_skew_ms,omitempty" yaml:"max_clock_skew_ms,omitempty"`                 // Clock offset reported as a warning (default 1000)
+	SecretsBackend          string            `json:"secrets_backend,omitempty" yaml:"secrets_backend,omitempty"`                     // How saved passwords are encrypted (legacy, passphrase, keyring, env, file)
+	SecretsKDF              *SecretsKDF       `json:"secrets_kdf,omitempty" yaml:"secrets_kdf,omitempty"`                             // Key derivation parameters for the passphrase backend
+	SecretsKeyFile          string            `json:"secrets_key_file,omitempty" yaml:"secrets_key_file,omitempty"`                   // Key file for the file backend (default ~/.galerahealth.key)
+	SecretsKeyCheck         string            `json:"secrets_key_check,omitempty" yaml:"secrets_key_check,omitempty"`                 // Known value encrypted with the master key, detects a wrong key
+	Clusters                []ClusterProfile  `json:"clusters,omitempty" yaml:"clusters,omitempty"`                                   // Named cluster profiles selected with --cluster
+	Output                  OutputSettings    `json:"output,omitempty" yaml:"output,omitempty"`                                       // How results are printed
+
+	activeCluster   string          // Profile selected with --cluster, its settings are in the top-level fields
+	defaultSettings *ClusterProfile // Top-level settings set aside while a profile is selected
 }
 
-// getConfigPath returns the path to the configuration file
+// getConfigPath returns the path to the configuration file (~/.galerahealth.yaml, or $GALERAHEALTH_CONFIG)
 func getConfigPath() string {
+	if path := os.Getenv(configPathEnv); path != "" {
+		return path
+	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return ""
 	}
-	return filepath.Join(homeDir, ".galerahealth")
+	return filepath.Join(homeDir, ".galerahealth.yaml")
 }
 
-// generateKey generates a key from the node IP for encryption
+// generateKey generates a key from the node IP for encryption (legacy secrets backend)
 func generateKey(nodeIP string) []byte {
 	hash := sha256.Sum256([]byte("galerahealth:" + nodeIP))
 	return hash[:]
@@ -125,7 +169,7 @@ func decryptPassword(encryptedPassword, nodeIP string) (string, error) {
 	return string(plaintext), nil
 }
 
-// loadConfig loads configuration from ~/.galerahealth
+// loadConfig loads configuration from the YAML configuration file, migrating the old JSON file if needed
 func loadConfig() *Config {
 	configPath := getConfigPath()
 	if configPath == "" {
@@ -134,44 +178,36 @@ func loadConfig() *Config {
 
 	// Check if config file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if os.Getenv(configPathEnv) == "" {
+			config, err := migrateLegacyConfig(configPath)
+			if err != nil {
+				logNormal("Warning: Could not migrate old configuration: %v", err)
+			} else if config != nil {
+				return config
+			}
+		}
 		return &Config{} // Return empty config if file doesn't exist
 	}
 
-	// Read and parse config file
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		logNormal("Warning: Could not read config file: %v", err)
-		return &Config{}
+	// A hand-edited file that can't be parsed must not be overwritten with an empty config
+	config, err := readConfigFile(configPath, false)
+	if config == nil || err != nil {
+		logMinimal("❌ Could not parse config file %s: %v", configPath, err)
+		logMinimal("   Fix it or check it with: galerahealth config validate")
+		os.Exit(1)
 	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		logNormal("Warning: Could not parse config file: %v", err)
-		return &Config{}
-	}
-
-	return &config
+	return config
 }
 
-// saveConfig saves configuration to ~/.galerahealth
+// saveConfig saves configuration to the YAML configuration file
 func saveConfig(config *Config) error {
 	configPath := getConfigPath()
 	if configPath == "" {
 		return fmt.Errorf("could not determine home directory")
 	}
 
-	// Convert config to JSON
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("could not marshal config: %v", err)
-	}
-
-	// Write to file
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
-		return fmt.Errorf("could not write config file: %v", err)
-	}
-
-	return nil
+	return writeConfigFile(configPath, config.forSaving())
 }
 
 // clearConfig removes the configuration file
@@ -218,9 +254,8 @@ func promptForInputWithDefault(message, defaultValue string) string {
 		fmt.Print(message + ": ")
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	input := strings.TrimSpace(scanner.Text())
+	stdinScanner.Scan()
+	input := strings.TrimSpace(stdinScanner.Text())
 
 	if input == "" && defaultValue != "" {
 		return defaultValue
@@ -260,6 +295,16 @@ func (c *Config) getNodeCredentials(nodeIP string) *NodeCredentials {
 	return nil
 }
 
+// saveSSHConnection records how a node was reached and reports whether its password was saved.
+// Passwords read from ssh_password_ref or --ssh-password-file stay out of the configuration.
+func (c *Config) saveSSHConnection(nodeIP string, connInfo *SSHConnectionInfo) (bool, error) {
+	sshPassword := ""
+	if connInfo.HasPassword && !connInfo.PasswordFromRef {
+		sshPassword = connInfo.Password
+	}
+	return sshPassword != "", c.setNodeCredentials(nodeIP, connInfo.Username, "", sshPassword, "", connInfo.UsedKeys)
+}
+
 // setNodeCredentials saves or updates credentials for a specific node
 func (c *Config) setNodeCredentials(nodeIP string, sshUsername, mysqlUsername, sshPassword, mysqlPassword string, usesSSHKeys bool) error {
 	// Find existing credentials or create new ones
@@ -277,14 +322,16 @@ func (c *Config) setNodeCredentials(nodeIP string, sshUsername, mysqlUsername, s
 		creds = &c.NodeCredentials[len(c.NodeCredentials)-1]
 	}
 
-	// Update credentials
+	// Update credentials; an empty MySQL username keeps the saved one (SSH logins don't know it)
 	creds.SSHUsername = sshUsername
-	creds.MySQLUsername = mysqlUsername
+	if mysqlUsername != "" {
+		creds.MySQLUsername = mysqlUsername
+	}
 	creds.UsesSSHKeys = usesSSHKeys
 
 	// Encrypt and store SSH password if provided
 	if sshPassword != "" {
-		encryptedSSH, err := encryptPassword(sshPassword, nodeIP)
+		encryptedSSH, err := c.encryptSecret(sshPassword, nodeIP)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt SSH password: %v", err)
 		}
@@ -294,7 +341,7 @@ func (c *Config) setNodeCredentials(nodeIP string, sshUsername, mysqlUsername, s
 
 	// Encrypt and store MySQL password if provided
 	if mysqlPassword != "" {
-		encryptedMySQL, err := encryptPassword(mysqlPassword, nodeIP)
+		encryptedMySQL, err := c.encryptSecret(mysqlPassword, nodeIP)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt MySQL password: %v", err)
 		}
@@ -308,19 +355,25 @@ func (c *Config) setNodeCredentials(nodeIP string, sshUsername, mysqlUsername, s
 // getNodeSSHPassword retrieves and decrypts SSH password for a specific node
 func (c *Config) getNodeSSHPassword(nodeIP string) (string, error) {
 	creds := c.getNodeCredentials(nodeIP)
+	if creds != nil && creds.SSHPasswordRef != "" {
+		return resolveSecretRef(creds.SSHPasswordRef)
+	}
 	if creds == nil || !creds.HasSSHPassword {
 		return "", nil
 	}
 
-	return decryptPassword(creds.EncryptedSSHPassword, nodeIP)
+	return c.decryptSecret(creds.EncryptedSSHPassword, nodeIP)
 }
 
 // getNodeMySQLPassword retrieves and decrypts MySQL password for a specific node
 func (c *Config) getNodeMySQLPassword(nodeIP string) (string, error) {
 	creds := c.getNodeCredentials(nodeIP)
+	if creds != nil && creds.MySQLPasswordRef != "" {
+		return resolveSecretRef(creds.MySQLPasswordRef)
+	}
 	if creds == nil || !creds.HasMySQLPassword {
 		return "", nil
 	}
 
-	return decryptPassword(creds.EncryptedMySQLPassword, nodeIP)
+	return c.decryptSecret(creds.EncryptedMySQLPassword, nodeIP)
 }
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configPathEnv    = "GALERAHEALTH_CONFIG"  // Overrides the configuration file location
+	clusterEnv       = "GALERAHEALTH_CLUSTER" // Default for --cluster
+	configFileHeader = "GaleraHealth configuration. Hand edits are kept when galerahealth saves new answers.\n" +
+		"Precedence: built-in defaults < this file < GALERAHEALTH_* environment variables < command line flags.\n" +
+		"Check it with: galerahealth config validate"
+)
+
+// envOverrides are the GALERAHEALTH_* variables that override settings from the configuration file
+var envOverrides = []struct {
+	name  string
+	apply func(c *Config, value string) error
+}{
+	{"GALERAHEALTH_NODE", func(c *Config, value string) error { c.LastNodeIP = value; return nil }},
+	{"GALERAHEALTH_SSH_USER", func(c *Config, value string) error { c.LastSSHUsername = value; return nil }},
+	{"GALERAHEALTH_MYSQL_USER", func(c *Config, value string) error { c.LastMySQLUsername = value; return nil }},
+	{"GALERAHEALTH_MYSQL_PASSWORD_REF", func(c *Config, value string) error { c.MySQLPasswordRef = value; return nil }},
+	{"GALERAHEALTH_BOOTSTRAP_TIMEOUT", func(c *Config, value string) error { return parseEnvInt(value, &c.BootstrapTimeoutSeconds) }},
+	{"GALERAHEALTH_JOIN_TIMEOUT", func(c *Config, value string) error { return parseEnvInt(value, &c.JoinTimeoutSeconds) }},
+	{"GALERAHEALTH_LOCK_TTL", func(c *Config, value string) error { return parseEnvInt(value, &c.LockTTLSeconds) }},
+	{"GALERAHEALTH_MAX_CLOCK_SKEW_MS", func(c *Config, value string) error { return parseEnvInt(value, &c.MaxClockSkewMs) }},
+	{"GALERAHEALTH_AUDIT_LOG", func(c *Config, value string) error { c.AuditLogPath = value; return nil }},
+	{"GALERAHEALTH_LOG_LEVEL", func(c *Config, value string) error { return parseEnvInt(value, &c.Output.Verbosity) }},
+	{"GALERAHEALTH_SUMMARY", func(c *Config, value string) error {
+		summary, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false")
+		}
+		c.Output.Summary = summary
+		return nil
+	}},
+}
+
+// parseEnvInt parses a non-negative integer setting
+func parseEnvInt(value string, target *int) error {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return fmt.Errorf("expected a non-negative number")
+	}
+	*target = number
+	return nil
+}
+
+// applyEnvOverrides applies the GALERAHEALTH_* environment variables on top of the file settings
+func applyEnvOverrides(config *Config) error {
+	for _, override := range envOverrides {
+		value, ok := os.LookupEnv(override.name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := override.apply(config, value); err != nil {
+			return fmt.Errorf("invalid %s=%q: %v", override.name, value, err)
+		}
+		logDebug("Setting overridden by %s", override.name)
+	}
+	return nil
+}
+
+// getLegacyConfigPath returns the path of the JSON configuration written by older versions
+func getLegacyConfigPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".galerahealth")
+}
+
+// readConfigFile parses a YAML configuration file; strict mode rejects unknown keys. On a
+// *yaml.TypeError the values that could be decoded are returned with the error.
+func readConfigFile(path string, strict bool) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(strict)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return config, err
+		}
+		return nil, err
+	}
+	return config, nil
+}
+
+// writeConfigFile writes the configuration as YAML, keeping the comments and key order of the existing file
+func writeConfigFile(path string, config *Config) error {
+	var updated yaml.Node
+	if err := updated.Encode(config); err != nil {
+		return fmt.Errorf("could not encode config: %v", err)
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, HeadComment: configFileHeader, Content: []*yaml.Node{&updated}}
+	if data, err := os.ReadFile(path); err == nil {
+		var existing yaml.Node
+		if yaml.Unmarshal(data, &existing) == nil && len(existing.Content) > 0 && existing.Content[0].Kind == yaml.MappingNode {
+			mergeYAMLNode(existing.Content[0], &updated)
+			doc = &existing
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("could not encode config: %v", err)
+	}
+	encoder.Close()
+
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("could not write config file: %v", err)
+	}
+	return nil
+}
+
+// mergeYAMLNode copies the values of src into dst, keeping the comments, styles and key order of dst
+func mergeYAMLNode(dst, src *yaml.Node) {
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		used := make(map[string]bool)
+		var content []*yaml.Node
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			key := dst.Content[i].Value
+			if value := yamlMappingValue(src, key); value != nil {
+				mergeYAMLNode(dst.Content[i+1], value)
+				content = append(content, dst.Content[i], dst.Content[i+1])
+				used[key] = true
+			}
+		}
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			if !used[src.Content[i].Value] {
+				content = append(content, src.Content[i], src.Content[i+1])
+			}
+		}
+		dst.Content = content
+
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		// Items are matched by their ip/name (or scalar value), so comments follow the node they describe
+		used := make(map[*yaml.Node]bool)
+		var content []*yaml.Node
+		for _, item := range src.Content {
+			var match *yaml.Node
+			for _, candidate := range dst.Content {
+				if !used[candidate] && yamlItemID(candidate) == yamlItemID(item) {
+					match = candidate
+					break
+				}
+			}
+			if match == nil {
+				content = append(content, item)
+				continue
+			}
+			used[match] = true
+			mergeYAMLNode(match, item)
+			content = append(content, match)
+		}
+		dst.Content = content
+
+	case dst.Kind == yaml.ScalarNode && src.Kind == yaml.ScalarNode && dst.Value == src.Value:
+		// Unchanged, keep the original quoting
+
+	default:
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+	}
+}
+
+// yamlMappingValue returns the value of key in a mapping node, or nil
+func yamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlItemID identifies a sequence item: nodes by ip, clusters by name, scalars by value
+func yamlItemID(item *yaml.Node) string {
+	if item.Kind == yaml.ScalarNode {
+		return item.Value
+	}
+	for _, key := range []string{"ip", "name"} {
+		if value := yamlMappingValue(item, key); value != nil {
+			return key + "=" + value.Value
+		}
+	}
+	return ""
+}
+
+// migrateLegacyConfig converts the JSON configuration of older versions to YAML at path
+func migrateLegacyConfig(path string) (*Config, error) {
+	legacyPath := getLegacyConfigPath()
+	info, err := os.Stat(legacyPath)
+	if err != nil || info.IsDir() {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", legacyPath, err)
+	}
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", legacyPath, err)
+	}
+
+	if err := writeConfigFile(path, config); err != nil {
+		return nil, err
+	}
+	backupPath := legacyPath + ".json.bak"
+	if err := os.Rename(legacyPath, backupPath); err != nil {
+		return nil, fmt.Errorf("could not move %s aside: %v", legacyPath, err)
+	}
+
+	logMinimal("📦 Migrated configuration from %s to %s (old file kept as %s)", legacyPath, path, backupPath)
+	return config, nil
+}
+
+// loadOutputSettings returns the output settings from the configuration file and environment,
+// before the rest of the configuration is loaded
+func loadOutputSettings() OutputSettings {
+	config, err := readConfigFile(getConfigPath(), false)
+	if err != nil {
+		config = &Config{}
+	}
+	if err := applyEnvOverrides(config); err != nil {
+		logMinimal("⚠️  %v", err)
+	}
+	return config.Output
+}
+
+// validateConfig checks a loaded configuration for values galerahealth can't use
+func validateConfig(config *Config) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if config.Output.Verbosity < 0 || config.Output.Verbosity > 3 {
+		add("output.verbosity must be between 0 and 3, got %d", config.Output.Verbosity)
+	}
+	switch config.SecretsBackend {
+	case "", SecretsBackendLegacy, SecretsBackendPassphrase, SecretsBackendKeyring, SecretsBackendEnv, SecretsBackendFile:
+	default:
+		add("unknown secrets_backend %q", config.SecretsBackend)
+	}
+	if config.SecretsKDF != nil && config.SecretsKDF.Algorithm != KDFArgon2id && config.SecretsKDF.Algorithm != KDFScrypt {
+		add("unknown secrets_kdf.algorithm %q", config.SecretsKDF.Algorithm)
+	}
+	if config.SecretsBackend == SecretsBackendPassphrase && config.SecretsKDF == nil {
+		add("secrets_backend passphrase requires secrets_kdf (run 'galerahealth secrets migrate --backend passphrase')")
+	}
+
+	type setting struct {
+		name  string
+		value int
+	}
+	validateSettings := func(scope string, ref string, settings []setting, nodes []NodeCredentials) {
+		if ref != "" {
+			if err := validateSecretRef(ref); err != nil {
+				add("%smysql_password_ref: %v", scope, err)
+			}
+		}
+		for _, s := range settings {
+			if s.value < 0 {
+				add("%s%s must not be negative", scope, s.name)
+			}
+		}
+
+		seen := make(map[string]bool)
+		for i, node := range nodes {
+			where := fmt.Sprintf("%snodes[%d]", scope, i)
+			if node.NodeIP == "" {
+				add("%s: ip is required", where)
+				continue
+			}
+			where = fmt.Sprintf("%snodes[%s]", scope, node.NodeIP)
+			if seen[node.NodeIP] {
+				add("%s: node listed more than once", where)
+			}
+			seen[node.NodeIP] = true
+			if node.SSHPasswordRef != "" {
+				if err := validateSecretRef(node.SSHPasswordRef); err != nil {
+					add("%s.ssh_password_ref: %v", where, err)
+				}
+			}
+			if node.MySQLPasswordRef != "" {
+				if err := validateSecretRef(node.MySQLPasswordRef); err != nil {
+					add("%s.mysql_password_ref: %v", where, err)
+				}
+			}
+			if transport := node.Transport; transport != nil {
+				switch transport.Type {
+				case "", TransportSSH:
+				case TransportDocker, TransportPodman:
+					if transport.Container == "" {
+						add("%s.transport: container is required for %s", where, transport.Type)
+					}
+				default:
+					add("%s.transport: unknown type %q", where, transport.Type)
+				}
+			}
+			if service := node.Service; service != nil {
+				switch service.Manager {
+				case ServiceManagerSystemd, ServiceManagerSysV, ServiceManagerOpenRC, ServiceManagerCustom, ServiceManagerDocker, ServiceManagerPodman:
+				default:
+					add("%s.service: unknown manager %q", where, service.Manager)
+				}
+			}
+		}
+	}
+
+	validateSettings("", config.MySQLPasswordRef, []setting{
+		{"bootstrap_timeout_seconds", config.BootstrapTimeoutSeconds},
+		{"join_timeout_seconds", config.JoinTimeoutSeconds},
+		{"lock_ttl_seconds", config.LockTTLSeconds},
+		{"max_clock_skew_ms", config.MaxClockSkewMs},
+	}, config.NodeCredentials)
+
+	names := make(map[string]bool)
+	for i, profile := range config.Clusters {
+		scope := fmt.Sprintf("clusters[%s].", profile.Name)
+		if err := validateClusterName(profile.Name); err != nil {
+			add("clusters[%d]: %v", i, err)
+		} else if names[profile.Name] {
+			add("clusters[%s]: cluster defined more than once", profile.Name)
+		}
+		names[profile.Name] = true
+		if len(profile.SeedNodes) == 0 {
+			add("%sseed_nodes: at least one seed node is required", scope)
+		}
+		validateSettings(scope, profile.MySQLPasswordRef, []setting{
+			{"bootstrap_timeout_seconds", profile.BootstrapTimeoutSeconds},
+			{"join_timeout_seconds", profile.JoinTimeoutSeconds},
+			{"lock_ttl_seconds", profile.LockTTLSeconds},
+		}, profile.NodeCredentials)
+	}
+
+	return problems
+}
+
+// validateSecretRef checks that a password reference can be resolved
+func validateSecretRef(ref string) error {
+	kind, target, _ := strings.Cut(ref, ":")
+	if kind == "file" && target != "" {
+		if _, err := os.Stat(target); err != nil {
+			return fmt.Errorf("password file %s: %v", target, err)
+		}
+		return nil
+	}
+	if kind == "env" && target != "" {
+		return nil // May only be set when galerahealth runs
+	}
+	return fmt.Errorf("invalid password reference %q (use env:NAME or file:/path)", ref)
+}
+
+// runConfigCommand implements the "config" subcommand
+func runConfigCommand(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return fmt.Errorf("usage: galerahealth config validate [file]")
+	}
+
+	path := getConfigPath()
+	if len(args) > 1 {
+		path = args[1]
+	} else if _, err := os.Stat(path); os.IsNotExist(err) {
+		loadConfig() // Migrates the old JSON configuration, if any
+	}
+
+	// Unknown keys are usually typos; report them together with the other problems
+	var problems []string
+	config, err := readConfigFile(path, true)
+	if config == nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if err != nil {
+		problems = append(problems, err.(*yaml.TypeError).Errors...)
+	}
+	problems = append(problems, validateConfig(config)...)
+	var envConfig Config
+	if err := applyEnvOverrides(&envConfig); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		logMinimal("❌ %d problem(s) in %s:", len(problems), path)
+		for _, problem := range problems {
+			logMinimal("   - %s", problem)
+		}
+		return fmt.Errorf("configuration is not valid")
+	}
+
+	logMinimal("✅ %s is valid (%d nodes, %d clusters)", path, len(config.NodeCredentials), len(config.Clusters))
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTempHome points the configuration file locations to a temporary home directory
+func useTempHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(configPathEnv, "")
+	return home
+}
+
+func TestLegacyConfigMigration(t *testing.T) {
+	home := useTempHome(t)
+	legacy := `{"last_node_ip":"10.0.0.1","last_ssh_username":"admin","last_check_coherence":true,` +
+		`"node_credentials":[{"node_ip":"10.0.0.1","ssh_username":"admin","uses_ssh_keys":true,` +
+		`"transport":{"type":"docker","container":"galera1"}}],"join_timeout_seconds":900}`
+	if err := os.WriteFile(filepath.Join(home, ".galerahealth"), []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := loadConfig()
+	if config.LastNodeIP != "10.0.0.1" || config.LastSSHUsername != "admin" || config.JoinTimeoutSeconds != 900 {
+		t.Errorf("settings not migrated: %+v", config)
+	}
+	if creds := config.getNodeCredentials("10.0.0.1"); creds == nil || creds.Transport == nil || creds.Transport.Container != "galera1" {
+		t.Errorf("node credentials not migrated: %+v", creds)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".galerahealth.json.bak")); err != nil {
+		t.Errorf("old configuration not kept as a backup: %v", err)
+	}
+
+	// The next load reads the YAML file
+	reloaded := loadConfig()
+	if reloaded.LastSSHUsername != "admin" || len(reloaded.NodeCredentials) != 1 {
+		t.Errorf("YAML configuration not read back: %+v", reloaded)
+	}
+}
+
+func TestSaveConfigKeepsComments(t *testing.T) {
+	home := useTempHome(t)
+	path := filepath.Join(home, ".galerahealth.yaml")
+	original := `# Production cluster
+node: 10.0.0.1 # first node
+ssh_user: root
+nodes:
+  # Jump host only reachable over the VPN
+  - ip: 10.0.0.2
+    ssh_user: deploy
+  - ip: 10.0.0.1
+    ssh_user: root
+`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := loadConfig()
+	config.LastNodeIP = "10.0.0.3"
+	if err := config.setNodeCredentials("10.0.0.3", "root", "", "", "", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	for _, want := range []string{"# Production cluster", "node: 10.0.0.3 # first node", "# Jump host only reachable over the VPN\n  - ip: 10.0.0.2", "- ip: 10.0.0.3"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("saved file is missing %q:\n%s", want, saved)
+		}
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	home := useTempHome(t)
+	content := "ssh_user: from-file\nmysql_user: from-file\njoin_timeout_seconds: 600\n" +
+		"clusters:\n  - name: prod\n    seed_nodes: [10.0.0.1]\n    ssh_user: from-profile\n"
+	if err := os.WriteFile(filepath.Join(home, ".galerahealth.yaml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GALERAHEALTH_MYSQL_USER", "from-env")
+	t.Setenv("GALERAHEALTH_JOIN_TIMEOUT", "1200")
+
+	previousCluster := clusterName
+	t.Cleanup(func() { clusterName = previousCluster })
+
+	clusterName = ""
+	config := loadClusterConfig()
+	if config.LastSSHUsername != "from-file" || config.LastMySQLUsername != "from-env" || config.JoinTimeoutSeconds != 1200 {
+		t.Errorf("got ssh %s, mysql %s, join timeout %d", config.LastSSHUsername, config.LastMySQLUsername, config.JoinTimeoutSeconds)
+	}
+
+	// The environment also overrides the selected profile
+	clusterName = "prod"
+	config = loadClusterConfig()
+	if config.LastSSHUsername != "from-profile" || config.LastMySQLUsername != "from-env" {
+		t.Errorf("profile: got ssh %s, mysql %s", config.LastSSHUsername, config.LastMySQLUsername)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "mysql.pw")
+	if err := os.WriteFile(passwordFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		want   []string
+	}{
+		{
+			name: "valid",
+			config: Config{
+				MySQLPasswordRef: "file:" + passwordFile,
+				NodeCredentials:  []NodeCredentials{{NodeIP: "10.0.0.1", SSHPasswordRef: "env:SSH_PASSWORD"}},
+				Clusters:         []ClusterProfile{{Name: "prod", SeedNodes: []string{"10.0.0.1"}}},
+			},
+		},
+		{
+			name:   "bad references",
+			config: Config{MySQLPasswordRef: "secret", NodeCredentials: []NodeCredentials{{NodeIP: "10.0.0.1", MySQLPasswordRef: "file:/does/not/exist"}}},
+			want:   []string{"mysql_password_ref: invalid password reference", "nodes[10.0.0.1].mysql_password_ref: password file"},
+		},
+		{
+			name: "duplicate nodes and unknown transport",
+			config: Config{NodeCredentials: []NodeCredentials{
+				{NodeIP: "10.0.0.1"}, {NodeIP: "10.0.0.1", Transport: &NodeTransport{Type: "lxc"}},
+			}},
+			want: []string{"node listed more than once", "unknown type \"lxc\""},
+		},
+		{
+			name: "cluster problems",
+			config: Config{Clusters: []ClusterProfile{
+				{Name: "prod", SeedNodes: []string{"10.0.0.1"}}, {Name: "prod", JoinTimeoutSeconds: -1}, {Name: "all"},
+			}},
+			want: []string{"defined more than once", "clusters[prod].seed_nodes", "join_timeout_seconds must not be negative", "reserved"},
+		},
+		{
+			name:   "output and secrets",
+			config: Config{Output: OutputSettings{Verbosity: 5}, SecretsBackend: "vault"},
+			want:   []string{"output.verbosity", "unknown secrets_backend"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertErrors(t, validateConfig(&tt.config), tt.want)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported node transports
+const (
+	TransportSSH    = "ssh"
+	TransportDocker = "docker"
+	TransportPodman = "podman"
+)
+
+// NodeTransport describes how commands reach a node. The default is SSH to the node itself;
+// containerized nodes run commands with docker/podman exec, locally or on a remote host over SSH.
+type NodeTransport struct {
+	Type      string `json:"type" yaml:"type"`                               // ssh (default), docker or podman
+	Container string `json:"container,omitempty" yaml:"container,omitempty"` // Container name or ID running MariaDB/MySQL
+	Host      string `json:"host,omitempty" yaml:"host,omitempty"`           // Host running the container runtime (empty = this machine)
+	Runtime   string `json:"runtime,omitempty" yaml:"runtime,omitempty"`     // Path to the runtime binary (defaults to docker/podman)
+	// Command run on the container host to start the container as a new cluster; the entrypoint
+	// decides how --wsrep-new-cluster is passed, so there is no default
+	BootstrapCommand string `json:"bootstrap_command,omitempty" yaml:"bootstrap_command,omitempty"`
+}
+
+// isContainer reports whether the transport runs commands inside a container
+func (t *NodeTransport) isContainer() bool {
+	return t != nil && (t.Type == TransportDocker || t.Type == TransportPodman)
+}
+
+// isLocalRuntime reports whether the container runtime is on this machine
+func (t *NodeTransport) isLocalRuntime() bool {
+	return t.Host == "" || isLocalhost(t.Host)
+}
+
+// runtimeBinary returns the container runtime command
+func (t *NodeTransport) runtimeBinary() string {
+	if t.Runtime != "" {
+		return t.Runtime
+	}
+	return t.Type
+}
+
+// wrapCommand turns a shell command into one executed inside the container
+func (t *NodeTransport) wrapCommand(command string) string {
+	return fmt.Sprintf("%s exec %s sh -c %s", t.runtimeBinary(), shellQuote(t.Container), shellQuote(command))
+}
+
+// String returns a short description of the transport
+func (t *NodeTransport) String() string {
+	if !t.isContainer() {
+		return TransportSSH
+	}
+	if t.isLocalRuntime() {
+		return fmt.Sprintf("%s:%s", t.Type, t.Container)
+	}
+	return fmt.Sprintf("%s:%s@%s", t.Type, t.Container, t.Host)
+}
+
+// getNodeTransport returns the configured transport for a node, or nil for plain SSH
+func getNodeTransport(ip string, config *Config) *NodeTransport {
+	if config == nil {
+		return nil
+	}
+	if creds := config.getNodeCredentials(ip); creds != nil && creds.Transport.isContainer() {
+		return creds.Transport
+	}
+	return nil
+}
+
+// connectContainerExecutor returns an executor running commands inside a node's container,
+// on this machine or through SSH to the container host
+func connectContainerExecutor(ip string, transport *NodeTransport, config *Config) (NodeExecutor, *SSHConnectionInfo, error) {
+	if transport.Container == "" {
+		return nil, nil, fmt.Errorf("node %s uses %s transport but no container is configured", ip, transport.Type)
+	}
+
+	logVerbose("      🐳 Using %s transport for node %s", transport, ip)
+	if transport.isLocalRuntime() {
+		return &ContainerExecutor{host: &LocalExecutor{}, transport: transport}, nil, nil
+	}
+
+	// Connect to the container host with the credentials saved for that host
+	host, connInfo, err := openNodeExecutor(transport.Host, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to container host %s: %v", transport.Host, err)
+	}
+	if connInfo != nil {
+		if _, err := config.saveSSHConnection(transport.Host, connInfo); err != nil {
+			logVerbose("Warning: Could not save credentials for container host %s: %v", transport.Host, err)
+		}
+	}
+	return &ContainerExecutor{host: host, transport: transport}, nil, nil
+}
+
+// containerServiceBackend returns the service backend that starts and stops a node's container
+func containerServiceBackend(transport *NodeTransport) *ServiceBackend {
+	return &ServiceBackend{
+		Manager:          transport.Type,
+		Unit:             transport.Container,
+		Runtime:          transport.Runtime,
+		BootstrapCommand: transport.BootstrapCommand,
+		AutoDetected:     true,
+	}
+}
+
+// containerStatusCommand maps the container state to active, activating, inactive or failed
+func containerStatusCommand(runtime, container string) string {
+	return fmt.Sprintf(`s=$(%s inspect -f '{{.State.Status}} {{.State.ExitCode}}' %s 2>/dev/null); case "$s" in running*) echo active;; restarting*) echo activating;; ""|created*|"exited 0") echo inactive;; *) echo failed;; esac`,
+		runtime, shellQuote(container))
+}
+
+// containerLogCommand prints recent log lines of a container
+func containerLogCommand(runtime, container, since string, lines int) string {
+	// Container runtimes expect durations such as 30m instead of journalctl's "30 min ago"
+	duration := strings.TrimSuffix(since, " ago")
+	duration = strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(duration, " min", "m"), " hour", "h"), " ", "")
+	return fmt.Sprintf("%s logs --since %s --tail %d %s 2>&1", runtime, duration, lines, shellQuote(container))
+}
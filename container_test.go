@@ -0,0 +1,90 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContainerTransport(t *testing.T) {
+	runtime, err := filepath.Abs("testdata/fake-container-runtime.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FAKE_RUNTIME_DIR", t.TempDir())
+	transport := &NodeTransport{Type: TransportDocker, Container: "galera1", Runtime: runtime}
+
+	executor, _, err := connectContainerExecutor("10.0.0.1", transport, newTestConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := containerServiceBackend(transport)
+	host := serviceExecutor(executor, service)
+	if host == executor {
+		t.Fatal("service commands of a container run inside it instead of on the container host")
+	}
+	status := func() string {
+		t.Helper()
+		output, err := runCommand(host, service.statusCommand())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(output)
+	}
+
+	// Commands run inside the container
+	if output, err := runCommand(executor, "echo $CONTAINER_NAME"); err != nil || strings.TrimSpace(output) != "galera1" {
+		t.Errorf("exec = %q, %v", output, err)
+	}
+	if got := status(); got != "active" {
+		t.Errorf("status of a running container = %q", got)
+	}
+
+	stopCmd, err := service.stopCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCommand(host, stopCmd); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); got != "inactive" {
+		t.Errorf("status of a stopped container = %q", got)
+	}
+	if _, err := runCommand(executor, "true"); err == nil {
+		t.Error("exec in a stopped container succeeded")
+	}
+
+	startCmd, err := service.startCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCommand(host, startCmd); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); got != "active" {
+		t.Errorf("status of a restarted container = %q", got)
+	}
+
+	if _, err := runCommand(host, runtime+" kill galera1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); got != "failed" {
+		t.Errorf("status of a killed container = %q", got)
+	}
+
+	if output, err := runCommand(host, service.logCommand("30 min ago", 100)); err != nil || !strings.Contains(output, "fake log line for galera1") {
+		t.Errorf("logs = %q, %v", output, err)
+	}
+}
+
+func TestContainerBootstrapCommand(t *testing.T) {
+	transport := &NodeTransport{Type: TransportPodman, Container: "galera1"}
+	if _, err := containerServiceBackend(transport).bootstrapCommand(); err == nil || !strings.Contains(err.Error(), "bootstrap_command") {
+		t.Errorf("bootstrap without a command: %v", err)
+	}
+
+	transport.BootstrapCommand = "/srv/galera/bootstrap.sh"
+	if command, err := containerServiceBackend(transport).bootstrapCommand(); err != nil || command != transport.BootstrapCommand {
+		t.Errorf("bootstrap command = %q, %v", command, err)
+	}
+}
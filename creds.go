@@ -0,0 +1,465 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Credential kinds whose last successful use is recorded
+const (
+	credentialSSH   = "ssh"
+	credentialMySQL = "mysql"
+)
+
+// CredentialCheck is the result of trying one stored credential against a node
+type CredentialCheck struct {
+	Node   string
+	Method string // ssh-keys, ssh-password, transport, mysql-node, mysql-cluster
+	User   string
+	OK     bool
+	Detail string
+}
+
+// dialSSHCredentials opens an SSH connection with keys (empty password) or a password; tests replace it
+var dialSSHCredentials = func(ip, username, password string) (NodeExecutor, error) {
+	if password == "" {
+		return createSSHConnectionWithKeys(ip, username)
+	}
+	return createSSHConnectionWithPassword(ip, username, password)
+}
+
+// markCredentialsUsed records that a node's saved credentials worked; nodes without saved credentials are ignored
+func (c *Config) markCredentialsUsed(nodeIP, kind string) {
+	creds := c.getNodeCredentials(nodeIP)
+	if creds == nil {
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	switch kind {
+	case credentialSSH:
+		creds.LastSSHSuccess = now
+	case credentialMySQL:
+		creds.LastMySQLSuccess = now
+	}
+}
+
+// removeNodeCredentials deletes the saved credentials of a node, returning false if there were none
+func (c *Config) removeNodeCredentials(nodeIP string) bool {
+	for i := range c.NodeCredentials {
+		if c.NodeCredentials[i].NodeIP == nodeIP {
+			c.NodeCredentials = append(c.NodeCredentials[:i], c.NodeCredentials[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// describeSSHAuth summarizes how SSH logins to a node are authenticated
+func describeSSHAuth(creds *NodeCredentials) string {
+	var methods []string
+	if creds.Transport.isContainer() {
+		methods = append(methods, creds.Transport.String())
+	}
+	if creds.UsesSSHKeys {
+		methods = append(methods, "keys")
+	}
+	if creds.SSHPasswordRef != "" {
+		methods = append(methods, creds.SSHPasswordRef)
+	} else if creds.HasSSHPassword {
+		methods = append(methods, "password")
+	}
+	if len(methods) == 0 {
+		return "-"
+	}
+	return strings.Join(methods, "+")
+}
+
+// describeMySQLAuth summarizes which MySQL password is stored for a node
+func describeMySQLAuth(creds *NodeCredentials) string {
+	var methods []string
+	if creds.MySQLLoginPath != "" {
+		methods = append(methods, "login-path "+creds.MySQLLoginPath)
+	}
+	if creds.MySQLOptionFile {
+		methods = append(methods, "~/.my.cnf")
+	}
+	if creds.MySQLPasswordRef != "" {
+		methods = append(methods, creds.MySQLPasswordRef)
+	} else if creds.HasMySQLPassword {
+		methods = append(methods, "password")
+	}
+	if len(methods) == 0 {
+		return "cluster default"
+	}
+	return strings.Join(methods, "+")
+}
+
+// formatLastUse formats the time a credential last worked
+func formatLastUse(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// credentialNodes returns the nodes a creds command applies to: the one given, or every saved node
+func credentialNodes(config *Config, args []string) ([]string, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("usage: galerahealth creds test [node]")
+	}
+	if len(args) == 1 {
+		if config.getNodeCredentials(args[0]) == nil && !containsString(config.seedNodes(), args[0]) {
+			return nil, fmt.Errorf("no saved credentials for node %s", args[0])
+		}
+		return args, nil
+	}
+
+	var nodes []string
+	for _, creds := range config.NodeCredentials {
+		nodes = append(nodes, creds.NodeIP)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no saved node credentials (add some with 'galerahealth creds set <node>')")
+	}
+	return nodes, nil
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// testNodeCredentials tries every stored SSH and MySQL credential of a node separately
+func testNodeCredentials(nodeIP string, config *Config) []CredentialCheck {
+	var checks []CredentialCheck
+	creds := config.getNodeCredentials(nodeIP)
+	if creds == nil {
+		creds = &NodeCredentials{NodeIP: nodeIP, SSHUsername: config.LastSSHUsername}
+	}
+	sshUser := creds.SSHUsername
+	if sshUser == "" {
+		sshUser = "root"
+	}
+
+	// The executor of the first working login is reused for the MySQL checks
+	var executor NodeExecutor
+	keep := func(e NodeExecutor) {
+		if executor == nil {
+			executor = e
+		} else {
+			e.Close()
+		}
+	}
+
+	if isLocalhost(nodeIP) || getNodeTransport(nodeIP, config) != nil {
+		check := CredentialCheck{Node: nodeIP, Method: "transport", OK: true}
+		e, err := getNodeExecutor(nodeIP, config)
+		if err != nil {
+			check.OK, check.Detail = false, err.Error()
+		} else {
+			executor = e
+		}
+		checks = append(checks, check)
+	} else {
+		if creds.UsesSSHKeys {
+			check := CredentialCheck{Node: nodeIP, Method: "ssh-keys", User: sshUser, OK: true}
+			if e, err := dialSSHCredentials(nodeIP, sshUser, ""); err != nil {
+				check.OK, check.Detail = false, err.Error()
+			} else {
+				keep(e)
+			}
+			checks = append(checks, check)
+		}
+		if creds.HasSSHPassword || creds.SSHPasswordRef != "" {
+			check := CredentialCheck{Node: nodeIP, Method: "ssh-password", User: sshUser, OK: true}
+			if password, err := config.getNodeSSHPassword(nodeIP); err != nil {
+				check.OK, check.Detail = false, err.Error()
+			} else if e, err := dialSSHCredentials(nodeIP, sshUser, password); err != nil {
+				check.OK, check.Detail = false, err.Error()
+			} else {
+				keep(e)
+			}
+			checks = append(checks, check)
+		}
+		if len(checks) == 0 {
+			checks = append(checks, CredentialCheck{Node: nodeIP, Method: "ssh", User: sshUser, Detail: "no SSH keys or password stored"})
+		}
+	}
+	if executor != nil {
+		config.markCredentialsUsed(nodeIP, credentialSSH)
+	}
+
+	// MySQL: each configured login in the order the status check tries them
+	for _, mysqlCreds := range mysqlCredentialCandidates(nodeIP, config, nil) {
+		if mysqlCreds.Source == mysqlSourceFallback {
+			continue
+		}
+		check := CredentialCheck{Node: nodeIP, Method: "mysql-" + mysqlCreds.Source, User: mysqlCreds.describe()}
+		if executor == nil {
+			check.Detail = "skipped, no working login to the node"
+		} else if err := testMySQLLogin(executor, mysqlCreds); err != nil {
+			check.Detail = err.Error()
+		} else {
+			check.OK = true
+			config.markCredentialsUsed(nodeIP, credentialMySQL)
+		}
+		checks = append(checks, check)
+	}
+
+	if executor != nil && getNodeTransport(nodeIP, config) == nil && !isLocalhost(nodeIP) {
+		executor.Close()
+	}
+	return checks
+}
+
+// testMySQLLogin tries to log in to MySQL on the node with the given credentials
+func testMySQLLogin(executor NodeExecutor, creds *MySQLConnectionInfo) error {
+	if _, output, ok := connectMySQL(executor, creds); !ok {
+		if message := strings.TrimSpace(output); message != "" {
+			return fmt.Errorf("%s", message)
+		}
+		return fmt.Errorf("mysql login failed")
+	}
+	return nil
+}
+
+// readNewPassword prompts for a password to store
+func readNewPassword(prompt string) (string, error) {
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", fmt.Errorf("a terminal is required to enter a password (use a password file or reference instead)")
+	}
+	fmt.Print(prompt)
+	password, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("error reading password: %v", err)
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("empty password")
+	}
+	return string(password), nil
+}
+
+// runCredsCommand implements the "creds" subcommand
+func runCredsCommand(args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	// The profile is selected without the environment and flag overrides, which must not be saved
+	if clusterName == fleetClusterName {
+		return fmt.Errorf("--cluster %s is not supported for creds", fleetClusterName)
+	}
+	config := loadConfig()
+	if err := config.selectCluster(clusterName); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		if len(config.NodeCredentials) == 0 {
+			logMinimal("No saved node credentials (add some with 'galerahealth creds set <node>')")
+			return nil
+		}
+		nodes := append([]NodeCredentials(nil), config.NodeCredentials...)
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].NodeIP < nodes[j].NodeIP })
+
+		fmt.Printf("%-16s %-10s %-22s %-16s %-22s %-17s %s\n", "NODE", "SSH USER", "SSH AUTH", "MYSQL USER", "MYSQL AUTH", "LAST SSH OK", "LAST MYSQL OK")
+		for i := range nodes {
+			creds := &nodes[i]
+			mysqlUser := creds.MySQLUsername
+			if mysqlUser == "" {
+				mysqlUser = "(" + config.LastMySQLUsername + ")"
+			}
+			fmt.Printf("%-16s %-10s %-22s %-16s %-22s %-17s %s\n", creds.NodeIP, creds.SSHUsername, describeSSHAuth(creds),
+				mysqlUser, describeMySQLAuth(creds), formatLastUse(creds.LastSSHSuccess), formatLastUse(creds.LastMySQLSuccess))
+		}
+
+		cluster := "none"
+		if ref := config.MySQLPasswordRef; ref != "" {
+			cluster = ref
+		} else if config.HasSavedPassword {
+			cluster = "password"
+		}
+		fmt.Printf("\nCluster default MySQL user: %s, password: %s\n", config.LastMySQLUsername, cluster)
+		return nil
+
+	case "set":
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			return fmt.Errorf("usage: galerahealth creds set <node> [--ssh-user u] [--mysql-user u] [--keys|--no-keys] " +
+				"[--ssh-password] [--mysql-password] [--ssh-password-ref ref] [--mysql-password-ref ref] " +
+				"[--mysql-login-path name] [--mysql-option-file|--no-mysql-option-file]")
+		}
+		nodeIP := args[1]
+
+		// Start from the saved values so only the options given change
+		creds := NodeCredentials{NodeIP: nodeIP, SSHUsername: config.LastSSHUsername}
+		if existing := config.getNodeCredentials(nodeIP); existing != nil {
+			creds = *existing
+		}
+		if creds.SSHUsername == "" || creds.SSHUsername == "local" {
+			creds.SSHUsername = "root"
+		}
+
+		// --ssh-user, --mysql-user and the password files are global flags, parsed into cliOptions
+		if cliOptions.SSHUser != "" {
+			creds.SSHUsername = cliOptions.SSHUser
+		}
+		if cliOptions.MySQLUser != "" {
+			creds.MySQLUsername = cliOptions.MySQLUser
+		}
+		var sshPassword, mysqlPassword string
+		var err error
+		if cliOptions.SSHPasswordFile != "" {
+			if sshPassword, err = readPasswordFile(cliOptions.SSHPasswordFile); err != nil {
+				return err
+			}
+		}
+		if cliOptions.MySQLPasswordFile != "" {
+			if mysqlPassword, err = readPasswordFile(cliOptions.MySQLPasswordFile); err != nil {
+				return err
+			}
+		}
+
+		sshRef, mysqlRef := creds.SSHPasswordRef, creds.MySQLPasswordRef
+		for i := 2; i < len(args); i++ {
+			switch {
+			case args[i] == "--keys":
+				creds.UsesSSHKeys = true
+			case args[i] == "--no-keys":
+				creds.UsesSSHKeys = false
+			case args[i] == "--ssh-password":
+				if sshPassword, err = readNewPassword(fmt.Sprintf("SSH password for %s@%s: ", creds.SSHUsername, nodeIP)); err != nil {
+					return err
+				}
+			case args[i] == "--mysql-password":
+				if mysqlPassword, err = readNewPassword(fmt.Sprintf("MySQL password for %s on %s: ", creds.MySQLUsername, nodeIP)); err != nil {
+					return err
+				}
+			case args[i] == "--ssh-password-ref" && i+1 < len(args):
+				i++
+				if err := validateSecretRef(args[i]); err != nil {
+					return err
+				}
+				sshRef = args[i]
+			case args[i] == "--mysql-password-ref" && i+1 < len(args):
+				i++
+				if err := validateSecretRef(args[i]); err != nil {
+					return err
+				}
+				mysqlRef = args[i]
+			case args[i] == "--mysql-login-path" && i+1 < len(args):
+				i++
+				creds.MySQLLoginPath = args[i]
+			case args[i] == "--mysql-option-file":
+				creds.MySQLOptionFile = true
+			case args[i] == "--no-mysql-option-file":
+				creds.MySQLOptionFile = false
+			default:
+				return fmt.Errorf("unknown creds set option: %s", args[i])
+			}
+		}
+
+		if err := config.setNodeCredentials(nodeIP, creds.SSHUsername, creds.MySQLUsername, sshPassword, mysqlPassword, creds.UsesSSHKeys); err != nil {
+			return err
+		}
+		saved := config.getNodeCredentials(nodeIP)
+		saved.SSHPasswordRef, saved.MySQLPasswordRef = sshRef, mysqlRef
+		saved.MySQLLoginPath, saved.MySQLOptionFile = creds.MySQLLoginPath, creds.MySQLOptionFile
+
+		// A new password replaces the stored one and any reference; a new reference replaces the stored password
+		if sshPassword != "" {
+			saved.SSHPasswordRef = ""
+		} else if sshRef != creds.SSHPasswordRef && sshRef != "" {
+			saved.EncryptedSSHPassword, saved.HasSSHPassword = "", false
+		}
+		if mysqlPassword != "" {
+			saved.MySQLPasswordRef = ""
+		} else if mysqlRef != creds.MySQLPasswordRef && mysqlRef != "" {
+			saved.EncryptedMySQLPassword, saved.HasMySQLPassword = "", false
+		}
+
+		if err := saveConfig(config); err != nil {
+			return err
+		}
+		logMinimal("✅ Saved credentials for node %s (SSH: %s as %s, MySQL: %s)", nodeIP, describeSSHAuth(saved), saved.SSHUsername, describeMySQLAuth(saved))
+		return nil
+
+	case "remove":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: galerahealth creds remove <node> [--ssh-password] [--mysql-password]")
+		}
+		nodeIP := args[1]
+		creds := config.getNodeCredentials(nodeIP)
+		if creds == nil {
+			return fmt.Errorf("no saved credentials for node %s", nodeIP)
+		}
+
+		if len(args) == 2 {
+			config.removeNodeCredentials(nodeIP)
+			if err := saveConfig(config); err != nil {
+				return err
+			}
+			logMinimal("✅ Removed saved credentials for node %s", nodeIP)
+			return nil
+		}
+
+		// Forget only the given passwords
+		for _, option := range args[2:] {
+			switch option {
+			case "--ssh-password":
+				creds.EncryptedSSHPassword, creds.HasSSHPassword, creds.SSHPasswordRef = "", false, ""
+			case "--mysql-password":
+				creds.EncryptedMySQLPassword, creds.HasMySQLPassword, creds.MySQLPasswordRef = "", false, ""
+			default:
+				return fmt.Errorf("unknown creds remove option: %s", option)
+			}
+		}
+		if err := saveConfig(config); err != nil {
+			return err
+		}
+		logMinimal("✅ Removed %s for node %s", strings.Join(args[2:], " "), nodeIP)
+		return nil
+
+	case "test":
+		nodes, err := credentialNodes(config, args[1:])
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		fmt.Printf("%-16s %-14s %-12s %s\n", "NODE", "METHOD", "USER", "RESULT")
+		for _, nodeIP := range nodes {
+			for _, check := range testNodeCredentials(nodeIP, config) {
+				result := "✅ ok"
+				if !check.OK {
+					result = "❌ " + check.Detail
+					failed++
+				}
+				fmt.Printf("%-16s %-14s %-12s %s\n", check.Node, check.Method, check.User, result)
+			}
+		}
+
+		// Keep the last successful use of the credentials that worked
+		if err := saveConfig(config); err != nil {
+			logNormal("Warning: Could not save configuration: %v", err)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d credential checks failed", failed)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown creds command: %s (use list, set, remove or test)", args[0])
+	}
+}
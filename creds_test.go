@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCredsSetAndRemove(t *testing.T) {
+	useTempHome(t)
+	resetCLIState(t)
+	passwordFile := filepath.Join(t.TempDir(), "ssh.pw")
+	if err := os.WriteFile(passwordFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Passwords are only saved once a secrets backend is set up
+	sessionMasterKey = nil
+	t.Cleanup(func() { sessionMasterKey = nil })
+	t.Setenv(secretKeyEnv, "c2VjcmV0cy1iYWNrZW5kLXRlc3Qta2V5LTMyLWJ5dGU=")
+	if err := runSecretsCommand([]string{"migrate", "--backend", SecretsBackendEnv}); err != nil {
+		t.Fatal(err)
+	}
+
+	cliOptions = CLIOptions{SSHUser: "deploy", SSHPasswordFile: passwordFile}
+	if err := runCredsCommand([]string{"set", "10.0.0.1", "--keys", "--mysql-password-ref", "env:MYSQL_PW"}); err != nil {
+		t.Fatal(err)
+	}
+	config := loadConfig()
+	creds := config.getNodeCredentials("10.0.0.1")
+	if creds == nil || creds.SSHUsername != "deploy" || !creds.UsesSSHKeys || creds.MySQLPasswordRef != "env:MYSQL_PW" {
+		t.Fatalf("credentials not saved: %+v", creds)
+	}
+	if password, err := config.getNodeSSHPassword("10.0.0.1"); err != nil || password != "s3cret" {
+		t.Errorf("SSH password = %q (%v)", password, err)
+	}
+
+	// Only the options given change; a reference replaces the stored password
+	cliOptions = CLIOptions{}
+	if err := runCredsCommand([]string{"set", "10.0.0.1", "--ssh-password-ref", "file:" + passwordFile}); err != nil {
+		t.Fatal(err)
+	}
+	creds = loadConfig().getNodeCredentials("10.0.0.1")
+	if creds.SSHUsername != "deploy" || !creds.UsesSSHKeys || creds.HasSSHPassword || creds.SSHPasswordRef != "file:"+passwordFile {
+		t.Errorf("credentials after setting a reference: %+v", creds)
+	}
+
+	if err := runCredsCommand([]string{"remove", "10.0.0.1", "--mysql-password"}); err != nil {
+		t.Fatal(err)
+	}
+	if creds = loadConfig().getNodeCredentials("10.0.0.1"); creds.MySQLPasswordRef != "" || creds.SSHPasswordRef == "" {
+		t.Errorf("credentials after removing the MySQL password: %+v", creds)
+	}
+	if err := runCredsCommand([]string{"remove", "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if creds = loadConfig().getNodeCredentials("10.0.0.1"); creds != nil {
+		t.Errorf("credentials not removed: %+v", creds)
+	}
+	if err := runCredsCommand([]string{"set", "10.0.0.1", "--ssh-password-ref", "secret"}); err == nil {
+		t.Error("expected an invalid reference to be rejected")
+	}
+}
+
+func TestTestNodeCredentials(t *testing.T) {
+	cluster := newFakeCluster(t, &fakeNode{IP: "10.0.0.1", Service: "active", MySQLUsers: map[string]string{"monitor": "mysql-one"}},
+		&fakeNode{IP: "10.0.0.2", Service: "inactive"})
+	previousDial := dialSSHCredentials
+	dialSSHCredentials = func(ip, username, password string) (NodeExecutor, error) {
+		if password == "" {
+			return nil, fmt.Errorf("ssh: unable to authenticate, attempted methods [none publickey]")
+		}
+		if password != "ssh-"+ip {
+			return nil, fmt.Errorf("ssh: unable to authenticate, attempted methods [none password]")
+		}
+		return &FakeExecutor{Handler: cluster.Nodes[ip].handle}, nil
+	}
+	t.Cleanup(func() { dialSSHCredentials = previousDial })
+
+	config := newTestConfig(t)
+	config.LastNodeIP = "10.0.0.1"
+	if err := config.setNodeCredentials("10.0.0.1", "root", "monitor", "ssh-10.0.0.1", "mysql-one", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.setNodeCredentials("10.0.0.2", "root", "", "wrong", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var results []string
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		for _, check := range testNodeCredentials(ip, config) {
+			results = append(results, fmt.Sprintf("%s %s %t", check.Node, check.Method, check.OK))
+		}
+	}
+	want := []string{"10.0.0.1 ssh-keys false", "10.0.0.1 ssh-password true", "10.0.0.1 mysql-node true", "10.0.0.1 mysql-cluster false",
+		"10.0.0.2 ssh-password false", "10.0.0.2 mysql-cluster false"}
+	if strings.Join(results, "\n") != strings.Join(want, "\n") {
+		t.Errorf("checks:\n%s\nwant:\n%s", strings.Join(results, "\n"), strings.Join(want, "\n"))
+	}
+
+	if creds := config.getNodeCredentials("10.0.0.1"); creds.LastSSHSuccess.IsZero() || creds.LastMySQLSuccess.IsZero() {
+		t.Errorf("last successful use not recorded: %+v", creds)
+	}
+	if creds := config.getNodeCredentials("10.0.0.2"); !creds.LastSSHSuccess.IsZero() {
+		t.Errorf("failed login recorded as successful: %+v", creds)
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 )
 
 // summaryPrint prints to console, respecting report mode
@@ -28,6 +30,16 @@ func displayClusterInfo(info *GaleraClusterInfo) {
 
 	if info.ClusterAddress != "" {
 		fmt.Printf("📍 Cluster Address: %s\n", info.ClusterAddress)
+		if address, err := parseGcommAddress(info.ClusterAddress); err != nil {
+			fmt.Printf("⚠️  Cluster Address: %v\n", err)
+		} else if len(address.Options) > 0 {
+			var options []string
+			for key, value := range address.Options {
+				options = append(options, key+"="+value)
+			}
+			sort.Strings(options)
+			fmt.Printf("⚙️  Cluster Address Options: %s\n", strings.Join(options, ", "))
+		}
 	} else {
 		fmt.Println("⚠️  Cluster Address: Not configured")
 	}
@@ -52,10 +64,14 @@ func displayClusterAnalysis(analysis *ClusterAnalysis) {
 	fmt.Printf("📊 Nodes analyzed: %d/%d\n", len(analysis.AllNodes), len(analysis.ClusterNodes))
 	fmt.Printf("🎯 Cluster name: %s\n", analysis.InitialNode.ClusterName)
 
+	fmt.Println()
+	displayMembership(analysis.Membership)
+	displayVersions(analysis.AllNodes)
+
 	fmt.Println()
 	fmt.Println("📋 All nodes in cluster:")
 	for i, node := range analysis.AllNodes {
-		fmt.Printf("   %d. %s\n", i+1, node.NodeIP)
+		fmt.Printf("   %d. %s\n", i+1, node.aliasSummary())
 		fmt.Printf("      Cluster Name: %s\n", node.ClusterName)
 		fmt.Printf("      Cluster Address: %s\n", node.ClusterAddress)
 		if node.NodeName != "" {
@@ -64,6 +80,25 @@ func displayClusterAnalysis(analysis *ClusterAnalysis) {
 		if node.NodeAddress != "" {
 			fmt.Printf("      Node Address: %s\n", node.NodeAddress)
 		}
+		if node.Clock != nil {
+			fmt.Printf("      Clock: %s\n", node.Clock.describe())
+		}
+		if node.Versions != nil {
+			fmt.Printf("      Version: %s\n", node.Versions.describe())
+			if node.Versions.OS != "" {
+				fmt.Printf("      OS: %s\n", node.Versions.OS)
+			}
+			if currentVerbosity >= VerbosityNormal && len(node.Versions.Packages) > 0 {
+				fmt.Printf("      Packages: %s\n", strings.Join(node.Versions.Packages, ", "))
+			}
+		}
+		if node.Resources != nil {
+			fmt.Printf("      Disk: %s\n", node.Resources.describeDisk())
+			fmt.Printf("      Memory: %s\n", node.Resources.describeMemory())
+		}
+		if currentVerbosity >= VerbosityNormal {
+			fmt.Printf("      Identity: %s\n", node.describeIdentity())
+		}
 		fmt.Println()
 	}
 
@@ -161,13 +196,14 @@ func displayClusterAnalysisWithMySQL(analysis *ClusterAnalysis) {
 		}
 		fmt.Printf("   %s Synced state: %d/%d responding nodes\n", syncedIcon, syncedNodes, respondingNodes)
 	}
+	displayStateTransfers(analysis.Transfers)
 
 	fmt.Println()
 
 	// Display all nodes information with MySQL status
 	fmt.Println("📋 Node Details with MySQL Status:")
 	for i, node := range analysis.AllNodes {
-		fmt.Printf("   %d. %s\n", i+1, node.NodeIP)
+		fmt.Printf("   %d. %s\n", i+1, node.aliasSummary())
 		fmt.Printf("      Cluster Name: %s\n", node.ClusterName)
 		fmt.Printf("      Cluster Address: %s\n", node.ClusterAddress)
 		if node.NodeName != "" {
@@ -224,71 +260,138 @@ func displayClusterAnalysisWithMySQL(analysis *ClusterAnalysis) {
 	}
 }
 
-// displayClusterSummary displays a final summary of the cluster health status
-func displayClusterSummary(analysis *ClusterAnalysis) {
-	summaryPrint("")
-	summaryPrint("=== CLUSTER HEALTH SUMMARY ===")
-	summaryPrint("")
+// ClusterHealth summarizes the health checks of an analyzed cluster
+type ClusterHealth struct {
+	TotalNodes      int
+	RespondingNodes int
+	ReadyNodes      int
+	PrimaryNodes    int
+	SyncedNodes     int
+	HasMySQLData    bool
+	Issues          []string
+	Warnings        []string
+}
 
-	totalNodes := len(analysis.AllNodes)
-	issues := []string{}
-	warnings := []string{}
+// evaluateClusterHealth classifies the analysis into critical issues and warnings
+func evaluateClusterHealth(analysis *ClusterAnalysis) *ClusterHealth {
+	health := &ClusterHealth{TotalNodes: len(analysis.AllNodes)}
 
 	// Check configuration coherence
 	if !analysis.IsCoherent {
-		issues = append(issues, fmt.Sprintf("Incoherent configuration (%d errors)", len(analysis.ConfigErrors)))
+		health.Issues = append(health.Issues, fmt.Sprintf("Incoherent configuration (%d errors)", len(analysis.ConfigErrors)))
 	}
 
-	// Check MySQL/MariaDB status if available
-	respondingNodes := 0
-	readyNodes := 0
-	primaryNodes := 0
-	syncedNodes := 0
-	hasMySQLData := false
+	// Compare the configured nodes with the running cluster
+	if diff := analysis.Membership; diff != nil {
+		if len(diff.NotInCluster) > 0 {
+			health.Warnings = append(health.Warnings, fmt.Sprintf("Configured nodes not in the running cluster: %s", strings.Join(diff.NotInCluster, ", ")))
+		}
+		if len(diff.NotConfigured) > 0 {
+			health.Warnings = append(health.Warnings, fmt.Sprintf("Cluster members missing from wsrep_cluster_address: %s", strings.Join(diff.NotConfigured, ", ")))
+		}
+		if diff.SizeMismatch != "" {
+			health.Warnings = append(health.Warnings, fmt.Sprintf("Nodes disagree on the cluster size: %s", diff.SizeMismatch))
+		}
+	}
+
+	// Check clocks: offsets from this machine, skew between nodes and time synchronization
+	maxSkew := analysis.MaxClockSkew
+	if maxSkew == 0 {
+		maxSkew = defaultMaxClockSkew
+	}
+	var clocks []*ClockStatus
+	for _, node := range analysis.AllNodes {
+		health.Warnings = append(health.Warnings, clockWarnings(node.NodeIP, node.Clock, maxSkew)...)
+		clocks = append(clocks, node.Clock)
+	}
+	if skew, ok := clockSkew(clocks); ok && skew > maxSkew {
+		health.Warnings = append(health.Warnings, fmt.Sprintf("Clock skew between nodes is %s (threshold %s)", skew.Round(time.Millisecond), maxSkew))
+	}
+
+	// Running state transfers: the joiner is not usable until it completes
+	for _, transfer := range analysis.Transfers {
+		health.Warnings = append(health.Warnings, "State transfer in progress: "+transfer.String())
+	}
+
+	// Check versions: mixed server, provider, protocol, OS and package versions. A mix outside a
+	// supported rolling upgrade is an issue, not a warning.
+	for _, mismatch := range compareVersions(analysis.AllNodes) {
+		if mismatch.Supported {
+			health.Warnings = append(health.Warnings, mismatch.String())
+		} else {
+			health.Issues = append(health.Issues, mismatch.String())
+		}
+	}
 
+	// Check host resources: disk space for SST, inodes, gcache and memory
+	for _, node := range analysis.AllNodes {
+		health.Warnings = append(health.Warnings, node.Resources.warnings(node.NodeIP)...)
+	}
+
+	// Check MySQL/MariaDB status if available
 	for _, node := range analysis.AllNodes {
 		// Check if we have any MySQL data (either responding or error status)
 		if node.MySQLResponding || node.StatusError != "" {
-			hasMySQLData = true
+			health.HasMySQLData = true
 		}
 
 		if node.MySQLResponding {
-			respondingNodes++
+			health.RespondingNodes++
 			if node.IsReady {
-				readyNodes++
+				health.ReadyNodes++
 			}
 			if node.ClusterStatus == "Primary" {
-				primaryNodes++
+				health.PrimaryNodes++
 			}
 			if node.LocalStateComment == "Synced" {
-				syncedNodes++
+				health.SyncedNodes++
 			}
 		}
 	}
 
 	// MySQL/MariaDB issues
-	if hasMySQLData {
-		if respondingNodes != totalNodes {
-			issues = append(issues, fmt.Sprintf("MySQL/MariaDB not responding on %d/%d nodes", totalNodes-respondingNodes, totalNodes))
+	if health.HasMySQLData {
+		total, responding := health.TotalNodes, health.RespondingNodes
+		if responding != total {
+			health.Issues = append(health.Issues, fmt.Sprintf("MySQL/MariaDB not responding on %d/%d nodes", total-responding, total))
 		}
 
-		if respondingNodes > 0 {
-			if readyNodes != respondingNodes {
-				issues = append(issues, fmt.Sprintf("Nodes not ready: %d/%d", respondingNodes-readyNodes, respondingNodes))
+		if responding > 0 {
+			if health.ReadyNodes != responding {
+				health.Issues = append(health.Issues, fmt.Sprintf("Nodes not ready: %d/%d", responding-health.ReadyNodes, responding))
 			}
-			if primaryNodes != respondingNodes {
-				if primaryNodes == 0 {
-					issues = append(issues, "No nodes in Primary state")
+			if health.PrimaryNodes != responding {
+				if health.PrimaryNodes == 0 {
+					health.Issues = append(health.Issues, "No nodes in Primary state")
 				} else {
-					warnings = append(warnings, fmt.Sprintf("Only %d/%d nodes in Primary state", primaryNodes, respondingNodes))
+					health.Warnings = append(health.Warnings, fmt.Sprintf("Only %d/%d nodes in Primary state", health.PrimaryNodes, responding))
 				}
 			}
-			if syncedNodes != respondingNodes {
-				issues = append(issues, fmt.Sprintf("Nodes not synchronized: %d/%d", respondingNodes-syncedNodes, respondingNodes))
+			if health.SyncedNodes != responding {
+				health.Issues = append(health.Issues, fmt.Sprintf("Nodes not synchronized: %d/%d", responding-health.SyncedNodes, responding))
 			}
 		}
 	}
 
+	return health
+}
+
+// displayClusterSummary displays a final summary of the cluster health status
+func displayClusterSummary(analysis *ClusterAnalysis) {
+	summaryPrint("")
+	summaryPrint("=== CLUSTER HEALTH SUMMARY ===")
+	summaryPrint("")
+
+	health := evaluateClusterHealth(analysis)
+	totalNodes := health.TotalNodes
+	issues := health.Issues
+	warnings := health.Warnings
+	respondingNodes := health.RespondingNodes
+	readyNodes := health.ReadyNodes
+	primaryNodes := health.PrimaryNodes
+	syncedNodes := health.SyncedNodes
+	hasMySQLData := health.HasMySQLData
+
 	// Display summary
 	if len(issues) == 0 && len(warnings) == 0 {
 		summaryPrint("🎉 GALERA CLUSTER IN PERFECT HEALTH")
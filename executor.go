@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// CommandResult holds the outcome of a command run on a node
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// NodeExecutor runs shell commands on a node. Run only returns an error when the command could
+// not be run at all (connection lost, context cancelled); a non-zero exit is reported in ExitCode.
+type NodeExecutor interface {
+	Run(ctx context.Context, command string, stdin io.Reader) (*CommandResult, error)
+	Close() error
+}
+
+// CommandExitError is returned by runCommand when a command exits with a non-zero status
+type CommandExitError struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (e *CommandExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.ExitCode)
+}
+
+// runCommand runs a command and returns its stdout followed by its stderr. A non-zero exit
+// status is returned as a *CommandExitError together with the output.
+func runCommand(executor NodeExecutor, command string) (string, error) {
+	logDebug("Executing command: %s", command)
+	result, err := executor.Run(context.Background(), command, nil)
+	if err != nil {
+		return "", err
+	}
+	output := result.Stdout + result.Stderr
+	if result.ExitCode != 0 {
+		return output, &CommandExitError{ExitCode: result.ExitCode, Stderr: result.Stderr}
+	}
+	return output, nil
+}
+
+// LocalExecutor runs commands on this machine with bash
+type LocalExecutor struct{}
+
+// Run executes a command locally
+func (l *LocalExecutor) Run(ctx context.Context, command string, stdin io.Reader) (*CommandResult, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := &CommandResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			result.ExitCode = exitErr.ExitCode()
+			return result, nil
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// Close does nothing for local execution
+func (l *LocalExecutor) Close() error {
+	return nil
+}
+
+// Run executes a command on the remote server in a new session on the shared connection
+func (s *SSHClient) Run(ctx context.Context, command string, stdin io.Reader) (*CommandResult, error) {
+	session, err := s.newSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(command)
+	}()
+
+	select {
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		return nil, ctx.Err()
+	case err = <-done:
+	}
+
+	result := &CommandResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	if err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitStatus()
+			return result, nil
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// ContainerExecutor runs commands inside a container through an executor on the container host
+type ContainerExecutor struct {
+	host      NodeExecutor
+	transport *NodeTransport
+}
+
+// Run executes a command inside the container
+func (c *ContainerExecutor) Run(ctx context.Context, command string, stdin io.Reader) (*CommandResult, error) {
+	return c.host.Run(ctx, c.transport.wrapCommand(command), stdin)
+}
+
+// Close does nothing: the container host executor is shared and closed on its own
+func (c *ContainerExecutor) Close() error {
+	return nil
+}
+
+// serviceExecutor returns the executor for service commands: the container host for
+// containerized nodes, the node itself otherwise
+func serviceExecutor(executor NodeExecutor, service *ServiceBackend) NodeExecutor {
+	if container, ok := executor.(*ContainerExecutor); ok && service.runsOnHost() {
+		return container.host
+	}
+	return executor
+}
+
+// FakeResponse is a canned result returned by FakeExecutor for commands containing Match
+type FakeResponse struct {
+	Match  string
+	Result CommandResult
+}
+
+// FakeExecutor is a NodeExecutor that returns canned results, for tests
+type FakeExecutor struct {
+	Responses []FakeResponse                                    // Checked in order, first match wins
+	Handler   func(command string, stdin string) *CommandResult // Used when no response matches
+	Commands  []string                                          // Every command run, in order
+	mu        sync.Mutex
+}
+
+// Run records the command and returns the first matching canned result
+func (f *FakeExecutor) Run(ctx context.Context, command string, stdin io.Reader) (*CommandResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	input := ""
+	if stdin != nil {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		input = string(data)
+	}
+
+	f.mu.Lock()
+	f.Commands = append(f.Commands, command)
+	f.mu.Unlock()
+
+	for _, response := range f.Responses {
+		if strings.Contains(command, response.Match) {
+			result := response.Result
+			return &result, nil
+		}
+	}
+	if f.Handler != nil {
+		if result := f.Handler(command, input); result != nil {
+			return result, nil
+		}
+	}
+	return &CommandResult{Stderr: "command not found", ExitCode: 127}, nil
+}
+
+// Close does nothing for the fake executor
+func (f *FakeExecutor) Close() error {
+	return nil
+}
+
+// nodeExecutors is the per-run connection pool: one executor per node, shared by every subsystem
+var (
+	nodeExecutors   = make(map[string]NodeExecutor)
+	nodeExecutorsMu sync.Mutex
+)
+
+// connectNodeExecutor opens a new executor for a node; tests replace it to plug in fakes
+var connectNodeExecutor func(ip string, config *Config) (NodeExecutor, *SSHConnectionInfo, error)
+
+func init() {
+	connectNodeExecutor = dialNodeExecutor
+}
+
+// dialNodeExecutor opens an executor using the node's configured transport
+func dialNodeExecutor(ip string, config *Config) (NodeExecutor, *SSHConnectionInfo, error) {
+	transport := getNodeTransport(ip, config)
+	if transport != nil {
+		return connectContainerExecutor(ip, transport, config)
+	}
+	if isLocalhost(ip) {
+		return &LocalExecutor{}, nil, nil
+	}
+	client, connInfo, err := createSSHConnectionWithNodeCredentials(ip, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.markCredentialsUsed(ip, credentialSSH)
+	return client, connInfo, nil
+}
+
+// openNodeExecutor returns the shared executor for a node, connecting if needed. connInfo is
+// only returned when a new SSH connection to the node itself was made.
+func openNodeExecutor(ip string, config *Config) (NodeExecutor, *SSHConnectionInfo, error) {
+	nodeExecutorsMu.Lock()
+	executor, ok := nodeExecutors[ip]
+	nodeExecutorsMu.Unlock()
+	if ok {
+		return executor, nil, nil
+	}
+
+	// Connect without holding the lock: container nodes open their host's executor first
+	executor, connInfo, err := connectNodeExecutor(ip, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodeExecutorsMu.Lock()
+	defer nodeExecutorsMu.Unlock()
+	if existing, ok := nodeExecutors[ip]; ok {
+		executor.Close()
+		return existing, nil, nil
+	}
+	nodeExecutors[ip] = executor
+	return executor, connInfo, nil
+}
+
+// getNodeExecutor returns the shared executor for a node
+func getNodeExecutor(ip string, config *Config) (NodeExecutor, error) {
+	executor, _, err := openNodeExecutor(ip, config)
+	return executor, err
+}
+
+// closeNodeExecutors closes every executor opened during this run
+func closeNodeExecutors() {
+	nodeExecutorsMu.Lock()
+	defer nodeExecutorsMu.Unlock()
+
+	if len(nodeExecutors) > 0 {
+		logVerbose("Closing %d node connections (%d SSH handshakes this run)", len(nodeExecutors), atomic.LoadInt64(&sshHandshakes))
+	}
+	for ip, executor := range nodeExecutors {
+		if err := executor.Close(); err != nil {
+			logDebug("Error closing connection to %s: %v", ip, err)
+		}
+		delete(nodeExecutors, ip)
+	}
+}
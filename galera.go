@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
@@ -10,142 +9,20 @@ import (
 
 // getGaleraClusterInfoLocal retrieves Galera cluster configuration from localhost
 func getGaleraClusterInfoLocal(nodeIP string) (*GaleraClusterInfo, error) {
-	clusterInfo := &GaleraClusterInfo{
-		NodeIP: nodeIP,
-	}
-
-	logNormal("🔍 Searching for cluster information locally...")
-
-	// Search recursively for all .cnf files in /etc/mysql and also check /etc/my.cnf
-	logVerbose("📁 Searching for configuration files...")
-
-	var foundConfigs []string
-
-	// Find all .cnf files recursively in /etc/mysql
-	cmd := exec.Command("find", "/etc/mysql", "-name", "*.cnf", "-type", "f")
-	output, err := cmd.Output()
-	if err == nil {
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line != "" {
-				foundConfigs = append(foundConfigs, line)
-			}
-		}
-	}
-
-	// Also check /etc/my.cnf
-	cmd = exec.Command("test", "-f", "/etc/my.cnf")
-	if cmd.Run() == nil {
-		foundConfigs = append(foundConfigs, "/etc/my.cnf")
-	}
-
-	if len(foundConfigs) == 0 {
-		return nil, fmt.Errorf("no MySQL configuration files found in /etc/mysql or /etc/my.cnf")
-	}
-
-	logVerbose("📁 Configuration files found: %d files", len(foundConfigs))
-	for _, config := range foundConfigs {
-		logDebug("   - %s", config)
-	}
-
-	// Search in each configuration file
-	logVerbose("Analyzing %d configuration files", len(foundConfigs))
-	for _, configFile := range foundConfigs {
-		logVerbose("   Analyzing %s...", configFile)
-
-		cmd := exec.Command("cat", configFile)
-		content, err := cmd.Output()
-		if err != nil {
-			logDebug("Error reading %s: %v", configFile, err)
-			continue
-		}
-
-		contentStr := string(content)
-
-		// Look for wsrep_cluster_name
-		if clusterInfo.ClusterName == "" {
-			if name := extractConfigValue(contentStr, "wsrep_cluster_name"); name != "" {
-				clusterInfo.ClusterName = name
-				logVerbose("   ✓ wsrep_cluster_name found in %s", configFile)
-			}
-		}
-
-		// Look for wsrep_cluster_address
-		if clusterInfo.ClusterAddress == "" {
-			if address := extractConfigValue(contentStr, "wsrep_cluster_address"); address != "" {
-				clusterInfo.ClusterAddress = address
-				logVerbose("   ✓ wsrep_cluster_address found in %s", configFile)
-			}
-		}
-
-		// Look for wsrep_node_name
-		if clusterInfo.NodeName == "" {
-			if name := extractConfigValue(contentStr, "wsrep_node_name"); name != "" {
-				clusterInfo.NodeName = name
-			}
-		}
-
-		// Look for wsrep_node_address
-		if clusterInfo.NodeAddress == "" {
-			if address := extractConfigValue(contentStr, "wsrep_node_address"); address != "" {
-				clusterInfo.NodeAddress = address
-			}
-		}
-	}
-
-	// Verify we have essential information
-	if clusterInfo.ClusterName == "" && clusterInfo.ClusterAddress == "" {
-		return nil, fmt.Errorf("no Galera configuration found in any configuration file")
-	}
-
-	return clusterInfo, nil
+	return getGaleraClusterInfo(&LocalExecutor{}, nodeIP)
 }
 
 // getGaleraClusterInfo retrieves Galera cluster configuration from a node
-func getGaleraClusterInfo(sshClient *SSHClient, nodeIP string) (*GaleraClusterInfo, error) {
+func getGaleraClusterInfo(executor NodeExecutor, nodeIP string) (*GaleraClusterInfo, error) {
 	clusterInfo := &GaleraClusterInfo{
 		NodeIP: nodeIP,
 	}
 
-	// Helper function to execute commands either locally or via SSH
-	executeCommand := func(cmd string) (string, error) {
-		if sshClient == nil {
-			// Execute locally for localhost
-			return executeLocalCommand(cmd)
-		} else {
-			// Execute via SSH for remote nodes
-			return sshClient.executeCommand(cmd)
-		}
-	}
-
 	logNormal("🔍 Searching for cluster information...")
 
 	// Search recursively for all .cnf files in /etc/mysql and also check /etc/my.cnf
 	logVerbose("📁 Searching for configuration files...")
-
-	var foundConfigs []string
-
-	// Find all .cnf files recursively in /etc/mysql
-	output, err := executeCommand("find /etc/mysql -name '*.cnf' -type f 2>/dev/null")
-	if err == nil {
-		lines := strings.Split(strings.TrimSpace(output), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line != "" {
-				foundConfigs = append(foundConfigs, line)
-			}
-		}
-	}
-
-	// Also check /etc/my.cnf
-	output, err = executeCommand("test -f /etc/my.cnf && echo '/etc/my.cnf' || echo ''")
-	if err == nil {
-		line := strings.TrimSpace(output)
-		if line != "" {
-			foundConfigs = append(foundConfigs, line)
-		}
-	}
+	foundConfigs := findConfigFiles(executor)
 
 	if len(foundConfigs) == 0 {
 		return nil, fmt.Errorf("no MySQL configuration files found in /etc/mysql or /etc/my.cnf")
@@ -161,7 +38,7 @@ func getGaleraClusterInfo(sshClient *SSHClient, nodeIP string) (*GaleraClusterIn
 		logVerbose("   Analyzing %s...", configPath)
 
 		// Read file content
-		content, err := executeCommand(fmt.Sprintf("cat %s", configPath))
+		content, err := runCommand(executor, fmt.Sprintf("cat %s", configPath))
 		if err != nil {
 			logVerbose("   ⚠️  Error reading %s: %v", configPath, err)
 			continue
@@ -194,6 +71,28 @@ func getGaleraClusterInfo(sshClient *SSHClient, nodeIP string) (*GaleraClusterIn
 			}
 		}
 
+		// Search for the Galera ports settings
+		if clusterInfo.ProviderOptions == "" {
+			clusterInfo.ProviderOptions = extractConfigValue(content, "wsrep_provider_options")
+		}
+		if clusterInfo.SSTReceiveAddress == "" {
+			clusterInfo.SSTReceiveAddress = extractConfigValue(content, "wsrep_sst_receive_address")
+		}
+		if clusterInfo.SSTMethod == "" {
+			clusterInfo.SSTMethod = extractConfigValue(content, "wsrep_sst_method")
+		}
+
+		// Search for the storage and memory settings
+		if clusterInfo.DataDir == "" {
+			clusterInfo.DataDir = extractConfigValue(content, "datadir")
+		}
+		if clusterInfo.TmpDir == "" {
+			clusterInfo.TmpDir = extractConfigValue(content, "tmpdir")
+		}
+		if clusterInfo.BufferPoolSize == "" {
+			clusterInfo.BufferPoolSize = extractConfigValue(content, "innodb_buffer_pool_size")
+		}
+
 		// Search for wsrep_node_address
 		if clusterInfo.NodeAddress == "" {
 			nodeAddress := extractConfigValue(content, "wsrep_node_address")
@@ -206,7 +105,7 @@ func getGaleraClusterInfo(sshClient *SSHClient, nodeIP string) (*GaleraClusterIn
 
 	// Also try to get information from MySQL runtime variables
 	logVerbose("🔍 Checking MySQL runtime variables...")
-	runtimeInfo, err := getRuntimeMySQLInfo(sshClient)
+	runtimeInfo, err := getRuntimeMySQLInfo(executor)
 	if err == nil {
 		if clusterInfo.ClusterName == "" && runtimeInfo.ClusterName != "" {
 			clusterInfo.ClusterName = runtimeInfo.ClusterName
@@ -226,9 +125,38 @@ func getGaleraClusterInfo(sshClient *SSHClient, nodeIP string) (*GaleraClusterIn
 		}
 	}
 
+	clusterInfo.MachineID = readMachineID(executor)
+	clusterInfo.Hostname = readHostname(executor)
 	return clusterInfo, nil
 }
 
+// findConfigFiles returns the .cnf files under /etc/mysql and /etc/my.cnf if it exists
+func findConfigFiles(executor NodeExecutor) []string {
+	var foundConfigs []string
+
+	// Find all .cnf files recursively in /etc/mysql
+	output, err := runCommand(executor, "find /etc/mysql -name '*.cnf' -type f 2>/dev/null")
+	if err == nil {
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line != "" {
+				foundConfigs = append(foundConfigs, line)
+			}
+		}
+	}
+
+	// Also check /etc/my.cnf
+	output, err = runCommand(executor, "test -f /etc/my.cnf && echo '/etc/my.cnf' || echo ''")
+	if err == nil {
+		line := strings.TrimSpace(output)
+		if line != "" {
+			foundConfigs = append(foundConfigs, line)
+		}
+	}
+	return foundConfigs
+}
+
 // extractConfigValue extracts a configuration value from file content
 func extractConfigValue(content, key string) string {
 	lines := strings.Split(content, "\n")
@@ -259,17 +187,7 @@ func extractConfigValue(content, key string) string {
 }
 
 // getRuntimeMySQLInfo gets Galera information from MySQL runtime variables
-func getRuntimeMySQLInfo(sshClient *SSHClient) (*GaleraClusterInfo, error) {
-	// Helper function to execute commands either locally or via SSH
-	executeCommand := func(cmd string) (string, error) {
-		if sshClient == nil {
-			// Execute locally for localhost
-			return executeLocalCommand(cmd)
-		} else {
-			// Execute via SSH for remote nodes
-			return sshClient.executeCommand(cmd)
-		}
-	}
+func getRuntimeMySQLInfo(executor NodeExecutor) (*GaleraClusterInfo, error) {
 
 	// Try to get information from runtime variables
 	queries := []string{
@@ -282,7 +200,7 @@ func getRuntimeMySQLInfo(sshClient *SSHClient) (*GaleraClusterInfo, error) {
 	info := &GaleraClusterInfo{}
 
 	for _, query := range queries {
-		output, err := executeCommand(query)
+		output, err := runCommand(executor, query)
 		if err != nil {
 			continue // If MySQL is not accessible, continue
 		}
@@ -319,70 +237,51 @@ func getRuntimeMySQLInfo(sshClient *SSHClient) (*GaleraClusterInfo, error) {
 	return info, nil
 }
 
-// checkMySQLStatus checks MySQL/MariaDB status on a node
-func checkMySQLStatus(sshClient *SSHClient, nodeIP string, mysqlCreds *MySQLConnectionInfo, info *GaleraClusterInfo) {
-	// Helper function to execute commands either locally or via SSH
-	executeCommand := func(cmd string) (string, error) {
-		if sshClient == nil {
-			// Execute locally for localhost
-			return executeLocalCommand(cmd)
-		} else {
-			// Execute via SSH for remote nodes
-			return sshClient.executeCommand(cmd)
-		}
-	}
-
+// checkMySQLStatus checks MySQL/MariaDB status on a node, trying each candidate credential in turn.
+// It returns the credentials that worked, or nil if none did.
+func checkMySQLStatus(executor NodeExecutor, nodeIP string, candidates []*MySQLConnectionInfo, service *ServiceBackend, info *GaleraClusterInfo) *MySQLConnectionInfo {
 	// First, check if MySQL/MariaDB service is running
-	serviceCheck, _ := executeCommand("systemctl is-active mysql mariadb 2>/dev/null || service mysql status 2>/dev/null || service mariadb status 2>/dev/null")
-	if !strings.Contains(serviceCheck, "active") && !strings.Contains(serviceCheck, "running") {
+	serviceCheck, _ := runCommand(serviceExecutor(executor, service), service.statusCommand())
+	if strings.TrimSpace(serviceCheck) != "active" {
 		// Get detailed service status
-		serviceStatus, _ := executeCommand("systemctl status mysql mariadb 2>/dev/null | head -10")
-		info.StatusError = fmt.Sprintf("MySQL/MariaDB service is not running. Status: %s", strings.TrimSpace(serviceStatus))
+		serviceStatus, _ := runCommand(serviceExecutor(executor, service), service.statusDetailCommand())
+		info.StatusError = fmt.Sprintf("MySQL/MariaDB service (%s) is not running. Status: %s", service, strings.TrimSpace(serviceStatus))
 
 		// Provide suggestions for starting the service
-		suggestions := getSuggestionsForInactiveService(sshClient)
+		suggestions := getSuggestionsForInactiveService(executor, service)
 		if suggestions != "" {
 			info.StatusError += fmt.Sprintf(". Suggestions: %s", suggestions)
 		}
-		return
+		return nil
 	}
 
-	// Try different connection methods
+	// Try each credential over TCP first (more reliable for remote checks), then the socket
 	var mysqlCmd string
-	if mysqlCreds.Password != "" {
-		// Try TCP connection first (more reliable for remote checks)
-		mysqlCmd = fmt.Sprintf("mysql -h 127.0.0.1 -P 3306 -u %s -p'%s'", mysqlCreds.Username, mysqlCreds.Password)
-	} else {
-		mysqlCmd = fmt.Sprintf("mysql -h 127.0.0.1 -P 3306 -u %s", mysqlCreds.Username)
-	}
-
-	// Test basic connectivity first
-	testCmd := fmt.Sprintf("%s -e \"SELECT 1;\" 2>&1", mysqlCmd)
-	output, err := executeCommand(testCmd)
-	if err != nil || strings.Contains(output, "ERROR") {
-		// If TCP fails, try socket connection
-		if mysqlCreds.Password != "" {
-			mysqlCmd = fmt.Sprintf("mysql -u %s -p'%s'", mysqlCreds.Username, mysqlCreds.Password)
-		} else {
-			mysqlCmd = fmt.Sprintf("mysql -u %s", mysqlCreds.Username)
-		}
-
-		testCmd = fmt.Sprintf("%s -e \"SELECT 1;\" 2>&1", mysqlCmd)
-		output, err = executeCommand(testCmd)
-		if err != nil || strings.Contains(output, "ERROR") {
-			// Get diagnostic information
-			diagnostic := diagnoseMySQL(sshClient, nodeIP)
-			info.StatusError = fmt.Sprintf("MySQL connection failed. Error: %s. Diagnostic: %s", strings.TrimSpace(output), diagnostic)
-			return
+	var working *MySQLConnectionInfo
+	var failures []string
+	for _, creds := range candidates {
+		cmd, output, ok := connectMySQL(executor, creds)
+		if ok {
+			mysqlCmd, working = cmd, creds
+			break
 		}
+		logVerbose("      ⚠️  MySQL login as %s failed on %s: %s", creds.describe(), nodeIP, strings.TrimSpace(output))
+		failures = append(failures, fmt.Sprintf("%s: %s", creds.describe(), strings.TrimSpace(output)))
 	}
+	if working == nil {
+		// Get diagnostic information
+		diagnostic := diagnoseMySQL(executor, nodeIP, service)
+		info.StatusError = fmt.Sprintf("MySQL connection failed. Error: %s. Diagnostic: %s", strings.Join(failures, "; "), diagnostic)
+		return nil
+	}
+	logVerbose("      ✓ MySQL login as %s on %s", working.describe(), nodeIP)
 
 	// Check cluster size
 	cmd := fmt.Sprintf("%s -e \"SHOW STATUS LIKE 'wsrep_cluster_size';\" 2>&1", mysqlCmd)
-	output, err = executeCommand(cmd)
+	output, err := runCommand(executor, cmd)
 	if err != nil || strings.Contains(output, "ERROR") {
 		info.StatusError = fmt.Sprintf("Failed to get cluster size. Error: %s", strings.TrimSpace(output))
-		return
+		return working
 	}
 
 	if parseClusterSize(output, info) {
@@ -390,55 +289,45 @@ func checkMySQLStatus(sshClient *SSHClient, nodeIP string, mysqlCreds *MySQLConn
 
 		// Check cluster status
 		cmd = fmt.Sprintf("%s -e \"SHOW STATUS LIKE 'wsrep_cluster_status';\" 2>&1", mysqlCmd)
-		if output, err := executeCommand(cmd); err == nil && !strings.Contains(output, "ERROR") {
+		if output, err := runCommand(executor, cmd); err == nil && !strings.Contains(output, "ERROR") {
 			parseClusterStatus(output, info)
 		}
 
 		// Check if node is ready
 		cmd = fmt.Sprintf("%s -e \"SHOW STATUS LIKE 'wsrep_ready';\" 2>&1", mysqlCmd)
-		if output, err := executeCommand(cmd); err == nil && !strings.Contains(output, "ERROR") {
+		if output, err := runCommand(executor, cmd); err == nil && !strings.Contains(output, "ERROR") {
 			parseReadyStatus(output, info)
 		}
 
 		// Check local state comment
 		cmd = fmt.Sprintf("%s -e \"SHOW STATUS LIKE 'wsrep_local_state_comment';\" 2>&1", mysqlCmd)
-		if output, err := executeCommand(cmd); err == nil && !strings.Contains(output, "ERROR") {
+		if output, err := runCommand(executor, cmd); err == nil && !strings.Contains(output, "ERROR") {
 			parseLocalStateComment(output, info)
 		}
 	} else {
 		info.StatusError = "Could not retrieve cluster size - node may not be part of Galera cluster"
 	}
+	return working
 }
 
 // diagnoseMySQL provides diagnostic information for MySQL connection issues
-func diagnoseMySQL(sshClient *SSHClient, nodeIP string) string {
+func diagnoseMySQL(executor NodeExecutor, nodeIP string, service *ServiceBackend) string {
 	var diagnostic []string
 
-	// Helper function to execute commands either locally or via SSH
-	executeCmd := func(cmd string) (string, error) {
-		if sshClient == nil {
-			// Execute locally for localhost
-			return executeLocalCommand(cmd)
-		} else {
-			// Execute via SSH for remote nodes
-			return sshClient.executeCommand(cmd)
-		}
-	}
-
 	// Check if MySQL/MariaDB is installed
-	checkInstalled, _ := executeCmd("which mysql mysqld mariadb 2>/dev/null")
+	checkInstalled, _ := runCommand(executor, "which mysql mysqld mariadb 2>/dev/null")
 	if checkInstalled == "" {
 		diagnostic = append(diagnostic, "MySQL/MariaDB client not found - may not be installed")
 	}
 
 	// Check service status with more detail
-	serviceStatus, _ := executeCmd("systemctl status mysql mariadb 2>/dev/null | head -3")
+	serviceStatus, _ := runCommand(serviceExecutor(executor, service), service.statusDetailCommand()+" | head -3")
 	if serviceStatus != "" {
 		diagnostic = append(diagnostic, fmt.Sprintf("Service status: %s", strings.TrimSpace(serviceStatus)))
 	}
 
 	// Check if socket file exists
-	socketCheck, _ := executeCmd("ls -la /run/mysqld/mysqld.sock /var/lib/mysql/mysql.sock /tmp/mysql.sock 2>/dev/null")
+	socketCheck, _ := runCommand(executor, "ls -la /run/mysqld/mysqld.sock /var/lib/mysql/mysql.sock /tmp/mysql.sock 2>/dev/null")
 	if socketCheck != "" {
 		diagnostic = append(diagnostic, fmt.Sprintf("Socket files found: %s", strings.TrimSpace(socketCheck)))
 	} else {
@@ -446,14 +335,14 @@ func diagnoseMySQL(sshClient *SSHClient, nodeIP string) string {
 	}
 
 	// Check if port 3306 is listening (try multiple methods)
-	portCheck, _ := executeCmd("ss -tlnp | grep 3306 2>/dev/null")
+	portCheck, _ := runCommand(executor, "ss -tlnp | grep 3306 2>/dev/null")
 	if portCheck == "" {
 		// Try netstat if ss didn't work
-		portCheck, _ = executeCmd("netstat -tlnp 2>/dev/null | grep 3306")
+		portCheck, _ = runCommand(executor, "netstat -tlnp 2>/dev/null | grep 3306")
 	}
 	if portCheck == "" {
 		// Try lsof as another fallback
-		portCheck, _ = executeCmd("lsof -i :3306 2>/dev/null")
+		portCheck, _ = runCommand(executor, "lsof -i :3306 2>/dev/null")
 	}
 	if portCheck != "" {
 		diagnostic = append(diagnostic, fmt.Sprintf("Port 3306 status: %s", strings.TrimSpace(portCheck)))
@@ -462,7 +351,7 @@ func diagnoseMySQL(sshClient *SSHClient, nodeIP string) string {
 	}
 
 	// Check for recent service errors in logs
-	errorCheck, _ := executeCmd("journalctl -u mysql -u mariadb --no-pager -n 3 --since '1 hour ago' 2>/dev/null | grep -i error | tail -1")
+	errorCheck, _ := runCommand(serviceExecutor(executor, service), service.logCommand("1 hour ago", 50)+" | grep -i error | tail -1")
 	if errorCheck != "" {
 		diagnostic = append(diagnostic, fmt.Sprintf("Recent error: %s", strings.TrimSpace(errorCheck)))
 	}
@@ -471,40 +360,28 @@ func diagnoseMySQL(sshClient *SSHClient, nodeIP string) string {
 }
 
 // getSuggestionsForInactiveService provides suggestions for starting MySQL/MariaDB service
-func getSuggestionsForInactiveService(sshClient *SSHClient) string {
+func getSuggestionsForInactiveService(executor NodeExecutor, service *ServiceBackend) string {
 	var suggestions []string
 
-	// Helper function to execute commands either locally or via SSH
-	executeCmd := func(cmd string) (string, error) {
-		if sshClient == nil {
-			// Execute locally for localhost
-			return executeLocalCommand(cmd)
-		} else {
-			// Execute via SSH for remote nodes
-			return sshClient.executeCommand(cmd)
-		}
-	}
-
-	// Check which service name to use
-	mysqlCheck, _ := executeCmd("systemctl list-unit-files | grep -E '^(mysql|mariadb)' | head -1")
-	if strings.Contains(mysqlCheck, "mysql") {
-		suggestions = append(suggestions, "Try: sudo systemctl start mysql && sudo systemctl enable mysql")
-	} else if strings.Contains(mysqlCheck, "mariadb") {
-		suggestions = append(suggestions, "Try: sudo systemctl start mariadb && sudo systemctl enable mariadb")
+	// Suggest the start command for the detected service backend
+	if startCmd, err := service.startCommand(); err == nil {
+		suggestions = append(suggestions, fmt.Sprintf("Try: sudo %s", startCmd))
 	} else {
-		suggestions = append(suggestions, "Try: sudo systemctl start mysql/mariadb")
+		suggestions = append(suggestions, err.Error())
 	}
 
 	// Check for common config issues
-	configCheck, _ := executeCmd("systemctl status mysql mariadb 2>/dev/null | grep -i 'failed\\|error'")
+	configCheck, _ := runCommand(serviceExecutor(executor, service), service.statusDetailCommand()+" | grep -i 'failed\\|error'")
 	if configCheck != "" {
-		suggestions = append(suggestions, "Check service logs: sudo journalctl -u mysql -u mariadb --no-pager -n 20")
+		suggestions = append(suggestions, fmt.Sprintf("Check service logs: sudo %s", service.logCommand("1 hour ago", 20)))
 	}
 
 	// Check if it's a Galera specific issue
-	galeraCheck, _ := executeCmd("grep -r 'wsrep\\|galera' /etc/mysql/ 2>/dev/null | head -1")
+	galeraCheck, _ := runCommand(executor, "grep -r 'wsrep\\|galera' /etc/mysql/ 2>/dev/null | head -1")
 	if galeraCheck != "" {
-		suggestions = append(suggestions, "For Galera cluster startup, you may need to bootstrap: sudo galera_new_cluster")
+		if bootstrapCmd, err := service.bootstrapCommand(); err == nil {
+			suggestions = append(suggestions, fmt.Sprintf("For Galera cluster startup, you may need to bootstrap: sudo %s", bootstrapCmd))
+		}
 	}
 
 	return strings.Join(suggestions, "; ")
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultGaleraPort is the group communication port used when a gcomm:// member has none
+const defaultGaleraPort = 4567
+
+// GcommMember is one node of a gcomm:// cluster address
+type GcommMember struct {
+	Host string // Hostname or IP address, without brackets
+	Port int    // Port, 0 when not given
+	IPv6 bool   // Host is an IPv6 address
+}
+
+// GcommAddress is a parsed wsrep_cluster_address
+type GcommAddress struct {
+	Members []GcommMember
+	Options map[string]string // Query options such as pc.wait_prim=no
+}
+
+// String returns the member as written in a cluster address, bracketing IPv6 hosts
+func (m GcommMember) String() string {
+	host := m.Host
+	if m.IPv6 {
+		host = "[" + host + "]"
+	}
+	if m.Port != 0 {
+		return fmt.Sprintf("%s:%d", host, m.Port)
+	}
+	return host
+}
+
+// parseGcommAddress parses a gcomm://host1,[fd00::2]:4567,host3?pc.wait_prim=no cluster address.
+// An empty member list ("gcomm://") is valid and means the node bootstraps a new cluster.
+func parseGcommAddress(value string) (*GcommAddress, error) {
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	rest, ok := strings.CutPrefix(value, "gcomm://")
+	if !ok {
+		return nil, fmt.Errorf("not a gcomm:// address: %q", value)
+	}
+
+	address := &GcommAddress{Options: make(map[string]string)}
+	members, query, _ := strings.Cut(rest, "?")
+	if query != "" {
+		options, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid options in %q: %v", value, err)
+		}
+		for key, values := range options {
+			address.Options[key] = values[len(values)-1]
+		}
+	}
+
+	for _, member := range strings.Split(members, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		parsed, err := parseGcommMember(member)
+		if err != nil {
+			return nil, fmt.Errorf("invalid member in %q: %v", value, err)
+		}
+		address.Members = append(address.Members, parsed)
+	}
+	return address, nil
+}
+
+// parseGcommMember parses host, host:port, [ipv6] or [ipv6]:port. A bare IPv6 address without
+// brackets is accepted too, without a port.
+func parseGcommMember(member string) (GcommMember, error) {
+	host, portText := member, ""
+	switch {
+	case strings.HasPrefix(member, "["):
+		end := strings.Index(member, "]")
+		if end == -1 {
+			return GcommMember{}, fmt.Errorf("missing ']' in %q", member)
+		}
+		host = member[1:end]
+		if after := member[end+1:]; after != "" {
+			if !strings.HasPrefix(after, ":") {
+				return GcommMember{}, fmt.Errorf("unexpected %q after ']' in %q", after, member)
+			}
+			portText = after[1:]
+		}
+	case strings.Count(member, ":") == 1:
+		host, portText, _ = strings.Cut(member, ":")
+	}
+
+	parsed := GcommMember{Host: host, IPv6: strings.Contains(host, ":")}
+	if host == "" {
+		return GcommMember{}, fmt.Errorf("missing host in %q", member)
+	}
+	if portText != "" {
+		port, err := strconv.Atoi(portText)
+		if err != nil || port <= 0 || port > 65535 {
+			return GcommMember{}, fmt.Errorf("invalid port in %q", member)
+		}
+		parsed.Port = port
+	}
+	return parsed, nil
+}
+
+// hosts returns the member hosts, in order
+func (g *GcommAddress) hosts() []string {
+	var hosts []string
+	for _, member := range g.Members {
+		hosts = append(hosts, member.Host)
+	}
+	return hosts
+}
+
+// lookupHost resolves hostnames; tests replace it
+var lookupHost = net.LookupHost
+
+var (
+	resolvedHosts   = make(map[string][]string)
+	resolvedHostsMu sync.Mutex
+)
+
+// resolveHost returns the sorted addresses of a host, the host itself for IP addresses, or the
+// lower-cased name when it does not resolve. Results are cached for the run.
+func resolveHost(host string) []string {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{ip.String()}
+	}
+	host = strings.ToLower(host)
+
+	resolvedHostsMu.Lock()
+	defer resolvedHostsMu.Unlock()
+	if addresses, ok := resolvedHosts[host]; ok {
+		return addresses
+	}
+	addresses, err := lookupHost(host)
+	if err != nil || len(addresses) == 0 {
+		logDebug("Could not resolve %s: %v", host, err)
+		addresses = []string{host}
+	} else {
+		for i, address := range addresses {
+			if ip := net.ParseIP(address); ip != nil {
+				addresses[i] = ip.String()
+			}
+		}
+		sort.Strings(addresses)
+	}
+	resolvedHosts[host] = addresses
+	return addresses
+}
+
+// sameHost reports whether two hosts, by name or address, refer to the same node
+func sameHost(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	for _, x := range resolveHost(a) {
+		for _, y := range resolveHost(b) {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsHost reports whether hosts holds a name or address of host
+func containsHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if sameHost(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareGcommMembers compares two member lists as sets, matching hosts through DNS and treating a
+// missing port as the default Galera port. It returns the members only in a and only in b.
+func compareGcommMembers(a, b *GcommAddress) (onlyA, onlyB []string) {
+	matches := func(x, y GcommMember) bool {
+		return gcommPort(x) == gcommPort(y) && sameHost(x.Host, y.Host)
+	}
+	missing := func(from, in *GcommAddress) []string {
+		var result []string
+		for _, x := range from.Members {
+			found := false
+			for _, y := range in.Members {
+				if matches(x, y) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				result = append(result, x.String())
+			}
+		}
+		return result
+	}
+	return missing(a, b), missing(b, a)
+}
+
+// gcommPort returns the member's port, or the default Galera port
+func gcommPort(member GcommMember) int {
+	if member.Port == 0 {
+		return defaultGaleraPort
+	}
+	return member.Port
+}
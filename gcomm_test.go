@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeDNS resolves hostnames from a fixed table for the duration of a test
+func fakeDNS(t *testing.T, hosts map[string][]string) {
+	t.Helper()
+	previous := lookupHost
+	lookupHost = func(host string) ([]string, error) {
+		if addresses, ok := hosts[host]; ok {
+			return addresses, nil
+		}
+		return nil, fmt.Errorf("lookup %s: no such host", host)
+	}
+	resolvedHosts = make(map[string][]string)
+	t.Cleanup(func() {
+		lookupHost = previous
+		resolvedHosts = make(map[string][]string)
+	})
+}
+
+func TestParseGcommAddress(t *testing.T) {
+	tests := []struct {
+		value       string
+		wantMembers string
+		wantOptions string
+		wantErr     bool
+	}{
+		{value: "gcomm://10.0.0.1,10.0.0.2,10.0.0.3", wantMembers: "10.0.0.1,10.0.0.2,10.0.0.3"},
+		{value: "gcomm://", wantMembers: ""},
+		{value: `"gcomm://db1:4567, db2:4568"`, wantMembers: "db1:4567,db2:4568"},
+		{value: "gcomm://[fd00::1]:4567,[fd00::2],fd00::3", wantMembers: "[fd00::1]:4567,[fd00::2],[fd00::3]"},
+		{value: "gcomm://10.0.0.1,10.0.0.2?pc.wait_prim=no&gmcast.listen_addr=tcp://0.0.0.0:4567",
+			wantMembers: "10.0.0.1,10.0.0.2", wantOptions: "gmcast.listen_addr=tcp://0.0.0.0:4567,pc.wait_prim=no"},
+		{value: "10.0.0.1,10.0.0.2", wantErr: true},
+		{value: "gcomm://10.0.0.1:port", wantErr: true},
+		{value: "gcomm://[fd00::1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		address, err := parseGcommAddress(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseGcommAddress(%q): expected an error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseGcommAddress(%q): %v", tt.value, err)
+			continue
+		}
+		var members, options []string
+		for _, member := range address.Members {
+			members = append(members, member.String())
+		}
+		for _, key := range []string{"gmcast.listen_addr", "pc.wait_prim"} {
+			if value, ok := address.Options[key]; ok {
+				options = append(options, key+"="+value)
+			}
+		}
+		if got := strings.Join(members, ","); got != tt.wantMembers {
+			t.Errorf("parseGcommAddress(%q) members = %q, want %q", tt.value, got, tt.wantMembers)
+		}
+		if got := strings.Join(options, ","); got != tt.wantOptions {
+			t.Errorf("parseGcommAddress(%q) options = %q, want %q", tt.value, got, tt.wantOptions)
+		}
+	}
+}
+
+func TestCompareClusterAddresses(t *testing.T) {
+	fakeDNS(t, map[string][]string{"db1": {"10.0.0.1"}, "db2": {"10.0.0.2"}})
+
+	tests := []struct {
+		address, reference, want string
+	}{
+		{"gcomm://10.0.0.1,10.0.0.2", "gcomm://10.0.0.2,10.0.0.1", ""},
+		{"gcomm://db1,db2:4567", "gcomm://10.0.0.1:4567,10.0.0.2", ""},
+		{"gcomm://10.0.0.1,10.0.0.2?pc.wait_prim=no", "gcomm://10.0.0.1,10.0.0.2", ""},
+		{"gcomm://db1,10.0.0.3", "gcomm://10.0.0.1,10.0.0.2", "extra: 10.0.0.3; missing: 10.0.0.2"},
+		{"gcomm://10.0.0.1:5567", "gcomm://10.0.0.1", "extra: 10.0.0.1:5567; missing: 10.0.0.1"},
+	}
+	for _, tt := range tests {
+		if got := compareClusterAddresses(tt.address, tt.reference); got != tt.want {
+			t.Errorf("compareClusterAddresses(%q, %q) = %q, want %q", tt.address, tt.reference, got, tt.want)
+		}
+	}
+
+	// A node listed by name is recognized as the initial node reached by address
+	if nodes := parseClusterNodes("gcomm://db1,db2"); !containsHost(nodes, "10.0.0.2") || containsHost(nodes, "10.0.0.3") {
+		t.Errorf("hosts %v not matched through DNS", nodes)
+	}
+}
@@ -7,6 +7,7 @@ toolchain go1.24.2
 require (
 	golang.org/x/crypto v0.40.0
 	golang.org/x/term v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/sys v0.34.0 // indirect
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// readMachineID returns the node's systemd machine-id, or "" if it has none
+func readMachineID(executor NodeExecutor) string {
+	output, err := runCommand(executor, "cat /etc/machine-id 2>/dev/null || cat /var/lib/dbus/machine-id 2>/dev/null")
+	if err != nil {
+		return ""
+	}
+	id := strings.TrimSpace(output)
+	if len(id) != 32 || strings.ContainsAny(id, " \n") {
+		return ""
+	}
+	return id
+}
+
+// readHostname returns the node's hostname, or "" if it can't be read
+func readHostname(executor NodeExecutor) string {
+	output, err := runCommand(executor, "hostname 2>/dev/null || uname -n")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
+
+// sameNodeAs reports whether two analyzed entries are the same server, and how that was established.
+// The most specific identifier both entries have decides: wsrep_gcomm_uuid, then server_uuid, then
+// the machine-id. Cloned VMs share a machine-id, so a machine-id match also needs the same hostname.
+func (n *GaleraClusterInfo) sameNodeAs(other *GaleraClusterInfo) (bool, string) {
+	switch {
+	case n.GcommUUID != "" && other.GcommUUID != "":
+		return n.GcommUUID == other.GcommUUID, "wsrep_gcomm_uuid " + n.GcommUUID
+	case n.ServerUUID != "" && other.ServerUUID != "":
+		return n.ServerUUID == other.ServerUUID, "server_uuid " + n.ServerUUID
+	case n.MachineID != "" && other.MachineID != "":
+		// Clones stopped together have no MySQL identity either: only the hostname tells them apart
+		if n.MachineID == other.MachineID && n.Hostname != "" && n.Hostname == other.Hostname {
+			return true, "machine-id " + n.MachineID + " and hostname " + n.Hostname
+		}
+	}
+	return false, ""
+}
+
+// findSameNode returns the node already analyzed that is the same server as info, and how it was identified
+func (a *ClusterAnalysis) findSameNode(info *GaleraClusterInfo) (*GaleraClusterInfo, string) {
+	for _, node := range a.AllNodes {
+		if same, reason := node.sameNodeAs(info); same {
+			return node, reason
+		}
+	}
+	return nil, ""
+}
+
+// hosts returns the address the node was analyzed through, followed by its aliases
+func (n *GaleraClusterInfo) hosts() []string {
+	return append([]string{n.NodeIP}, n.Aliases...)
+}
+
+// addAlias records another address of the node
+func (n *GaleraClusterInfo) addAlias(address string) {
+	if !containsHost(n.hosts(), address) {
+		n.Aliases = append(n.Aliases, address)
+	}
+}
+
+// describeIdentity returns the identifiers of a node for display
+func (n *GaleraClusterInfo) describeIdentity() string {
+	var parts []string
+	if n.GcommUUID != "" {
+		parts = append(parts, "gcomm "+n.GcommUUID)
+	}
+	if n.ServerUUID != "" {
+		parts = append(parts, "server "+n.ServerUUID)
+	}
+	if n.MachineID != "" {
+		parts = append(parts, "machine "+n.MachineID)
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mergeDuplicateNode records nodeIP as an alias of an already analyzed node when info is the same
+// server, and reports whether it did
+func (a *ClusterAnalysis) mergeDuplicateNode(nodeIP string, info *GaleraClusterInfo) bool {
+	existing, reason := a.findSameNode(info)
+	if existing == nil {
+		return false
+	}
+	existing.addAlias(nodeIP)
+	a.progress("      🔁 Same node as %s (%s) - recorded as an alias\n", existing.NodeIP, reason)
+	logVerbose("      Node %s is reachable as %s", existing.NodeIP, strings.Join(existing.hosts(), ", "))
+	return true
+}
+
+// aliasSummary returns "ip (also a, b)" for messages
+func (n *GaleraClusterInfo) aliasSummary() string {
+	if len(n.Aliases) == 0 {
+		return n.NodeIP
+	}
+	return fmt.Sprintf("%s (also %s)", n.NodeIP, strings.Join(n.Aliases, ", "))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNodeIdentityAliases(t *testing.T) {
+	fakeDNS(t, map[string][]string{})
+	members := []string{"db1", "10.0.0.2", "10.0.0.3", "10.0.1.3"}
+	node := func(ip, service string) *fakeNode {
+		return &fakeNode{IP: ip, Service: service, Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...)}}
+	}
+	first := node("10.0.0.1", "active")
+	first.Aliases = []string{"db1", "192.168.0.1"}
+	first.MachineID = "0f1e2d3c4b5a69788796a5b4c3d2e1f0"
+	// MySQL is down on the third node: only its machine-id identifies it
+	third := node("10.0.0.3", "inactive")
+	third.Aliases = []string{"10.0.1.3"}
+	third.MachineID = "33333333333333333333333333333333"
+	second := node("10.0.0.2", "active")
+	second.MachineID = first.MachineID // cloned VM: the gcomm UUID tells them apart
+	newFakeCluster(t, first, second, third)
+	config := newTestConfig(t)
+
+	// Connect through a VIP that is not listed anywhere
+	executor, err := getNodeExecutor("192.168.0.1", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialNode, err := getGaleraClusterInfo(executor, "192.168.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, _, err := performClusterAnalysis(initialNode, &SSHConnectionInfo{Username: "root", UsedKeys: true}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nodes []string
+	for _, node := range analysis.AllNodes {
+		nodes = append(nodes, node.aliasSummary())
+	}
+	want := "192.168.0.1 (also db1, 10.0.0.1), 10.0.0.2, 10.0.0.3 (also 10.0.1.3)"
+	if got := strings.Join(nodes, ", "); got != want {
+		t.Errorf("nodes = %q, want %q", got, want)
+	}
+	if diff := analysis.Membership; diff == nil || len(diff.NotInCluster) != 1 || diff.NotInCluster[0] != "10.0.0.3" || len(diff.NotConfigured) != 0 {
+		t.Errorf("membership = %+v", diff)
+	}
+	if same, _ := analysis.AllNodes[0].sameNodeAs(analysis.AllNodes[1]); same {
+		t.Error("nodes sharing a machine-id but not a gcomm UUID were merged")
+	}
+}
+
+func TestStoppedCloneIsNotMerged(t *testing.T) {
+	fakeDNS(t, map[string][]string{})
+	members := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip, service string) *fakeNode {
+		return &fakeNode{IP: ip, Service: service, MachineID: "0f1e2d3c4b5a69788796a5b4c3d2e1f0",
+			Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...)}}
+	}
+	// The three VMs were cloned from one image; MySQL is down on the third
+	first, second, third := node("10.0.0.1", "active"), node("10.0.0.2", "active"), node("10.0.0.3", "inactive")
+	newFakeCluster(t, first, second, third)
+	config := newTestConfig(t)
+
+	executor, err := getNodeExecutor("10.0.0.1", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialNode, err := getGaleraClusterInfo(executor, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, _, err := performClusterAnalysis(initialNode, &SSHConnectionInfo{Username: "root", UsedKeys: true}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nodes []string
+	for _, node := range analysis.AllNodes {
+		nodes = append(nodes, node.aliasSummary())
+	}
+	if got, want := strings.Join(nodes, ", "), "10.0.0.1, 10.0.0.2, 10.0.0.3"; got != want {
+		t.Errorf("nodes = %q, want %q", got, want)
+	}
+	if diff := analysis.Membership; diff == nil || len(diff.NotInCluster) != 1 || diff.NotInCluster[0] != "10.0.0.3" {
+		t.Errorf("membership = %+v", diff)
+	}
+
+	// The same stopped server reached through another address keeps its hostname
+	running := &GaleraClusterInfo{GcommUUID: "5b7c1e2a", MachineID: first.MachineID, Hostname: "db3"}
+	stopped := &GaleraClusterInfo{MachineID: first.MachineID, Hostname: "db3"}
+	if same, _ := running.sameNodeAs(stopped); !same {
+		t.Error("entries sharing a machine-id and hostname were not merged")
+	}
+}
+
+func TestStoppedClonesAreNotMerged(t *testing.T) {
+	fakeDNS(t, map[string][]string{})
+	members := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip string) *fakeNode {
+		return &fakeNode{IP: ip, Service: "inactive", MachineID: "0f1e2d3c4b5a69788796a5b4c3d2e1f0",
+			Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...)}}
+	}
+	// The whole cluster is down after an outage and its VMs were cloned from one image
+	newFakeCluster(t, node("10.0.0.1"), node("10.0.0.2"), node("10.0.0.3"))
+	config := newTestConfig(t)
+
+	executor, err := getNodeExecutor("10.0.0.1", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialNode, err := getGaleraClusterInfo(executor, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, _, err := performClusterAnalysis(initialNode, &SSHConnectionInfo{Username: "root", UsedKeys: true}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nodes []string
+	for _, node := range analysis.AllNodes {
+		nodes = append(nodes, node.aliasSummary())
+	}
+	if got, want := strings.Join(nodes, ", "), "10.0.0.1, 10.0.0.2, 10.0.0.3"; got != want {
+		t.Errorf("nodes = %q, want %q", got, want)
+	}
+
+	// Without hostnames a shared machine-id proves nothing
+	first, second := &GaleraClusterInfo{MachineID: "0f1e2d3c4b5a69788796a5b4c3d2e1f0"}, &GaleraClusterInfo{MachineID: "0f1e2d3c4b5a69788796a5b4c3d2e1f0"}
+	if same, _ := first.sameNodeAs(second); same {
+		t.Error("entries sharing only a machine-id were merged")
+	}
+}
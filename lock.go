@@ -0,0 +1,301 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Recovery lock settings
+const (
+	recoveryLockPath       = "/var/tmp/galerahealth-recovery.lock"
+	defaultRecoveryLockTTL = 2 * time.Hour
+	lockRetryInterval      = 5 * time.Second
+)
+
+// RecoveryLock is the content of the lock file placed on every node during recovery
+type RecoveryLock struct {
+	Owner     string    `json:"owner"`
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	Token     string    `json:"token"`
+	Purpose   string    `json:"purpose"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ClusterLock tracks the nodes on which this run holds the recovery lock
+type ClusterLock struct {
+	Lock      RecoveryLock
+	LockedIPs []string
+}
+
+// heldClusterLock is the lock this run holds, renewed while its recovery steps run
+var heldClusterLock *ClusterLock
+
+// isExpired reports whether the lock is past its expiry time
+func (l *RecoveryLock) isExpired() bool {
+	return time.Now().After(l.ExpiresAt)
+}
+
+// describe returns a one-line description of the lock holder
+func (l *RecoveryLock) describe() string {
+	return fmt.Sprintf("%s (pid %d on %s, %s) since %s, expires %s",
+		l.Owner, l.PID, l.Host, l.Purpose,
+		l.CreatedAt.Local().Format("2006-01-02 15:04:05"), l.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+// getRecoveryLockTTL returns the configured lock expiry
+func getRecoveryLockTTL(config *Config) time.Duration {
+	if config != nil && config.LockTTLSeconds > 0 {
+		return time.Duration(config.LockTTLSeconds) * time.Second
+	}
+	return defaultRecoveryLockTTL
+}
+
+// newRecoveryLock creates the lock record for this run
+func newRecoveryLock(purpose string, config *Config) (RecoveryLock, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return RecoveryLock{}, fmt.Errorf("failed to generate lock token: %v", err)
+	}
+	hostname, _ := os.Hostname()
+	now := time.Now().UTC()
+	return RecoveryLock{
+		Owner:     getOperatorName(),
+		PID:       os.Getpid(),
+		Host:      hostname,
+		Token:     hex.EncodeToString(token),
+		Purpose:   purpose,
+		CreatedAt: now,
+		ExpiresAt: now.Add(getRecoveryLockTTL(config)),
+	}, nil
+}
+
+// readNodeLock reads the recovery lock on a node; it returns nil if there is no lock
+func readNodeLock(ip string, config *Config) (*RecoveryLock, error) {
+	output, err := executeCommandOnNode(ip, fmt.Sprintf("cat %s 2>/dev/null || true", recoveryLockPath), config)
+	if err != nil {
+		return nil, err
+	}
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
+	}
+	var lock RecoveryLock
+	if err := json.Unmarshal([]byte(output), &lock); err != nil {
+		return nil, fmt.Errorf("unreadable lock file on %s: %v", ip, err)
+	}
+	return &lock, nil
+}
+
+// tryLockNode atomically creates the lock file on a node. It returns the current holder if
+// another run holds the lock.
+func tryLockNode(ip string, lock RecoveryLock, config *Config) (*RecoveryLock, error) {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal lock: %v", err)
+	}
+
+	// set -C (noclobber) makes the redirect fail if the file already exists
+	cmd := fmt.Sprintf("(set -C; printf '%%s\\n' %s > %s) 2>/dev/null", shellQuote(string(data)), recoveryLockPath)
+	if _, err := executeCommandOnNode(ip, cmd, config); err == nil {
+		return nil, nil
+	}
+
+	holder, readErr := readNodeLock(ip, config)
+	if readErr != nil {
+		return nil, readErr
+	}
+	if holder == nil {
+		// Lock disappeared between the two commands, retry once
+		if _, err := executeCommandOnNode(ip, cmd, config); err == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not create lock file on %s", ip)
+	}
+	if holder.Token == lock.Token {
+		return nil, nil
+	}
+	if holder.isExpired() {
+		logNormal("Removing expired recovery lock on %s held by %s", ip, holder.describe())
+		if err := removeNodeLock(ip, holder.Token, config); err != nil {
+			return nil, err
+		}
+		if _, err := executeCommandOnNode(ip, cmd, config); err == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not create lock file on %s after removing expired lock", ip)
+	}
+	return holder, nil
+}
+
+// removeNodeLock deletes the lock file on a node if it still holds the given token.
+// An empty token removes the lock regardless of its owner.
+func removeNodeLock(ip, token string, config *Config) error {
+	cmd := fmt.Sprintf("rm -f %s", recoveryLockPath)
+	if token != "" {
+		cmd = fmt.Sprintf("if grep -q %s %s 2>/dev/null; then rm -f %s; fi", shellQuote(token), recoveryLockPath, recoveryLockPath)
+	}
+	_, err := executeCommandOnNode(ip, cmd, config)
+	return err
+}
+
+// acquireClusterLock takes the recovery lock on the cluster nodes. A majority of nodes must be
+// reachable and locked; if another run holds the lock, it waits up to lockWait before refusing.
+func acquireClusterLock(clusterIPs []string, purpose string, config *Config) (*ClusterLock, error) {
+	lock, err := newRecoveryLock(purpose, config)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockWait)
+	for {
+		clusterLock := &ClusterLock{Lock: lock}
+		var holder *RecoveryLock
+		var unreachable []string
+
+		for _, ip := range clusterIPs {
+			current, err := tryLockNode(ip, lock, config)
+			if err != nil {
+				logVerbose("⚠️ Could not lock node %s: %v", ip, err)
+				unreachable = append(unreachable, ip)
+				continue
+			}
+			if current != nil {
+				holder = current
+				logMinimal("🔒 Node %s is locked by %s", ip, current.describe())
+				break
+			}
+			clusterLock.LockedIPs = append(clusterLock.LockedIPs, ip)
+		}
+
+		if holder == nil {
+			if len(clusterLock.LockedIPs)*2 <= len(clusterIPs) {
+				clusterLock.release(config)
+				return nil, fmt.Errorf("could only lock %d/%d nodes (unreachable: %s); a majority is required",
+					len(clusterLock.LockedIPs), len(clusterIPs), strings.Join(unreachable, ", "))
+			}
+			if len(unreachable) > 0 {
+				logMinimal("⚠️  Recovery lock not placed on unreachable nodes: %s", strings.Join(unreachable, ", "))
+			}
+			logNormal("🔒 Recovery lock acquired on %d/%d nodes", len(clusterLock.LockedIPs), len(clusterIPs))
+			heldClusterLock = clusterLock
+			return clusterLock, nil
+		}
+
+		// Another run holds the lock: release what we took and either wait or refuse
+		clusterLock.release(config)
+		if lockWait <= 0 || time.Now().After(deadline) {
+			return nil, fmt.Errorf("another recovery is in progress by %s (use --lock-wait to wait, or --break-lock if it is stale)",
+				holder.describe())
+		}
+		logMinimal("⏳ Waiting for the recovery lock to be released...")
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// release removes this run's lock from every node it was placed on
+func (c *ClusterLock) release(config *Config) {
+	for _, ip := range c.LockedIPs {
+		if err := removeNodeLock(ip, c.Lock.Token, config); err != nil {
+			logMinimal("⚠️  Warning: Could not release recovery lock on %s: %v", ip, err)
+		}
+	}
+	c.LockedIPs = nil
+	if heldClusterLock == c {
+		heldClusterLock = nil
+	}
+}
+
+// refresh moves the expiry of the lock one TTL ahead on every node it was placed on, leaving
+// alone the nodes where the lock is no longer ours
+func (c *ClusterLock) refresh(config *Config) {
+	c.Lock.ExpiresAt = time.Now().UTC().Add(getRecoveryLockTTL(config))
+	data, err := json.Marshal(c.Lock)
+	if err != nil {
+		logMinimal("⚠️  Warning: Could not renew recovery lock: %v", err)
+		return
+	}
+	for _, ip := range c.LockedIPs {
+		cmd := fmt.Sprintf("if grep -q %s %s 2>/dev/null; then printf '%%s\\n' %s > %s.tmp && mv -f %s.tmp %s; fi",
+			shellQuote(c.Lock.Token), recoveryLockPath, shellQuote(string(data)), recoveryLockPath, recoveryLockPath, recoveryLockPath)
+		if _, err := executeCommandOnNode(ip, cmd, config); err != nil {
+			logMinimal("⚠️  Warning: Could not renew recovery lock on %s: %v", ip, err)
+		}
+	}
+	logVerbose("🔒 Recovery lock renewed until %s", c.Lock.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+// renewHeldClusterLock renews the lock held by this run once half of its TTL has passed. The TTL
+// then only runs out for a run that stopped, not for a recovery that takes longer than the TTL.
+func renewHeldClusterLock(config *Config) {
+	if heldClusterLock == nil || time.Until(heldClusterLock.Lock.ExpiresAt) > getRecoveryLockTTL(config)/2 {
+		return
+	}
+	heldClusterLock.refresh(config)
+}
+
+// breakClusterLock forcibly removes recovery locks from all cluster nodes after confirmation
+func breakClusterLock(clusterIPs []string, config *Config) error {
+	found := false
+	for _, ip := range clusterIPs {
+		lock, err := readNodeLock(ip, config)
+		if err != nil {
+			logMinimal("⚠️  Node %s: %v", ip, err)
+			continue
+		}
+		if lock == nil {
+			logMinimal("🔓 Node %s: no recovery lock", ip)
+			continue
+		}
+		found = true
+		expired := ""
+		if lock.isExpired() {
+			expired = " [EXPIRED]"
+		}
+		logMinimal("🔒 Node %s: locked by %s%s", ip, lock.describe(), expired)
+	}
+
+	if !found {
+		logMinimal("✅ No recovery locks found")
+		return nil
+	}
+
+	logMinimal("")
+	logMinimal("⚠️  Breaking the lock while another recovery is running can bootstrap two nodes and cause split-brain.")
+	if !askUserPermission("break the recovery lock on all nodes") {
+		return fmt.Errorf("user declined to break the lock")
+	}
+
+	evidence := map[string]string{"reason": "break-lock"}
+	for _, ip := range clusterIPs {
+		cmd := fmt.Sprintf("rm -f %s", recoveryLockPath)
+		if _, err := executeAuditedCommandOnNode(ip, "break-lock", cmd, evidence, config); err != nil {
+			logMinimal("❌ Node %s: could not remove lock: %v", ip, err)
+		} else {
+			logMinimal("🔓 Node %s: lock removed", ip)
+		}
+	}
+	return nil
+}
+
+// recoverClusterWithLock analyzes the cluster state and performs recovery while holding the cluster lock
+func recoverClusterWithLock(clusterIPs []string, config *Config) error {
+	clusterLock, err := acquireClusterLock(clusterIPs, "recovery", config)
+	if err != nil {
+		return err
+	}
+	defer clusterLock.release(config)
+
+	state, err := analyzeClusterState(clusterIPs, config)
+	if err != nil {
+		return fmt.Errorf("failed to analyze cluster state: %v", err)
+	}
+
+	return performClusterRecovery(state, config)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecoveryLockRenewal(t *testing.T) {
+	nodes := []*fakeNode{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}, {IP: "10.0.0.3"}}
+	newFakeCluster(t, nodes...)
+	config := newTestConfig(t)
+	config.LockTTLSeconds = 600
+	ips := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+
+	clusterLock, err := acquireClusterLock(ips, "recovery", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { clusterLock.release(config) })
+	if heldClusterLock != clusterLock || len(clusterLock.LockedIPs) != 3 {
+		t.Fatalf("lock held on %v", clusterLock.LockedIPs)
+	}
+	if _, err := acquireClusterLock(ips, "recovery", config); err == nil {
+		t.Fatal("a second run took the lock")
+	}
+
+	// Early in the TTL nothing is rewritten
+	renewHeldClusterLock(config)
+	first, _ := readNodeLock("10.0.0.1", config)
+	if first == nil || !first.ExpiresAt.Equal(clusterLock.Lock.ExpiresAt) {
+		t.Fatalf("lock on 10.0.0.1 = %+v", first)
+	}
+
+	// The recovery has run for most of the TTL; another run has taken over the third node meanwhile
+	clusterLock.Lock.ExpiresAt = time.Now().Add(time.Minute)
+	nodes[2].Files[recoveryLockPath] = `{"token":"other"}` + "\n"
+	renewHeldClusterLock(config)
+	for _, ip := range ips[:2] {
+		lock, err := readNodeLock(ip, config)
+		if err != nil || lock == nil || lock.Token != clusterLock.Lock.Token || time.Until(lock.ExpiresAt) < 9*time.Minute {
+			t.Errorf("lock on %s after renewal = %+v, %v", ip, lock, err)
+		}
+	}
+	if lock, _ := readNodeLock("10.0.0.3", config); lock == nil || lock.Token != "other" {
+		t.Errorf("renewal overwrote another run's lock: %+v", lock)
+	}
+
+	clusterLock.release(config)
+	if heldClusterLock != nil || nodes[0].Files[recoveryLockPath] != "" {
+		t.Errorf("lock still held after release")
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log analysis defaults
+const (
+	defaultLogWindow = 6 * time.Hour
+	logMaxLines      = 20000
+)
+
+// logFilter keeps the lines that can hold a Galera event before they are copied over SSH
+const logFilter = ` | grep -aE 'WSREP|\[ERROR\]|Aborting|got signal|Assertion|ready for connections|Shutdown complete'`
+
+// Event severities
+const (
+	severityInfo     = "info"
+	severityWarning  = "warning"
+	severityCritical = "critical"
+)
+
+// LogEvent is a Galera event found in a node's error log
+type LogEvent struct {
+	Time     time.Time // Corrected for the node's clock offset when it was measured
+	Node     string
+	Kind     string
+	Severity string
+	Message  string
+	Line     string // Log line the event was found in
+}
+
+// Timeline is the cluster-wide sequence of Galera events
+type Timeline struct {
+	Since     time.Time
+	Events    []LogEvent
+	Nodes     []string          // Nodes whose log was read
+	Errors    map[string]string // Nodes whose log could not be read
+	Corrected bool              // Timestamps were corrected for measured clock offsets
+}
+
+// galeraLogEvent recognizes one kind of event; describe builds the message from the submatches
+type galeraLogEvent struct {
+	kind     string
+	severity string
+	pattern  *regexp.Regexp
+	describe func(matches []string) string
+}
+
+// galeraLogEvents are checked in order, the first match wins
+var galeraLogEvents = []galeraLogEvent{
+	{"inconsistency", severityCritical, regexp.MustCompile(`Inconsistency detected|Inconsistent by consensus|inconsistent with group`),
+		func(m []string) string { return "Inconsistency detected, the node leaves the cluster" }},
+	{"abort", severityCritical, regexp.MustCompile(`Aborting|got signal (\d+)|Assertion .* failed`),
+		func(m []string) string {
+			if m[1] != "" {
+				return "mysqld crashed (signal " + m[1] + ")"
+			}
+			return "mysqld aborted"
+		}},
+	{"sst-failed", severityCritical, regexp.MustCompile(`(?i)(SST|state transfer)[^.]*failed|SST script aborted|Failed to prepare for '?(\w+)'? SST`),
+		func(m []string) string { return "State transfer failed" }},
+	{"pc-lost", severityCritical, regexp.MustCompile(`(?i:non-primary)|no PC|PC not reachable`),
+		func(m []string) string { return "Primary component lost (non-Primary view)" }},
+	{"view", severityInfo, regexp.MustCompile(`New cluster view: .*view# ?(-?\d+): (\w+(?:-\w+)?), number of nodes: (\d+)`),
+		func(m []string) string { return fmt.Sprintf("View #%s: %s, %s nodes", m[1], m[2], m[3]) }},
+	{"sst-requested", severityWarning, regexp.MustCompile(`Member \S+ \(([^)]*)\) requested state transfer from '[^']*'\. Selected \S+ \(([^)]*)\)`),
+		func(m []string) string { return fmt.Sprintf("%s requested a state transfer, donor %s", m[1], m[2]) }},
+	{"ist", severityInfo, regexp.MustCompile(`Prepared IST receiver|async IST sender starting|IST received|Receiving IST`),
+		func(m []string) string { return "Incremental state transfer (IST)" }},
+	{"sst-complete", severityInfo, regexp.MustCompile(`(?i)SST complete|SST received|State transfer .* complete`),
+		func(m []string) string { return "State transfer complete" }},
+	{"synced", severityInfo, regexp.MustCompile(`Member \S+ \(([^)]*)\) synced with group`),
+		func(m []string) string { return m[1] + " synced with the group" }},
+	{"joined", severityInfo, regexp.MustCompile(`declaring \S+ at (tcp://\S+) stable`),
+		func(m []string) string { return "Node at " + strings.TrimPrefix(m[1], "tcp://") + " joined the view" }},
+	{"left", severityWarning, regexp.MustCompile(`forgetting \S+ \((tcp://[^)]+)\)`),
+		func(m []string) string { return "Node at " + strings.TrimPrefix(m[1], "tcp://") + " left the view" }},
+	{"suspected", severityWarning, regexp.MustCompile(`suspecting node: (\S+)`),
+		func(m []string) string { return "Node " + m[1] + " suspected (not responding)" }},
+	{"started", severityInfo, regexp.MustCompile(`ready for connections`),
+		func(m []string) string { return "mysqld ready for connections" }},
+	{"shutdown", severityInfo, regexp.MustCompile(`Shutdown complete`),
+		func(m []string) string { return "mysqld shut down" }},
+}
+
+var (
+	// 2024-03-01 10:00:00, 2024-03-01T10:00:00.123456Z, 2024-03-01T10:00:00+01:00
+	isoTimestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	// 240301 10:00:00 (MariaDB before 10.1.5)
+	shortTimestampPattern = regexp.MustCompile(`\b(\d{6})\s+(\d{1,2}:\d{2}:\d{2})\b`)
+	// Mar  1 10:00:00 (syslog and journalctl)
+	syslogTimestampPattern = regexp.MustCompile(`\b[A-Z][a-z]{2}\s+\d{1,2} \d{2}:\d{2}:\d{2}\b`)
+)
+
+// parseLogTimestamp returns the time of a log line. The server's own timestamp is preferred to
+// the journal's; times without a zone are in the node's time zone, syslog ones in the current year.
+func parseLogTimestamp(line string, now time.Time, location *time.Location) (time.Time, bool) {
+	if match := isoTimestampPattern.FindString(line); match != "" {
+		match = strings.Replace(match, " ", "T", 1)
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"} {
+			if t, err := time.Parse(layout, match); err == nil {
+				return t, true
+			}
+		}
+		if t, err := time.ParseInLocation("2006-01-02T15:04:05.999999999", match, location); err == nil {
+			return t, true
+		}
+	}
+	if matches := shortTimestampPattern.FindStringSubmatch(line); matches != nil {
+		if t, err := time.ParseInLocation("060102 15:04:05", matches[1]+" "+matches[2], location); err == nil {
+			return t, true
+		}
+	}
+	if match := syslogTimestampPattern.FindString(line); match != "" {
+		if t, err := time.ParseInLocation("Jan _2 15:04:05 2006", strings.Join(strings.Fields(match), " ")+" "+strconv.Itoa(now.In(location).Year()), location); err == nil {
+			// A December line read in January is from last year
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseGaleraEvents returns the events recognized in a node's log lines that happened after since,
+// reading the times without a zone in the node's time zone
+func parseGaleraEvents(nodeIP, output string, since, now time.Time, location *time.Location) []LogEvent {
+	var events []LogEvent
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for _, event := range galeraLogEvents {
+			matches := event.pattern.FindStringSubmatch(line)
+			if matches == nil {
+				continue
+			}
+			t, ok := parseLogTimestamp(line, now, location)
+			if !ok {
+				logDebug("No timestamp in log line of %s: %s", nodeIP, line)
+				break
+			}
+			if t.Before(since) {
+				break
+			}
+			events = append(events, LogEvent{Time: t, Node: nodeIP, Kind: event.kind, Severity: event.severity,
+				Message: event.describe(matches), Line: line})
+			break
+		}
+	}
+	return events
+}
+
+// logSinceArgument turns a window into the "N min ago" form of the service log commands
+func logSinceArgument(window time.Duration) string {
+	minutes := int(window.Minutes())
+	if minutes < 1 {
+		minutes = 1
+	}
+	return fmt.Sprintf("%d min ago", minutes)
+}
+
+// nodeLocation returns the time zone of a node from its current UTC offset, or the local time zone
+// if it can't be read. A fixed offset misplaces the events logged before a daylight saving change.
+func nodeLocation(executor NodeExecutor, nodeIP string) *time.Location {
+	output, err := runCommand(executor, "date +%z")
+	if err == nil {
+		offset := strings.TrimSpace(output)
+		if t, err := time.Parse("-0700", offset); err == nil {
+			_, seconds := t.Zone()
+			return time.FixedZone(offset, seconds)
+		}
+	}
+	logVerbose("   ⚠️  Could not read the time zone of %s, assuming the local one", nodeIP)
+	return time.Local
+}
+
+// collectNodeLogEvents reads the error log or journal of a node and returns its Galera events,
+// with the node's clock status used to correct their times
+func collectNodeLogEvents(ip string, since, now time.Time, config *Config) ([]LogEvent, *ClockStatus, error) {
+	executor, err := getNodeExecutor(ip, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to establish SSH connection to %s: %v", ip, err)
+	}
+	service := resolveServiceBackend(ip, config, nil)
+	output, err := runCommand(serviceExecutor(executor, service), service.logCommand(logSinceArgument(now.Sub(since)), logMaxLines)+logFilter)
+	// grep exits with 1 when nothing matched
+	if err != nil && getExitStatus(err) != 1 {
+		return nil, nil, fmt.Errorf("failed to read the service log: %v", err)
+	}
+	events := parseGaleraEvents(ip, output, since, now, nodeLocation(executor, ip))
+	return events, checkNodeClock(executor, ip), nil
+}
+
+// buildTimeline collects the events of every node and merges them into one timeline sorted by time
+func buildTimeline(ips []string, window time.Duration, config *Config) *Timeline {
+	now := time.Now()
+	timeline := &Timeline{Since: now.Add(-window), Errors: make(map[string]string)}
+	for _, ip := range ips {
+		logNormal("📜 Reading the log of %s...", ip)
+		events, clock, err := collectNodeLogEvents(ip, timeline.Since, now, config)
+		if err != nil {
+			logNormal("   ❌ %v", err)
+			timeline.Errors[ip] = err.Error()
+			continue
+		}
+		timeline.Nodes = append(timeline.Nodes, ip)
+		if clock != nil && clock.Measured {
+			// A node whose clock is ahead logs times that are too late
+			for i := range events {
+				events[i].Time = events[i].Time.Add(-clock.Offset)
+			}
+			timeline.Corrected = true
+		}
+		logVerbose("   %d events on %s", len(events), ip)
+		timeline.Events = append(timeline.Events, events...)
+	}
+	sort.SliceStable(timeline.Events, func(i, j int) bool { return timeline.Events[i].Time.Before(timeline.Events[j].Time) })
+	return timeline
+}
+
+// severityIcon returns the icon shown for an event severity
+func severityIcon(severity string) string {
+	switch severity {
+	case severityCritical:
+		return "❌"
+	case severityWarning:
+		return "⚠️ "
+	}
+	return "ℹ️ "
+}
+
+// displayTimeline prints the cluster-wide timeline
+func displayTimeline(timeline *Timeline) {
+	fmt.Println()
+	fmt.Printf("🕒 Cluster timeline since %s: %d events from %d nodes\n",
+		timeline.Since.Format("2006-01-02 15:04:05"), len(timeline.Events), len(timeline.Nodes))
+	if timeline.Corrected {
+		fmt.Println("   (times corrected for the measured clock offset of each node)")
+	}
+	if len(timeline.Events) == 0 {
+		fmt.Println("   No Galera events found")
+	}
+	width := 0
+	for _, event := range timeline.Events {
+		if len(event.Node) > width {
+			width = len(event.Node)
+		}
+	}
+	for _, event := range timeline.Events {
+		fmt.Printf("   %s  %-*s  %s %s\n", event.Time.Local().Format("2006-01-02 15:04:05.000"), width, event.Node, severityIcon(event.Severity), event.Message)
+		if currentVerbosity >= VerbosityNormal {
+			fmt.Printf("   %s  %-*s     %s\n", strings.Repeat(" ", 23), width, "", event.Line)
+		}
+	}
+
+	critical := 0
+	for _, event := range timeline.Events {
+		if event.Severity == severityCritical {
+			critical++
+		}
+	}
+	if critical > 0 {
+		fmt.Println()
+		fmt.Printf("❌ %d critical events (crashes, inconsistencies, failed transfers, lost Primary component)\n", critical)
+	}
+	for _, ip := range sortedKeys(timeline.Errors) {
+		fmt.Printf("❌ Log of %s not read: %s\n", ip, timeline.Errors[ip])
+	}
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseLogWindow parses the --since value: a Go duration ("90m", "6h") or a number of days ("2d")
+func parseLogWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("invalid value for --since: %s", value)
+	}
+	return window, nil
+}
+
+// runLogsCommand implements the "logs" subcommand
+func runLogsCommand(args []string) error {
+	window := defaultLogWindow
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--since" || i+1 >= len(args) {
+			return fmt.Errorf("unknown logs option: %s", args[i])
+		}
+		var err error
+		if window, err = parseLogWindow(args[i+1]); err != nil {
+			return err
+		}
+		i++
+	}
+
+	logMinimal("=== GaleraHealth - Log Timeline ===")
+	config := loadClusterConfig()
+	ips, err := discoverClusterNodes(config)
+	if err != nil {
+		return err
+	}
+
+	timeline := buildTimeline(ips, window, config)
+	displayTimeline(timeline)
+	if err := saveConfig(config); err != nil {
+		logNormal("Warning: Could not save configuration: %v", err)
+	}
+	if len(timeline.Nodes) == 0 {
+		return fmt.Errorf("no node log could be read")
+	}
+	return nil
+}
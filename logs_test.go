@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogTimestamp(t *testing.T) {
+	// Times without a zone are read in the node's time zone
+	node := time.FixedZone("+0530", 5*3600+1800)
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, node)
+	tests := []struct {
+		line string
+		want time.Time
+	}{
+		{"2024-01-02 10:00:01 0 [Note] WSREP: Shifting SYNCED -> DONOR/DESYNCED", time.Date(2024, 1, 2, 10, 0, 1, 0, node)},
+		{"2024-01-02T09:00:00.500000Z 0 [System] [MY-010931] [Server] ready for connections", time.Date(2024, 1, 2, 9, 0, 0, 500000000, time.UTC)},
+		{"Jan  2 10:00:00 db1 mariadbd[812]: 2024-01-02 10:00:03 2 [Note] WSREP: view", time.Date(2024, 1, 2, 10, 0, 3, 0, node)},
+		{"240102 10:00:04 [Note] WSREP: New cluster view", time.Date(2024, 1, 2, 10, 0, 4, 0, node)},
+		{"Dec 31 23:59:00 db1 mysqld: WSREP: forgetting", time.Date(2023, 12, 31, 23, 59, 0, 0, node)},
+	}
+	for _, tt := range tests {
+		if got, ok := parseLogTimestamp(tt.line, now, node); !ok || !got.Equal(tt.want) {
+			t.Errorf("parseLogTimestamp(%q) = %s, %t, want %s", tt.line, got, ok, tt.want)
+		}
+	}
+	if _, ok := parseLogTimestamp("WSREP: no time here", now, node); ok {
+		t.Error("parseLogTimestamp found a time in a line without one")
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	// The operator and the two nodes are in three different time zones
+	previousLocal := time.Local
+	time.Local = time.FixedZone("-0500", -5*3600)
+	t.Cleanup(func() { time.Local = previousLocal })
+	eastern := time.FixedZone("+0200", 2*3600)
+
+	now := time.Now()
+	at := func(zone *time.Location, ago time.Duration, offset time.Duration, text string) string {
+		return now.Add(-ago).Add(offset).In(zone).Format("2006-01-02 15:04:05") + " 0 [Note] " + text + "\n"
+	}
+	// The clock of 10.0.0.2 is 30s ahead: its events are moved back
+	first := &fakeNode{IP: "10.0.0.1", Service: "active", Log: at(time.UTC, 10*time.Hour, 0, "WSREP: forgetting 1a2b (tcp://10.0.0.3:4567)") +
+		at(time.UTC, 3*time.Minute, 0, "WSREP: New cluster view: global state: 6f2c:1234, view# 7: non-Primary, number of nodes: 1, my index: 0, protocol version 3") +
+		at(time.UTC, time.Minute, 0, "WSREP: Member 1.0 (node2) requested state transfer from '*any*'. Selected 0.0 (node1)(SYNCED) as donor.")}
+	second := &fakeNode{IP: "10.0.0.2", Service: "active", ClockOffset: 30 * time.Second, Zone: eastern,
+		Log: at(eastern, 2*time.Minute, 30*time.Second, "[ERROR] WSREP: Inconsistency detected: Inconsistent by consensus on 6f2c:1200") +
+			at(eastern, 2*time.Minute-time.Second, 30*time.Second, "WSREP: mysqld got signal 6 ;")}
+	newFakeCluster(t, first, second, &fakeNode{IP: "10.0.0.3", Unreachable: true})
+	config := newTestConfig(t)
+
+	timeline := buildTimeline([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, 6*time.Hour, config)
+	var got []string
+	for _, event := range timeline.Events {
+		got = append(got, event.Node+" "+event.Kind+" "+event.Message)
+	}
+	want := []string{
+		"10.0.0.1 pc-lost Primary component lost (non-Primary view)",
+		"10.0.0.2 inconsistency Inconsistency detected, the node leaves the cluster",
+		"10.0.0.2 abort mysqld crashed (signal 6)",
+		"10.0.0.1 sst-requested node2 requested a state transfer, donor node1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("timeline =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !timeline.Corrected || len(timeline.Nodes) != 2 || timeline.Errors["10.0.0.3"] == "" {
+		t.Errorf("timeline corrected %t, nodes %v, errors %v", timeline.Corrected, timeline.Nodes, timeline.Errors)
+	}
+}
@@ -56,35 +56,42 @@ func logReport(format string, args ...interface{}) {
 
 func main() {
 	// Parse command line arguments for verbosity and other options
-	var args []string
-	verbosityCount := 0
-
-	for i := 1; i < len(os.Args); i++ {
-		arg := os.Args[i]
-		switch {
-		case arg == "-v":
-			verbosityCount = 1
-		case arg == "-vv":
-			verbosityCount = 2
-		case arg == "-vvv":
-			verbosityCount = 3
-		case strings.HasPrefix(arg, "-v"):
-			// Count consecutive 'v's for -vvv style
-			verbosityCount = len(arg) - 1
-		case arg == "-y", arg == "--yes":
-			useDefaults = true
-		case arg == "-s", arg == "--summary":
-			reportMode = true
-		case arg == "-r", arg == "--recovery":
-			runMode = true
-		default:
-			args = append(args, arg)
-		}
+	args, verbosityCount, err := parseCommandLine(os.Args[1:])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Use --help for available options")
+		os.Exit(1)
+	}
+
+	command := commandCheck
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case commandStatus:
+		useDefaults, reportMode = true, true
+	case commandRecover:
+		runMode = true
+	}
+
+	// Flags take precedence over the environment and the configuration file
+	if clusterName == "" {
+		clusterName = os.Getenv(clusterEnv)
+	}
+	output := loadOutputSettings()
+	if verbosityCount < 0 {
+		verbosityCount = output.Verbosity
+	}
+	if output.Summary && useDefaults {
+		reportMode = true
 	}
 
 	// Set verbosity level
 	currentVerbosity = VerbosityLevel(verbosityCount)
 
+	// Connections to nodes are shared by every step of the run and closed on exit
+	defer closeNodeExecutors()
+
 	// Validate report mode requirements
 	if reportMode && !useDefaults {
 		fmt.Println("Error: -s (summary mode) can only be used with -y (automated mode)")
@@ -94,58 +101,131 @@ func main() {
 
 	logDebug("Verbosity level set to: %d", currentVerbosity)
 
-	// Check for other command line arguments
-	if len(args) > 0 {
-		switch args[0] {
-		case "--clear-config", "-c":
-			logMinimal("🗑️  Clearing saved configuration...")
-			if err := clearConfig(); err != nil {
-				log.Fatalf("Error clearing configuration: %v", err)
-			}
-			logMinimal("✓ Configuration file removed: %s", getConfigPath())
-			return
-		case "--help", "-h":
-			fmt.Println("GaleraHealth - Galera Cluster Monitor")
-			fmt.Println()
-			fmt.Println("Usage:")
-			fmt.Println("  galerahealth                      Run the cluster monitor")
-			fmt.Println("  galerahealth -y                   Run using saved defaults without prompts")
-			fmt.Println("  galerahealth -y -s                Run automated with summary only")
-			fmt.Println("  galerahealth -r                   Monitor and attempt cluster recovery if needed")
-			fmt.Println("  galerahealth -v                   Run with normal verbosity")
-			fmt.Println("  galerahealth -vv                  Run with verbose output")
-			fmt.Println("  galerahealth -vvv                 Run with debug output")
-			fmt.Println("  galerahealth --clear-config       Clear saved configuration")
-			fmt.Println("  galerahealth --help               Show this help")
-			fmt.Println()
-			fmt.Printf("Configuration file: %s\n", getConfigPath())
-			fmt.Println()
-			fmt.Println("Options:")
-			fmt.Println("  -y, --yes     - Use saved defaults without prompting")
-			fmt.Println("  -s, --summary - Show only final summary (requires -y)")
-			fmt.Println("  -r, --recovery - Attempt cluster recovery if nodes are down")
-			fmt.Println()
-			fmt.Println("Verbosity levels:")
-			fmt.Println("  (none) - Minimal output (default)")
-			fmt.Println("  -v     - Normal operations + warnings")
-			fmt.Println("  -vv    - Detailed operations + debug info")
-			fmt.Println("  -vvv   - Full debug output + raw data")
-			return
-		default:
-			fmt.Printf("Unknown option: %s\n", args[0])
+	if breakLock {
+		logMinimal("=== GaleraHealth - Break Recovery Lock ===")
+		config := loadClusterConfig()
+		clusterIPs, err := discoverClusterNodes(config)
+		if err != nil {
+			log.Fatalf("Error discovering cluster nodes: %v", err)
+		}
+		if err := breakClusterLock(clusterIPs, config); err != nil {
+			logMinimal("❌ %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	switch command {
+	case commandCheck, commandStatus, commandRecover:
+		if len(args) > 0 {
+			fmt.Printf("Unknown %s option: %s\n", command, args[0])
 			fmt.Println("Use --help for available options")
-			return
+			os.Exit(1)
+		}
+		runCheck()
+	case "--clear-config", "-c":
+		logMinimal("🗑️  Clearing saved configuration...")
+		if err := clearConfig(); err != nil {
+			log.Fatalf("Error clearing configuration: %v", err)
+		}
+		logMinimal("✓ Configuration file removed: %s", getConfigPath())
+	case "secrets":
+		if err := runSecretsCommand(args); err != nil {
+			logMinimal("❌ %v", err)
+			os.Exit(1)
+		}
+	case "config":
+		if err := runConfigCommand(args); err != nil {
+			logMinimal("❌ %v", err)
+			os.Exit(1)
+		}
+	case "cluster":
+		if err := runClusterCommand(args); err != nil {
+			logMinimal("❌ %v", err)
+			os.Exit(1)
+		}
+	case "creds":
+		if err := runCredsCommand(args); err != nil {
+			logMinimal("❌ %v", err)
+			os.Exit(1)
+		}
+	case "serve":
+		if err := runServeCommand(args); err != nil {
+			logMinimal("❌ %v", err)
+			os.Exit(1)
+		}
+	case "network":
+		if err := runNetworkCommand(args); err != nil {
+			logMinimal("❌ %v", err)
+			os.Exit(1)
+		}
+	case "logs":
+		if err := runLogsCommand(args); err != nil {
+			logMinimal("❌ %v", err)
+			os.Exit(1)
+		}
+	case "bundle":
+		if err := runBundleCommand(args); err != nil {
+			logMinimal("❌ %v", err)
+			os.Exit(1)
+		}
+	case "rolling-restart":
+		var order []string
+		for i := 0; i < len(args); i++ {
+			if args[i] == "--order" && i+1 < len(args) {
+				i++
+				for _, ip := range strings.Split(args[i], ",") {
+					if ip = strings.TrimSpace(ip); ip != "" {
+						order = append(order, ip)
+					}
+				}
+			} else {
+				fmt.Printf("Unknown rolling-restart option: %s\n", args[i])
+				os.Exit(1)
+			}
 		}
+
+		logMinimal("=== GaleraHealth - Rolling Restart ===")
+		config := loadClusterConfig()
+		if err := runRollingRestart(config, order); err != nil {
+			logMinimal("❌ Rolling restart failed: %v", err)
+			os.Exit(1)
+		}
+		if err := saveConfig(config); err != nil {
+			logNormal("Warning: Could not save configuration: %v", err)
+		}
+	case "--help", "-h", "help":
+		printUsage()
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		fmt.Println("Use --help for available options")
+		os.Exit(1)
 	}
+}
 
+// runCheck runs the cluster health check, prompting for anything not given on the command line or saved
+func runCheck() {
 	logMinimal("=== GaleraHealth - Galera Cluster Monitor ===")
 	if useDefaults {
 		logMinimal("🚀 Running in automatic mode (-y) - using saved defaults")
 	}
 	logDebug("Application started with verbosity level %d", currentVerbosity)
 
+	if clusterName == fleetClusterName {
+		config := loadConfig()
+		if len(config.Clusters) == 0 {
+			logMinimal("❌ No cluster profiles configured (add one with 'galerahealth cluster add <name>')")
+			os.Exit(1)
+		}
+		displayFleetSummary(runFleetCheck(config))
+		if err := saveConfig(config); err != nil {
+			logNormal("Warning: Could not save configuration: %v", err)
+		}
+		return
+	}
+
 	// Load saved configuration
-	config := loadConfig()
+	config := loadClusterConfig()
 	if config.LastNodeIP != "" {
 		logNormal("💾 Loaded saved configuration from %s", getConfigPath())
 		logVerbose("   Last used: Node IP: %s, SSH User: %s, MySQL User: %s",
@@ -157,7 +237,7 @@ func main() {
 	}
 
 	// Ask for node IP with default
-	nodeIP := promptForInputWithDefault("Enter the Galera cluster node IP", config.LastNodeIP)
+	nodeIP := promptUnlessGiven(cliOptions.Node, "Enter the Galera cluster node IP", config.LastNodeIP)
 	if nodeIP == "" {
 		if useDefaults {
 			log.Fatal("Node IP is required but no saved configuration found. Run without -y to configure.")
@@ -168,7 +248,7 @@ func main() {
 
 	// Ask for SSH username with default (only if not localhost)
 	var username string
-	var sshClient *SSHClient
+	var executor NodeExecutor
 	var connInfo *SSHConnectionInfo
 	var err error
 
@@ -189,33 +269,30 @@ func main() {
 		if defaultUsername == "" {
 			defaultUsername = "root"
 		}
-		username = promptForInputWithDefault("Enter SSH username", defaultUsername)
+		username = promptUnlessGiven(cliOptions.SSHUser, "Enter SSH username", defaultUsername)
 		if username == "" {
 			username = "root" // fallback default
 		}
+		config.LastSSHUsername = username // Used for nodes without saved credentials
 
 		logVerbose("Attempting SSH connection to %s@%s", username, nodeIP)
 		// Try SSH connection using per-node credentials
-		sshClient, connInfo, err = createSSHConnectionWithNodeCredentials(nodeIP, config)
+		executor, connInfo, err = openNodeExecutor(nodeIP, config)
 		if err != nil {
 			log.Fatal("Error connecting via SSH:", err)
 		}
-		defer sshClient.Close()
 
 		// Save the connection info for this node
 		if connInfo != nil {
-			sshPassword := ""
-			if connInfo.HasPassword {
-				sshPassword = connInfo.Password
-			}
-			err = config.setNodeCredentials(nodeIP, connInfo.Username, "", sshPassword, "", connInfo.UsedKeys)
+			savedPassword, err := config.saveSSHConnection(nodeIP, connInfo)
 			if err != nil {
 				logVerbose("Warning: Could not save node credentials: %v", err)
 			} else {
-				if connInfo.HasPassword {
+				if savedPassword {
 					logVerbose("✓ SSH password saved for node %s", nodeIP)
 				}
 				logVerbose("✓ SSH credentials saved for node %s", nodeIP)
+				config.markCredentialsUsed(nodeIP, credentialSSH)
 			}
 		}
 
@@ -230,18 +307,13 @@ func main() {
 		logMinimal("🔍 Analyzing local Galera configuration...")
 		initialClusterInfo, err = getGaleraClusterInfoLocal(nodeIP)
 	} else {
-		initialClusterInfo, err = getGaleraClusterInfo(sshClient, nodeIP)
+		initialClusterInfo, err = getGaleraClusterInfo(executor, nodeIP)
 	}
 
 	if err != nil {
 		log.Fatal("Error obtaining cluster information:", err)
 	}
 
-	// Close SSH connection if it was used
-	if sshClient != nil {
-		sshClient.Close()
-	}
-
 	// Display initial node information (skip in report mode)
 	if !reportMode {
 		displayClusterInfo(initialClusterInfo)
@@ -252,7 +324,7 @@ func main() {
 	defaultCoherence := config.LastCheckCoherence
 
 	// Smart default detection for cluster coherence
-	isMultiNode := len(initialClusterInfo.ClusterAddress) > 0 && strings.Contains(initialClusterInfo.ClusterAddress, ",")
+	isMultiNode := len(parseClusterNodes(initialClusterInfo.ClusterAddress)) > 1
 
 	if isMultiNode {
 		// For multi-node clusters, default to checking coherence
@@ -269,7 +341,7 @@ func main() {
 		}
 	}
 
-	checkCoherence := promptForBoolWithDefault("Do you want to check cluster configuration coherence across all nodes?", defaultCoherence)
+	checkCoherence := promptBoolUnlessGiven(cliOptions.CheckCoherence, "Do you want to check cluster configuration coherence across all nodes?", defaultCoherence)
 
 	// Update config with current values
 	config.LastNodeIP = nodeIP
@@ -293,7 +365,7 @@ func main() {
 
 		// Ask if user wants to check MySQL/MariaDB status with default
 		logMinimal("")
-		checkMySQL := promptForBoolWithDefault("Do you want to check MySQL/MariaDB cluster status on all nodes?", config.LastCheckMySQL)
+		checkMySQL := promptBoolUnlessGiven(cliOptions.CheckMySQL, "Do you want to check MySQL/MariaDB cluster status on all nodes?", config.LastCheckMySQL)
 		config.LastCheckMySQL = checkMySQL
 
 		logDebug("CheckMySQL set to: %t", checkMySQL)
@@ -302,7 +374,9 @@ func main() {
 			logVerbose("Gathering MySQL credentials")
 			// Get MySQL credentials with default
 			mysqlCreds := getMySQLCredentialsWithDefault(config.LastMySQLUsername, config, nodeIP)
-			config.LastMySQLUsername = mysqlCreds.Username
+			if mysqlCreds.Username != "" {
+				config.LastMySQLUsername = mysqlCreds.Username
+			}
 
 			logMinimal("")
 			logMinimal("🔍 Checking MySQL/MariaDB status on all nodes...")
@@ -389,23 +463,47 @@ func getMySQLCredentialsWithDefault(defaultUsername string, config *Config, node
 		fmt.Println("Enter MySQL/MariaDB credentials:")
 	}
 
+	// The mysql client on the nodes can read the credentials itself
+	if config != nil && cliOptions.MySQLUser == "" && cliOptions.MySQLPasswordFile == "" {
+		if config.MySQLLoginPath != "" {
+			logVerbose("✓ Using MySQL login path %s on the nodes", config.MySQLLoginPath)
+			return &MySQLConnectionInfo{LoginPath: config.MySQLLoginPath, Source: "cluster"}
+		}
+		if config.MySQLOptionFile {
+			logVerbose("✓ Using ~/.my.cnf on the nodes for MySQL")
+			return &MySQLConnectionInfo{OptionFile: true, Source: "cluster"}
+		}
+	}
+
 	if defaultUsername == "" {
 		defaultUsername = "root"
 	}
-	username := promptForInputWithDefault("MySQL username", defaultUsername)
+	username := promptUnlessGiven(cliOptions.MySQLUser, "MySQL username", defaultUsername)
 	if username == "" {
 		username = "root"
 	}
 
 	var password string
 
+	// A password referenced from the configuration file is used as is
+	if config != nil && config.clusterMySQLPasswordRef() != "" {
+		ref := config.clusterMySQLPasswordRef()
+		resolved, err := resolveSecretRef(ref)
+		if err != nil {
+			logNormal("Warning: %v", err)
+		} else {
+			logVerbose("✓ Using MySQL password from %s", ref)
+			return &MySQLConnectionInfo{Username: username, Password: resolved, Source: "cluster"}
+		}
+	}
+
 	// Check if we have a saved encrypted password
 	if config != nil && config.HasSavedPassword && nodeIP != "" {
 		logVerbose("Found saved encrypted password")
 		useStored := promptForBoolWithDefault("Use stored password?", true)
 		if useStored {
 			logDebug("Attempting to decrypt stored password")
-			decryptedPassword, err := decryptPassword(config.EncryptedMySQLPassword, nodeIP)
+			decryptedPassword, err := config.decryptSecret(config.EncryptedMySQLPassword, nodeIP)
 			if err != nil {
 				logNormal("Warning: Could not decrypt stored password: %v", err)
 				logMinimal("Please enter password manually:")
@@ -421,8 +519,8 @@ func getMySQLCredentialsWithDefault(defaultUsername string, config *Config, node
 	if password == "" {
 		// If -y flag is used but no saved password, we can't proceed with MySQL check
 		if useDefaults {
-			logMinimal("⚠️  No saved MySQL password found with -y flag. Skipping MySQL operations.")
-			return &MySQLConnectionInfo{Username: username, Password: ""}
+			logNormal("⚠️  No saved MySQL password found with -y flag, only per-node credentials and ~/.my.cnf will be tried")
+			return &MySQLConnectionInfo{Username: username, Password: "", Source: "cluster"}
 		}
 
 		fmt.Print("MySQL password: ")
@@ -430,7 +528,7 @@ func getMySQLCredentialsWithDefault(defaultUsername string, config *Config, node
 		fmt.Println()
 		if err != nil {
 			log.Printf("Error reading password: %v", err)
-			return &MySQLConnectionInfo{Username: username, Password: ""}
+			return &MySQLConnectionInfo{Username: username, Password: "", Source: "cluster"}
 		}
 		password = string(passwordBytes)
 
@@ -439,7 +537,7 @@ func getMySQLCredentialsWithDefault(defaultUsername string, config *Config, node
 			savePassword := promptForBoolWithDefault("Save this password for next time? (encrypted)", true)
 			if savePassword {
 				logDebug("Attempting to encrypt password for storage")
-				encryptedPassword, err := encryptPassword(password, nodeIP)
+				encryptedPassword, err := config.encryptSecret(password, nodeIP)
 				if err != nil {
 					logNormal("Warning: Could not encrypt password: %v", err)
 				} else {
@@ -455,6 +553,7 @@ func getMySQLCredentialsWithDefault(defaultUsername string, config *Config, node
 	return &MySQLConnectionInfo{
 		Username: username,
 		Password: password,
+		Source:   "cluster",
 	}
 }
 
@@ -474,7 +573,7 @@ func attemptClusterRecoveryAfterMySQLCheck(analysis *ClusterAnalysis, config *Co
 		// Quick check on primary node to avoid unnecessary SSH connections
 		primaryNode := analysis.InitialNode.NodeIP
 		if primaryNode == "localhost" || primaryNode == "127.0.0.1" {
-			cmd := "systemctl is-active mariadb mysqld mysql 2>/dev/null | head -1"
+			cmd := getNodeServiceBackend(primaryNode, config).statusCommand()
 			output, err := executeLocalCommand(cmd)
 			if err == nil && strings.TrimSpace(output) == "active" {
 				logMinimal("✅ Primary node MySQL/MariaDB is running and cluster appears healthy - no recovery needed")
@@ -485,12 +584,7 @@ func attemptClusterRecoveryAfterMySQLCheck(analysis *ClusterAnalysis, config *Co
 
 	// If we get here, either cluster has issues or we need to do full recovery analysis
 	logVerbose("Proceeding with detailed cluster state analysis for recovery...")
-	state, err := analyzeClusterState(analysis.ClusterNodes, config)
-	if err != nil {
-		return fmt.Errorf("failed to analyze cluster state: %v", err)
-	}
-
-	return performClusterRecovery(state, config)
+	return recoverClusterWithLock(analysis.ClusterNodes, config)
 }
 
 // attemptClusterRecoveryWithAnalysis attempts to recover the cluster using existing analysis
@@ -504,7 +598,7 @@ func attemptClusterRecoveryWithAnalysis(analysis *ClusterAnalysis, config *Confi
 		if len(analysis.ClusterNodes) == 1 {
 			nodeIP := analysis.ClusterNodes[0]
 			if nodeIP == "localhost" || nodeIP == "127.0.0.1" {
-				cmd := "systemctl is-active mariadb mysqld mysql 2>/dev/null | head -1"
+				cmd := getNodeServiceBackend(nodeIP, config).statusCommand()
 				output, err := executeLocalCommand(cmd)
 				if err == nil && strings.TrimSpace(output) == "active" {
 					logMinimal("✅ Local MySQL/MariaDB is running - no recovery needed")
@@ -516,13 +610,7 @@ func attemptClusterRecoveryWithAnalysis(analysis *ClusterAnalysis, config *Confi
 
 	// Proceed with detailed recovery analysis
 	logVerbose("Proceeding with detailed cluster state analysis for recovery...")
-	state, err := analyzeClusterState(analysis.ClusterNodes, config)
-	if err != nil {
-		return fmt.Errorf("failed to analyze cluster state: %v", err)
-	}
-
-	// Attempt recovery based on cluster state
-	return performClusterRecovery(state, config)
+	return recoverClusterWithLock(analysis.ClusterNodes, config)
 }
 
 // attemptClusterRecovery attempts to recover the cluster if needed
@@ -533,7 +621,7 @@ func attemptClusterRecovery(clusterIPs []string, config *Config) error {
 	for _, ip := range clusterIPs {
 		var cmd string
 		if ip == "localhost" || ip == "127.0.0.1" {
-			cmd = "systemctl is-active mariadb mysqld mysql 2>/dev/null | head -1"
+			cmd = getNodeServiceBackend(ip, config).statusCommand()
 		} else {
 			// For remote nodes, we'll need to do a more detailed analysis
 			// But first let's see if we can avoid SSH by checking if localhost is part of cluster
@@ -556,12 +644,6 @@ func attemptClusterRecovery(clusterIPs []string, config *Config) error {
 		return nil
 	}
 
-	// Need detailed analysis - analyze current cluster state
-	state, err := analyzeClusterState(clusterIPs, config)
-	if err != nil {
-		return fmt.Errorf("failed to analyze cluster state: %v", err)
-	}
-
-	// Attempt recovery based on cluster state
-	return performClusterRecovery(state, config)
+	// Need detailed analysis - analyze current cluster state and recover under the cluster lock
+	return recoverClusterWithLock(clusterIPs, config)
 }
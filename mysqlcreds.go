@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mysqlSourceFallback marks the ~/.my.cnf login tried on every node when nothing else works
+const mysqlSourceFallback = "fallback"
+
+// workingMySQLCredentials caches, per node, the MySQL credentials that worked during this run
+var workingMySQLCredentials = make(map[string]*MySQLConnectionInfo)
+
+// describe returns a short description of the credentials for messages
+func (m *MySQLConnectionInfo) describe() string {
+	var how string
+	switch {
+	case m.LoginPath != "":
+		how = "login-path " + m.LoginPath
+	case m.OptionFile:
+		how = "~/.my.cnf"
+	case m.Password != "":
+		how = m.Username + " with password"
+	default:
+		how = m.Username + " without password"
+	}
+	if m.Source != "" {
+		return fmt.Sprintf("%s (%s)", how, m.Source)
+	}
+	return how
+}
+
+// clusterMySQLCredentials returns the cluster-wide MySQL credentials saved in the configuration
+func clusterMySQLCredentials(config *Config) *MySQLConnectionInfo {
+	creds := &MySQLConnectionInfo{Username: config.LastMySQLUsername, Source: "cluster"}
+	if creds.Username == "" {
+		creds.Username = "root"
+	}
+
+	if ref := config.clusterMySQLPasswordRef(); ref != "" {
+		if password, err := resolveSecretRef(ref); err == nil {
+			creds.Password = password
+		} else {
+			logVerbose("Warning: %v", err)
+		}
+	} else if config.HasSavedPassword && config.LastNodeIP != "" {
+		if password, err := config.decryptSecret(config.EncryptedMySQLPassword, config.LastNodeIP); err == nil {
+			creds.Password = password
+		} else {
+			logVerbose("Warning: Could not decrypt stored MySQL password: %v", err)
+		}
+	}
+	return creds
+}
+
+// mysqlCredentialCandidates returns the MySQL credentials to try on a node, in order: the node's own
+// login path, option file and password, then the cluster default (clusterDefault, or the saved one if
+// nil), and finally the node's ~/.my.cnf so no password is needed where one is set up
+func mysqlCredentialCandidates(ip string, config *Config, clusterDefault *MySQLConnectionInfo) []*MySQLConnectionInfo {
+	var candidates []*MySQLConnectionInfo
+	seen := make(map[string]bool)
+	add := func(creds *MySQLConnectionInfo) {
+		key := fmt.Sprintf("%s|%t|%s|%s", creds.LoginPath, creds.OptionFile, creds.Username, creds.Password)
+		if !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, creds)
+		}
+	}
+
+	if creds := config.getNodeCredentials(ip); creds != nil {
+		if creds.MySQLLoginPath != "" {
+			add(&MySQLConnectionInfo{LoginPath: creds.MySQLLoginPath, Source: "node"})
+		}
+		if creds.MySQLOptionFile {
+			add(&MySQLConnectionInfo{OptionFile: true, Source: "node"})
+		}
+		if creds.HasMySQLPassword || creds.MySQLPasswordRef != "" {
+			if password, err := config.getNodeMySQLPassword(ip); err == nil {
+				username := creds.MySQLUsername
+				if username == "" {
+					username = config.LastMySQLUsername
+				}
+				add(&MySQLConnectionInfo{Username: username, Password: password, Source: "node"})
+			} else {
+				logVerbose("Warning: Could not read the MySQL password saved for %s: %v", ip, err)
+			}
+		}
+	}
+
+	if config.MySQLLoginPath != "" {
+		add(&MySQLConnectionInfo{LoginPath: config.MySQLLoginPath, Source: "cluster"})
+	}
+	if config.MySQLOptionFile {
+		add(&MySQLConnectionInfo{OptionFile: true, Source: "cluster"})
+	}
+	if clusterDefault == nil {
+		clusterDefault = clusterMySQLCredentials(config)
+	}
+	add(clusterDefault)
+	add(&MySQLConnectionInfo{OptionFile: true, Source: mysqlSourceFallback})
+	return candidates
+}
+
+// connectMySQL tests a login over TCP, then through the local socket, and returns the client
+// command that worked, or the last error output
+func connectMySQL(executor NodeExecutor, creds *MySQLConnectionInfo) (string, string, bool) {
+	client := buildMySQLClientCommand(creds)
+	var output string
+	for _, mysqlCmd := range []string{client + " -h 127.0.0.1 -P 3306", client} {
+		out, err := runCommand(executor, fmt.Sprintf("%s -e \"SELECT 1;\" 2>&1", mysqlCmd))
+		if err == nil && !strings.Contains(out, "ERROR") {
+			return mysqlCmd, out, true
+		}
+		output = out
+		if output == "" && err != nil {
+			output = err.Error()
+		}
+	}
+	return "", output, false
+}
+
+// isMySQLAccessDenied reports whether a mysql client error is a rejected login
+func isMySQLAccessDenied(output string) bool {
+	return strings.Contains(output, "ERROR 1045") || strings.Contains(output, "ERROR 1698") || strings.Contains(output, "Access denied")
+}
+
+// setNodeMySQLCredentials saves the MySQL username and password of a node, keeping its SSH settings
+func (c *Config) setNodeMySQLCredentials(nodeIP, username, password string) error {
+	sshUsername, usesKeys := c.LastSSHUsername, false
+	if creds := c.getNodeCredentials(nodeIP); creds != nil {
+		sshUsername, usesKeys = creds.SSHUsername, creds.UsesSSHKeys
+	}
+	if err := c.setNodeCredentials(nodeIP, sshUsername, username, "", password, usesKeys); err != nil {
+		return err
+	}
+	// A stored password replaces any reference, which would otherwise take precedence
+	c.getNodeCredentials(nodeIP).MySQLPasswordRef = ""
+	return nil
+}
+
+// promptNodeMySQLCredentials asks for MySQL credentials specific to a node after the saved ones were rejected
+func promptNodeMySQLCredentials(ip string, config *Config) *MySQLConnectionInfo {
+	if useDefaults || reportMode {
+		return nil
+	}
+	if !promptForBoolWithDefault(fmt.Sprintf("MySQL login was rejected on %s. Enter credentials for this node?", ip), true) {
+		return nil
+	}
+
+	defaultUsername := config.LastMySQLUsername
+	if creds := config.getNodeCredentials(ip); creds != nil && creds.MySQLUsername != "" {
+		defaultUsername = creds.MySQLUsername
+	}
+	if defaultUsername == "" {
+		defaultUsername = "root"
+	}
+	username := promptForInputWithDefault(fmt.Sprintf("MySQL username for %s", ip), defaultUsername)
+	password, err := readNewPassword(fmt.Sprintf("MySQL password for %s on %s: ", username, ip))
+	if err != nil {
+		logNormal("Warning: %v", err)
+		return nil
+	}
+	return &MySQLConnectionInfo{Username: username, Password: password, Source: "node"}
+}
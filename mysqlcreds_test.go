@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPerNodeMySQLCredentials(t *testing.T) {
+	previous := useDefaults
+	useDefaults = true
+	t.Cleanup(func() { useDefaults = previous })
+
+	members := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	node := func(ip string, users map[string]string, myCnf bool) *fakeNode {
+		return &fakeNode{IP: ip, Service: "active", MySQLUsers: users, MyCnf: myCnf,
+			Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...)}}
+	}
+	newFakeCluster(t,
+		node("10.0.0.1", map[string]string{"monitor": "cluster-pw"}, false),
+		node("10.0.0.2", map[string]string{"admin": "node-pw"}, false),
+		node("10.0.0.3", map[string]string{}, true),
+		node("10.0.0.4", map[string]string{"monitor": "other"}, false),
+	)
+
+	config := newTestConfig(t)
+	config.LastMySQLUsername = "monitor"
+	if err := config.setNodeCredentials("10.0.0.2", "root", "admin", "", "node-pw", true); err != nil {
+		t.Fatal(err)
+	}
+
+	analysis := &ClusterAnalysis{}
+	for _, ip := range members {
+		analysis.AllNodes = append(analysis.AllNodes, &GaleraClusterInfo{NodeIP: ip})
+	}
+	clusterDefault := &MySQLConnectionInfo{Username: "monitor", Password: "cluster-pw", Source: "cluster"}
+	if err := checkMySQLStatusOnAllNodes(analysis, &SSHConnectionInfo{Username: "root"}, clusterDefault, config, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	wantSources := map[string]string{"10.0.0.1": "cluster", "10.0.0.2": "node", "10.0.0.3": mysqlSourceFallback}
+	for _, node := range analysis.AllNodes {
+		want, ok := wantSources[node.NodeIP]
+		if !ok {
+			// No saved credential works on this node
+			if node.MySQLResponding || !isMySQLAccessDenied(node.StatusError) {
+				t.Errorf("%s: responding %t, error %q", node.NodeIP, node.MySQLResponding, node.StatusError)
+			}
+			continue
+		}
+		if !node.MySQLResponding {
+			t.Errorf("%s not responding: %s", node.NodeIP, node.StatusError)
+		}
+		if got := workingMySQLCredentials[node.NodeIP]; got == nil || got.Source != want {
+			t.Errorf("%s: credentials used %+v, want source %s", node.NodeIP, got, want)
+		}
+	}
+
+	// Recovery queries reuse the login that worked
+	if command := buildMySQLClientCommand(getRecoveryMySQLCredentials("10.0.0.2", config)); !strings.Contains(command, "-u admin") {
+		t.Errorf("recovery command for 10.0.0.2 = %q", command)
+	}
+	if command := buildMySQLClientCommand(getRecoveryMySQLCredentials("10.0.0.3", config)); command != "mysql" {
+		t.Errorf("recovery command for 10.0.0.3 = %q", command)
+	}
+
+	// A login path is used as is, and saving an SSH login keeps the node's MySQL user
+	config.MySQLLoginPath = "galera"
+	if command := buildMySQLClientCommand(mysqlCredentialCandidates("10.0.0.9", config, nil)[0]); command != "mysql --login-path='galera'" {
+		t.Errorf("login path command = %q", command)
+	}
+	if err := config.setNodeCredentials("10.0.0.2", "deploy", "", "", "", true); err != nil {
+		t.Fatal(err)
+	}
+	if creds := config.getNodeCredentials("10.0.0.2"); creds.MySQLUsername != "admin" {
+		t.Errorf("MySQL username lost: %+v", creds)
+	}
+}
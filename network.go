@@ -0,0 +1,379 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default Galera ports
+const (
+	defaultISTPortOffset = 1    // IST listens on the gmcast port + 1 unless ist.recv_addr is set
+	defaultSSTPort       = 4444 // rsync, mariabackup and xtrabackup SST
+)
+
+// portProbeTimeout bounds each TCP connection attempt between nodes
+const portProbeTimeout = 3
+
+// Galera port roles
+const (
+	portGmcast = "gmcast"
+	portIST    = "ist"
+	portSST    = "sst"
+)
+
+// Results of a TCP probe
+const (
+	probeOpen    = "open"    // Connected
+	probeClosed  = "closed"  // Refused: host reachable, nothing listening (or a REJECT rule)
+	probeTimeout = "timeout" // No answer: usually a firewall dropping packets
+	probeError   = "error"   // The probe could not run on the source node
+)
+
+// GaleraPorts are the ports a node listens on for the cluster
+type GaleraPorts struct {
+	Gmcast int `json:"gmcast"`
+	IST    int `json:"ist"`
+	SST    int `json:"sst"`
+}
+
+// PortProbe is the result of connecting from one node to another node's Galera port
+type PortProbe struct {
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Role    string        `json:"role"`
+	Port    int           `json:"port"`
+	Result  string        `json:"result"`
+	Latency time.Duration `json:"latency_ns,omitempty"`
+}
+
+// NodeNetwork is what is known about a node's Galera ports
+type NodeNetwork struct {
+	IP        string       `json:"ip"`
+	Ports     GaleraPorts  `json:"ports"`
+	Listening map[int]bool `json:"listening,omitempty"` // Local listening state of the Galera ports (nil if unknown)
+	Error     string       `json:"error,omitempty"`
+}
+
+// NetworkReport is the N×N reachability matrix of a cluster's Galera ports
+type NetworkReport struct {
+	Nodes    []*NodeNetwork `json:"nodes"`
+	Probes   []PortProbe    `json:"probes"`
+	Issues   []string       `json:"issues,omitempty"`
+	Warnings []string       `json:"warnings,omitempty"`
+}
+
+// parseGaleraPorts reads the node's ports from wsrep_provider_options (gmcast.listen_addr,
+// ist.recv_addr) and wsrep_sst_receive_address, using the Galera defaults for the rest
+func parseGaleraPorts(providerOptions, sstReceiveAddress string) GaleraPorts {
+	ports := GaleraPorts{Gmcast: defaultGaleraPort, SST: defaultSSTPort}
+	options := parseProviderOptions(providerOptions)
+	if port := addressPort(options["gmcast.listen_addr"]); port != 0 {
+		ports.Gmcast = port
+	}
+	ports.IST = ports.Gmcast + defaultISTPortOffset
+	if port := addressPort(options["ist.recv_addr"]); port != 0 {
+		ports.IST = port
+	}
+	if port := addressPort(sstReceiveAddress); port != 0 {
+		ports.SST = port
+	}
+	return ports
+}
+
+// parseProviderOptions splits "gcache.size=1G; gmcast.listen_addr=tcp://0.0.0.0:4567" into a map
+func parseProviderOptions(value string) map[string]string {
+	options := make(map[string]string)
+	for _, option := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(option, "=")
+		if ok {
+			options[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+	}
+	return options
+}
+
+// addressPort returns the port of "tcp://host:port", "host:port" or "[ipv6]:port", or 0
+func addressPort(address string) int {
+	if address == "" || address == "AUTO" {
+		return 0
+	}
+	if _, rest, ok := strings.Cut(address, "://"); ok {
+		address = rest
+	}
+	_, portText, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return 0
+	}
+	return port
+}
+
+// probeCommand returns a shell command that connects to host:port with bash's /dev/tcp and prints
+// "open <microseconds>", "timeout" or "closed"
+func probeCommand(host string, port int) string {
+	return fmt.Sprintf(`s=$(date +%%s%%N); timeout %d bash -c '</dev/tcp/%s/%d' 2>/dev/null; rc=$?; `+
+		`if [ $rc -eq 0 ]; then echo "open $(( ($(date +%%s%%N) - s) / 1000 ))"; elif [ $rc -eq 124 ]; then echo timeout; else echo closed; fi`,
+		portProbeTimeout, host, port)
+}
+
+// parseProbeOutput interprets the output of probeCommand
+func parseProbeOutput(output string) (string, time.Duration) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return probeError, 0
+	}
+	switch fields[0] {
+	case probeOpen:
+		var latency time.Duration
+		if len(fields) > 1 {
+			if usec, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				latency = time.Duration(usec) * time.Microsecond
+			}
+		}
+		return probeOpen, latency
+	case probeTimeout, probeClosed:
+		return fields[0], 0
+	}
+	return probeError, 0
+}
+
+// listeningPorts returns the TCP ports a node listens on, from ss or netstat
+func listeningPorts(executor NodeExecutor) (map[int]bool, error) {
+	output, err := runCommand(executor, "ss -ltnH 2>/dev/null || netstat -ltn 2>/dev/null")
+	if err != nil {
+		return nil, err
+	}
+	ports := make(map[int]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		// ss: State Recv-Q Send-Q Local Peer; netstat: Proto Recv-Q Send-Q Local Foreign State
+		if len(fields) < 4 {
+			continue
+		}
+		local := fields[3]
+		if i := strings.LastIndex(local, ":"); i != -1 {
+			if port, err := strconv.Atoi(local[i+1:]); err == nil {
+				ports[port] = true
+			}
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("neither ss nor netstat listed any listening port")
+	}
+	return ports, nil
+}
+
+// collectNodeNetwork reads a node's Galera ports and which of them are listening
+func collectNodeNetwork(ip string, config *Config) *NodeNetwork {
+	node := &NodeNetwork{IP: ip, Ports: parseGaleraPorts("", "")}
+	executor, err := getNodeExecutor(ip, config)
+	if err != nil {
+		node.Error = fmt.Sprintf("connection failed: %v", err)
+		return node
+	}
+
+	info, err := getGaleraClusterInfo(executor, ip)
+	if err == nil {
+		node.Ports = parseGaleraPorts(info.ProviderOptions, info.SSTReceiveAddress)
+	} else {
+		logVerbose("⚠️  Could not read the configuration of %s, assuming default ports: %v", ip, err)
+	}
+
+	listening, err := listeningPorts(executor)
+	if err != nil {
+		logVerbose("⚠️  Could not list listening ports on %s: %v", ip, err)
+	} else {
+		node.Listening = make(map[int]bool)
+		for _, port := range []int{node.Ports.Gmcast, node.Ports.IST, node.Ports.SST} {
+			node.Listening[port] = listening[port]
+		}
+	}
+	return node
+}
+
+// checkNetwork probes every node's Galera ports from every other node over SSH
+func checkNetwork(ips []string, config *Config) *NetworkReport {
+	report := &NetworkReport{}
+	for _, ip := range ips {
+		logNormal("🔍 Reading Galera ports of %s...", ip)
+		report.Nodes = append(report.Nodes, collectNodeNetwork(ip, config))
+	}
+
+	for _, from := range report.Nodes {
+		if from.Error != "" {
+			continue
+		}
+		executor, err := getNodeExecutor(from.IP, config)
+		if err != nil {
+			from.Error = fmt.Sprintf("connection failed: %v", err)
+			continue
+		}
+		for _, to := range report.Nodes {
+			if to.IP == from.IP {
+				continue
+			}
+			for _, target := range []struct {
+				role string
+				port int
+			}{{portGmcast, to.Ports.Gmcast}, {portIST, to.Ports.IST}, {portSST, to.Ports.SST}} {
+				probe := PortProbe{From: from.IP, To: to.IP, Role: target.role, Port: target.port}
+				output, err := runCommand(executor, probeCommand(to.IP, target.port))
+				if err != nil && output == "" {
+					probe.Result = probeError
+				} else {
+					probe.Result, probe.Latency = parseProbeOutput(output)
+				}
+				logVerbose("   %s -> %s:%d (%s): %s", from.IP, to.IP, target.port, target.role, probe.Result)
+				report.Probes = append(report.Probes, probe)
+			}
+		}
+	}
+
+	report.evaluate()
+	return report
+}
+
+// evaluate turns the matrix into issues and warnings. A refused gmcast port is only a problem when
+// the target is listening on it, and only a warning when its listening state is unknown; a timeout
+// always points to a firewall dropping packets. Galera only opens the IST and SST ports of a joiner
+// while it receives a transfer, so refusals on them are expected.
+func (r *NetworkReport) evaluate() {
+	byIP := make(map[string]*NodeNetwork)
+	for _, node := range r.Nodes {
+		byIP[node.IP] = node
+		if node.Error != "" {
+			r.Issues = append(r.Issues, fmt.Sprintf("%s: %s", node.IP, node.Error))
+			continue
+		}
+		if node.Listening != nil && !node.Listening[node.Ports.Gmcast] {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("%s is not listening on its gmcast port %d (MySQL stopped?)", node.IP, node.Ports.Gmcast))
+		}
+	}
+
+	for _, probe := range r.Probes {
+		target := byIP[probe.To]
+		description := fmt.Sprintf("%s cannot reach %s:%d (%s port)", probe.From, probe.To, probe.Port, probe.Role)
+		switch {
+		case probe.Result == probeTimeout:
+			r.Issues = append(r.Issues, description+": connection timed out, check the firewall")
+		case probe.Result == probeError:
+			r.Warnings = append(r.Warnings, fmt.Sprintf("%s could not probe %s:%d (bash and timeout required)", probe.From, probe.To, probe.Port))
+		case probe.Result == probeClosed && probe.Role == portGmcast && target.Listening == nil:
+			r.Warnings = append(r.Warnings, description+": connection refused, and whether the port is listening is unknown (ss or netstat required)")
+		case probe.Result == probeClosed && probe.Role == portGmcast && target.Listening[probe.Port]:
+			r.Issues = append(r.Issues, description+": connection refused although the port is listening, check the firewall")
+		}
+	}
+}
+
+// displayNetworkReport prints the reachability matrix, one table per port role
+func displayNetworkReport(report *NetworkReport) {
+	fmt.Println()
+	fmt.Println("=== GALERA NETWORK CHECK ===")
+	fmt.Println()
+	for _, node := range report.Nodes {
+		listening := "unknown"
+		if node.Listening != nil {
+			var parts []string
+			for _, p := range []struct {
+				role string
+				port int
+			}{{portGmcast, node.Ports.Gmcast}, {portIST, node.Ports.IST}, {portSST, node.Ports.SST}} {
+				mark := "✅"
+				if !node.Listening[p.port] {
+					mark = "–"
+				}
+				parts = append(parts, fmt.Sprintf("%s %d %s", p.role, p.port, mark))
+			}
+			listening = strings.Join(parts, ", ")
+		}
+		if node.Error != "" {
+			listening = "❌ " + node.Error
+		}
+		fmt.Printf("🏠 %s: %s\n", node.IP, listening)
+	}
+
+	probes := make(map[string]PortProbe)
+	for _, probe := range report.Probes {
+		probes[probe.From+"|"+probe.To+"|"+probe.Role] = probe
+	}
+	width := 6
+	for _, node := range report.Nodes {
+		if len(node.IP) > width {
+			width = len(node.IP)
+		}
+	}
+
+	for _, role := range []string{portGmcast, portIST, portSST} {
+		fmt.Println()
+		fmt.Printf("📡 %s (rows: from, columns: to)\n", strings.ToUpper(role))
+		fmt.Printf("   %-*s", width, "")
+		for _, to := range report.Nodes {
+			fmt.Printf("  %-*s", width, to.IP)
+		}
+		fmt.Println()
+		for _, from := range report.Nodes {
+			fmt.Printf("   %-*s", width, from.IP)
+			for _, to := range report.Nodes {
+				cell := "-"
+				if probe, ok := probes[from.IP+"|"+to.IP+"|"+role]; ok {
+					cell = formatProbe(probe)
+				} else if from.IP != to.IP {
+					cell = "?"
+				}
+				fmt.Printf("  %-*s", width, cell)
+			}
+			fmt.Println()
+		}
+	}
+
+	fmt.Println()
+	if len(report.Issues) == 0 && len(report.Warnings) == 0 {
+		fmt.Println("✅ Every node reaches the Galera ports of every other node")
+		return
+	}
+	for _, issue := range report.Issues {
+		fmt.Printf("❌ %s\n", issue)
+	}
+	for _, warning := range report.Warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+}
+
+// formatProbe returns a matrix cell: the latency of an open port, or the failure
+func formatProbe(probe PortProbe) string {
+	if probe.Result == probeOpen {
+		return fmt.Sprintf("%.1fms", float64(probe.Latency)/float64(time.Millisecond))
+	}
+	return probe.Result
+}
+
+// runNetworkCommand handles "galerahealth network": the Galera port reachability matrix
+func runNetworkCommand(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unknown network option: %s", args[0])
+	}
+
+	logMinimal("=== GaleraHealth - Network Check ===")
+	config := loadClusterConfig()
+	ips, err := discoverClusterNodes(config)
+	if err != nil {
+		return err
+	}
+
+	report := checkNetwork(ips, config)
+	displayNetworkReport(report)
+	if err := saveConfig(config); err != nil {
+		logNormal("Warning: Could not save configuration: %v", err)
+	}
+	if len(report.Issues) > 0 {
+		return fmt.Errorf("%d network problems found", len(report.Issues))
+	}
+	return nil
+}
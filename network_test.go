@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseGaleraPorts(t *testing.T) {
+	tests := []struct {
+		options, sst string
+		want         GaleraPorts
+	}{
+		{"", "", GaleraPorts{Gmcast: 4567, IST: 4568, SST: 4444}},
+		{"gcache.size=1G; gmcast.listen_addr=tcp://0.0.0.0:5567", "", GaleraPorts{Gmcast: 5567, IST: 5568, SST: 4444}},
+		{"gmcast.listen_addr=tcp://[::]:4567;ist.recv_addr=10.0.0.1:4600", "10.0.0.1:4500", GaleraPorts{Gmcast: 4567, IST: 4600, SST: 4500}},
+		{"", "AUTO", GaleraPorts{Gmcast: 4567, IST: 4568, SST: 4444}},
+	}
+	for _, tt := range tests {
+		if got := parseGaleraPorts(tt.options, tt.sst); got != tt.want {
+			t.Errorf("parseGaleraPorts(%q, %q) = %+v, want %+v", tt.options, tt.sst, got, tt.want)
+		}
+	}
+
+	if result, latency := parseProbeOutput("open 1250\n"); result != probeOpen || latency != 1250*time.Microsecond {
+		t.Errorf("parseProbeOutput = %s %v", result, latency)
+	}
+}
+
+func TestCheckNetwork(t *testing.T) {
+	members := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip, service, extra string) *fakeNode {
+		return &fakeNode{IP: ip, Service: service, Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...) + extra}}
+	}
+	// The second node receives IST on a custom port that its firewall drops
+	second := node("10.0.0.2", "active", "wsrep_provider_options = \"gcache.size=1G; ist.recv_addr=10.0.0.2:4580\"\n")
+	second.Blocked = []int{4580}
+	newFakeCluster(t, node("10.0.0.1", "active", ""), second, node("10.0.0.3", "inactive", ""))
+
+	report := checkNetwork(members, newTestConfig(t))
+
+	if len(report.Probes) != 18 {
+		t.Fatalf("got %d probes, want 18", len(report.Probes))
+	}
+	if ports := report.Nodes[1].Ports; ports.IST != 4580 {
+		t.Errorf("ports of 10.0.0.2 = %+v", ports)
+	}
+	assertErrors(t, report.Issues, []string{
+		"10.0.0.1 cannot reach 10.0.0.2:4580 (ist port): connection timed out",
+		"10.0.0.3 cannot reach 10.0.0.2:4580 (ist port): connection timed out",
+	})
+	if len(report.Issues) != 2 {
+		t.Errorf("issues: %v", report.Issues)
+	}
+	// Synced nodes don't listen on their IST port: only the stopped node is reported
+	assertErrors(t, report.Warnings, []string{"10.0.0.3 is not listening on its gmcast port 4567"})
+	if len(report.Warnings) != 1 {
+		t.Errorf("warnings: %v", report.Warnings)
+	}
+
+	for _, probe := range report.Probes {
+		if probe.From == "10.0.0.2" && probe.To == "10.0.0.1" && probe.Role == portGmcast && (probe.Result != probeOpen || probe.Latency == 0) {
+			t.Errorf("probe %+v, want open with a latency", probe)
+		}
+	}
+}
+
+func TestEvaluateUnknownListeningState(t *testing.T) {
+	ports := GaleraPorts{Gmcast: 4567, IST: 4568, SST: 4444}
+	report := &NetworkReport{
+		// Neither ss nor netstat runs on 10.0.0.2
+		Nodes: []*NodeNetwork{{IP: "10.0.0.1", Ports: ports, Listening: map[int]bool{4567: true}}, {IP: "10.0.0.2", Ports: ports}},
+		Probes: []PortProbe{
+			{From: "10.0.0.1", To: "10.0.0.2", Role: portGmcast, Port: 4567, Result: probeClosed},
+			{From: "10.0.0.1", To: "10.0.0.2", Role: portIST, Port: 4568, Result: probeClosed},
+			{From: "10.0.0.1", To: "10.0.0.2", Role: portSST, Port: 4444, Result: probeClosed},
+			{From: "10.0.0.2", To: "10.0.0.1", Role: portIST, Port: 4568, Result: probeClosed},
+		},
+	}
+	report.evaluate()
+
+	if len(report.Issues) != 0 {
+		t.Errorf("issues: %v", report.Issues)
+	}
+	assertErrors(t, report.Warnings, []string{"10.0.0.1 cannot reach 10.0.0.2:4567 (gmcast port): connection refused, and whether the port is listening is unknown"})
+	if len(report.Warnings) != 1 {
+		t.Errorf("warnings: %v", report.Warnings)
+	}
+}
@@ -1,9 +1,7 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +14,7 @@ type NodeState struct {
 	SeqNo       int64
 	LatestIDB   time.Time
 	HasGrastate bool
+	Clock       *ClockStatus // Measured for down nodes, whose .ibd timestamps may be compared
 }
 
 // ClusterState represents the overall state of the cluster
@@ -24,6 +23,8 @@ type ClusterState struct {
 	AllDown  bool
 	SomeDown bool
 	AllUp    bool
+
+	MaxClockSkew time.Duration // Skew above which .ibd timestamps can't be compared across nodes
 }
 
 // analyzeClusterState analyzes the current state of all cluster nodes
@@ -31,7 +32,8 @@ func analyzeClusterState(clusterIPs []string, config *Config) (*ClusterState, er
 	logNormal("🔍 Analyzing cluster state for recovery assessment...")
 
 	state := &ClusterState{
-		Nodes: make([]NodeState, len(clusterIPs)),
+		Nodes:        make([]NodeState, len(clusterIPs)),
+		MaxClockSkew: getMaxClockSkew(config),
 	}
 
 	upCount := 0
@@ -42,8 +44,8 @@ func analyzeClusterState(clusterIPs []string, config *Config) (*ClusterState, er
 		// Check if MySQL/MariaDB is running on this node
 		logVerbose("Checking MySQL/MariaDB status on node %s", ip)
 
-		cmd := "systemctl is-active mariadb mysqld mysql 2>/dev/null | head -1"
-		output, err := executeCommandOnNode(ip, cmd, config)
+		service := getNodeServiceBackend(ip, config)
+		output, err := executeServiceCommandOnNode(ip, service, service.statusCommand(), config)
 		nodeState.IsUp = (err == nil && strings.TrimSpace(output) == "active")
 
 		if nodeState.IsUp {
@@ -60,6 +62,11 @@ func analyzeClusterState(clusterIPs []string, config *Config) (*ClusterState, er
 			// Get latest IDB timestamp for fallback method
 			latestIDB := getLatestIDBTimestamp(ip, config)
 			nodeState.LatestIDB = latestIDB
+
+			// The timestamps are only comparable if the clocks agree
+			if executor, err := getNodeExecutor(ip, config); err == nil {
+				nodeState.Clock = checkNodeClock(executor, ip)
+			}
 		}
 
 		state.Nodes[i] = nodeState
@@ -183,11 +190,51 @@ func selectBootstrapNode(state *ClusterState) (string, string, error) {
 
 	method := fmt.Sprintf("Selected node %s based on latest .ibd file timestamp (%s)",
 		bestNode.IP, latestTime.Format("2006-01-02 15:04:05"))
+	if reason := timestampSelectionDoubt(downNodes, bestNode, state.MaxClockSkew); reason != "" {
+		method += " - UNTRUSTWORTHY: " + reason
+		logReport("⚠️ .ibd timestamps are compared across hosts: %s", reason)
+		logReport("   Verify the node with the latest data before confirming (e.g. mysqld --wsrep-recover on each node)")
+	}
 	logReport("🎯 %s", method)
 
 	return bestNode.IP, method, nil
 }
 
+// timestampSelectionDoubt explains why a bootstrap node chosen by .ibd timestamp may be wrong: clocks
+// skewed beyond maxSkew, skewed more than the lead of the chosen node, or not synchronized.
+// It returns "" when the choice can be trusted.
+func timestampSelectionDoubt(downNodes []NodeState, best NodeState, maxSkew time.Duration) string {
+	if maxSkew == 0 {
+		maxSkew = defaultMaxClockSkew
+	}
+
+	var clocks []*ClockStatus
+	var unsynced []string
+	lead := time.Duration(-1)
+	for _, node := range downNodes {
+		clocks = append(clocks, node.Clock)
+		if node.Clock != nil && node.Clock.SyncState == timeSyncUnsynced {
+			unsynced = append(unsynced, node.IP)
+		}
+		if node.IP != best.IP && !node.LatestIDB.IsZero() {
+			if gap := best.LatestIDB.Sub(node.LatestIDB); lead < 0 || gap < lead {
+				lead = gap
+			}
+		}
+	}
+
+	skew, measured := clockSkew(clocks)
+	switch {
+	case measured && skew > maxSkew:
+		return fmt.Sprintf("clock skew between nodes is %s (threshold %s)", skew.Round(time.Millisecond), maxSkew)
+	case measured && lead >= 0 && skew >= lead:
+		return fmt.Sprintf("clock skew between nodes (%s) is larger than the lead of %s (%s)", skew.Round(time.Millisecond), best.IP, lead)
+	case len(unsynced) > 0:
+		return fmt.Sprintf("time is not synchronized on %s", strings.Join(unsynced, ", "))
+	}
+	return ""
+}
+
 // performClusterRecovery attempts to recover the cluster
 func performClusterRecovery(state *ClusterState, config *Config) error {
 	if state.AllUp {
@@ -208,24 +255,49 @@ func performClusterRecovery(state *ClusterState, config *Config) error {
 	return nil
 }
 
-// startDownNodes attempts to start the down nodes
+// startDownNodes attempts to start the down nodes one at a time, waiting for each to reach Synced
 func startDownNodes(state *ClusterState, config *Config) error {
+	// Find a running node to act as reference for state transfer progress
+	var donorIP string
+	upCount := 0
 	for _, node := range state.Nodes {
-		if !node.IsUp {
-			logReport("🔄 Attempting to start MySQL/MariaDB on node %s...", node.IP)
-
-			if !askUserPermission(fmt.Sprintf("Start MySQL/MariaDB service on node %s", node.IP)) {
-				logReport("⏭️ Skipping node %s (user declined)", node.IP)
-				continue
+		if node.IsUp {
+			upCount++
+			if donorIP == "" {
+				donorIP = node.IP
 			}
+		}
+	}
 
-			err := startMySQLService(node.IP, config)
-			if err != nil {
-				logReport("❌ Failed to start MySQL/MariaDB on node %s: %v", node.IP, err)
-			} else {
-				logReport("✅ Successfully started MySQL/MariaDB on node %s", node.IP)
-			}
+	evidence := clusterStateEvidence(state)
+	var completed []string
+	for i, node := range state.Nodes {
+		if node.IsUp {
+			continue
 		}
+
+		logReport("🔄 Attempting to start MySQL/MariaDB on node %s...", node.IP)
+
+		if !askUserPermission(fmt.Sprintf("Start MySQL/MariaDB service on node %s", node.IP)) {
+			logReport("⏭️ Skipping node %s (user declined)", node.IP)
+			continue
+		}
+
+		err := startMySQLService(node.IP, evidence, config)
+		if err != nil {
+			reportJoinFailure(node.IP, nil, err, completed, pendingDownNodes(state.Nodes[i+1:], ""), config)
+			return fmt.Errorf("failed to start MySQL/MariaDB on node %s: %v", node.IP, err)
+		}
+
+		status, err := waitForNodeSynced(node.IP, upCount+1, getJoinTimeout(config), donorIP, config)
+		if err != nil {
+			reportJoinFailure(node.IP, status, err, completed, pendingDownNodes(state.Nodes[i+1:], ""), config)
+			return fmt.Errorf("node %s failed to join the cluster: %v", node.IP, err)
+		}
+
+		logReport("✅ Successfully started MySQL/MariaDB on node %s", node.IP)
+		upCount++
+		completed = append(completed, node.IP)
 	}
 	return nil
 }
@@ -240,25 +312,37 @@ func bootstrapCluster(state *ClusterState, config *Config) error {
 
 	logReport("📋 Bootstrap node selection method: %s", method)
 
+	// Fail before asking when the node has no known way to bootstrap (containers, openrc)
+	if _, err := getNodeServiceBackend(bootstrapIP, config).bootstrapCommand(); err != nil {
+		return fmt.Errorf("cannot bootstrap node %s, no node was changed: %v", bootstrapIP, err)
+	}
+
 	if !askUserPermission(fmt.Sprintf("Bootstrap the cluster using node %s", bootstrapIP)) {
 		return fmt.Errorf("user declined cluster bootstrap")
 	}
 
 	// Bootstrap the selected node
 	logReport("🚀 Bootstrapping cluster on node %s...", bootstrapIP)
-	err = bootstrapNode(bootstrapIP, config)
+	evidence := clusterStateEvidence(state)
+	evidence["selection_method"] = method
+	err = bootstrapNode(bootstrapIP, evidence, config)
 	if err != nil {
 		return fmt.Errorf("failed to bootstrap node %s: %v", bootstrapIP, err)
 	}
 
-	logReport("✅ Successfully bootstrapped cluster on node %s", bootstrapIP)
+	// Wait for the bootstrap node to form a Primary component on its own
+	status, err := waitForNodeSynced(bootstrapIP, 1, getBootstrapTimeout(config), "", config)
+	if err != nil {
+		reportJoinFailure(bootstrapIP, status, err, nil, pendingDownNodes(state.Nodes, bootstrapIP), config)
+		return fmt.Errorf("bootstrap node %s did not become Synced: %v", bootstrapIP, err)
+	}
 
-	// Wait a bit for the bootstrap node to stabilize
-	logReport("⏳ Waiting for bootstrap node to stabilize...")
-	time.Sleep(5 * time.Second)
+	logReport("✅ Successfully bootstrapped cluster on node %s", bootstrapIP)
 
-	// Start other nodes
-	for _, node := range state.Nodes {
+	// Start other nodes one at a time, waiting for each to join before the next
+	clusterSize := 1
+	completed := []string{bootstrapIP}
+	for i, node := range state.Nodes {
 		if node.IP != bootstrapIP && !node.IsUp {
 			logReport("🔄 Attempting to start MySQL/MariaDB on node %s...", node.IP)
 
@@ -267,40 +351,73 @@ func bootstrapCluster(state *ClusterState, config *Config) error {
 				continue
 			}
 
-			err := startMySQLService(node.IP, config)
+			err := startMySQLService(node.IP, evidence, config)
 			if err != nil {
-				logReport("❌ Failed to start MySQL/MariaDB on node %s: %v", node.IP, err)
-			} else {
-				logReport("✅ Successfully started MySQL/MariaDB on node %s", node.IP)
-				// Wait a bit between node starts
-				time.Sleep(2 * time.Second)
+				reportJoinFailure(node.IP, nil, err, completed, pendingDownNodes(state.Nodes[i+1:], bootstrapIP), config)
+				return fmt.Errorf("failed to start MySQL/MariaDB on node %s: %v", node.IP, err)
 			}
+
+			status, err := waitForNodeSynced(node.IP, clusterSize+1, getJoinTimeout(config), bootstrapIP, config)
+			if err != nil {
+				reportJoinFailure(node.IP, status, err, completed, pendingDownNodes(state.Nodes[i+1:], bootstrapIP), config)
+				return fmt.Errorf("node %s failed to join the cluster: %v", node.IP, err)
+			}
+
+			logReport("✅ Successfully started MySQL/MariaDB on node %s", node.IP)
+			clusterSize++
+			completed = append(completed, node.IP)
 		}
 	}
 
 	return nil
 }
 
-// bootstrapNode performs galera_new_cluster on the specified node
-func bootstrapNode(ip string, config *Config) error {
-	cmd := "galera_new_cluster"
-	_, err := executeCommandOnNode(ip, cmd, config)
+// pendingDownNodes returns the IPs of down nodes that have not been started yet
+func pendingDownNodes(nodes []NodeState, skipIP string) []string {
+	var pending []string
+	for _, node := range nodes {
+		if !node.IsUp && node.IP != skipIP {
+			pending = append(pending, node.IP)
+		}
+	}
+	return pending
+}
+
+// bootstrapNode starts the specified node as a new cluster (galera_new_cluster on systemd)
+func bootstrapNode(ip string, evidence map[string]string, config *Config) error {
+	service := getNodeServiceBackend(ip, config)
+	cmd, err := service.bootstrapCommand()
+	if err != nil {
+		return err
+	}
+	_, err = executeAuditedServiceCommandOnNode(ip, service, "bootstrap", cmd, evidence, config)
 	return err
 }
 
 // startMySQLService starts MySQL/MariaDB service on the specified node
-func startMySQLService(ip string, config *Config) error {
-	services := []string{"mariadb", "mysql", "mysqld"}
-
-	for _, service := range services {
-		cmd := fmt.Sprintf("systemctl start %s", service)
-		_, err := executeCommandOnNode(ip, cmd, config)
-		if err == nil {
-			return nil // Successfully started with this service name
-		}
+func startMySQLService(ip string, evidence map[string]string, config *Config) error {
+	service := getNodeServiceBackend(ip, config)
+	cmd, err := service.startCommand()
+	if err != nil {
+		return err
+	}
+	if _, err := executeAuditedServiceCommandOnNode(ip, service, "start", cmd, evidence, config); err != nil {
+		return fmt.Errorf("failed to start MySQL/MariaDB (%s): %v", service, err)
 	}
+	return nil
+}
 
-	return fmt.Errorf("failed to start MySQL/MariaDB with any service name")
+// stopMySQLService stops MySQL/MariaDB service on the specified node
+func stopMySQLService(ip string, evidence map[string]string, config *Config) error {
+	service := getNodeServiceBackend(ip, config)
+	cmd, err := service.stopCommand()
+	if err != nil {
+		return err
+	}
+	if _, err := executeAuditedServiceCommandOnNode(ip, service, "stop", cmd, evidence, config); err != nil {
+		return fmt.Errorf("failed to stop MySQL/MariaDB (%s): %v", service, err)
+	}
+	return nil
 }
 
 // askUserPermission asks the user for permission to perform an action
@@ -310,10 +427,8 @@ func askUserPermission(action string) bool {
 	// This is because recovery actions can be destructive and should be explicitly confirmed
 	fmt.Printf("❓ Do you want to %s? (y/N): ", action)
 
-	// Read input directly using bufio scanner
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	response := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	stdinScanner.Scan()
+	response := strings.ToLower(strings.TrimSpace(stdinScanner.Text()))
 
 	// Default to "no" if empty response
 	if response == "" {
@@ -323,24 +438,32 @@ func askUserPermission(action string) bool {
 	return response == "y" || response == "yes"
 }
 
-// executeCommandOnNode executes a command on a specific node using proper SSH authentication
+// executeCommandOnNode executes a command on a specific node using its shared executor
 func executeCommandOnNode(ip string, command string, config *Config) (string, error) {
-	// Check if this is a local command
-	if ip == "localhost" || ip == "127.0.0.1" {
-		return executeLocalCommand(command)
+	executor, err := getNodeExecutor(ip, config)
+	if err != nil {
+		return "", fmt.Errorf("failed to establish SSH connection to %s: %v", ip, err)
+	}
+
+	output, err := runCommand(executor, command)
+	if err != nil {
+		return output, fmt.Errorf("failed to execute command on %s: %w", ip, err)
 	}
 
-	// For remote nodes, use the existing SSH connection logic
-	sshClient, _, err := createSSHConnectionWithNodeCredentials(ip, config)
+	return output, nil
+}
+
+// executeServiceCommandOnNode executes a service command on a node; for containerized nodes
+// it runs on the container host instead of inside the container
+func executeServiceCommandOnNode(ip string, service *ServiceBackend, command string, config *Config) (string, error) {
+	executor, err := getNodeExecutor(ip, config)
 	if err != nil {
 		return "", fmt.Errorf("failed to establish SSH connection to %s: %v", ip, err)
 	}
-	defer sshClient.Close()
 
-	// Execute the command using the SSH client
-	output, err := sshClient.executeCommand(command)
+	output, err := runCommand(serviceExecutor(executor, service), command)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute command on %s: %v", ip, err)
+		return output, fmt.Errorf("failed to execute command on %s: %w", ip, err)
 	}
 
 	return output, nil
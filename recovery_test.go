@@ -0,0 +1,211 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelectBootstrapNode(t *testing.T) {
+	older := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		nodes      []NodeState
+		wantIP     string
+		wantMethod string
+		wantErr    bool
+	}{
+		{
+			name: "highest seqno",
+			nodes: []NodeState{
+				{IP: "10.0.0.1", SeqNo: 100, HasGrastate: true},
+				{IP: "10.0.0.2", SeqNo: 250, HasGrastate: true},
+				{IP: "10.0.0.3", SeqNo: 200, HasGrastate: true},
+			},
+			wantIP:     "10.0.0.2",
+			wantMethod: "highest seqno",
+		},
+		{
+			name: "running nodes are ignored",
+			nodes: []NodeState{
+				{IP: "10.0.0.1", IsUp: true},
+				{IP: "10.0.0.2", SeqNo: 10, HasGrastate: true},
+				{IP: "10.0.0.3", SeqNo: 20, HasGrastate: true},
+			},
+			wantIP:     "10.0.0.3",
+			wantMethod: "highest seqno",
+		},
+		{
+			name: "seqno -1 falls back to latest .ibd file",
+			nodes: []NodeState{
+				{IP: "10.0.0.1", SeqNo: -1, HasGrastate: true, LatestIDB: older},
+				{IP: "10.0.0.2", SeqNo: 300, HasGrastate: true, LatestIDB: newer},
+			},
+			wantIP:     "10.0.0.2",
+			wantMethod: "latest .ibd file timestamp",
+		},
+		{
+			name: "missing grastate.dat falls back to latest .ibd file",
+			nodes: []NodeState{
+				{IP: "10.0.0.1", SeqNo: 5, HasGrastate: true, LatestIDB: newer},
+				{IP: "10.0.0.2", SeqNo: -1, LatestIDB: older},
+			},
+			wantIP:     "10.0.0.1",
+			wantMethod: "latest .ibd file timestamp",
+		},
+		{
+			name: "no usable evidence",
+			nodes: []NodeState{
+				{IP: "10.0.0.1", SeqNo: -1},
+				{IP: "10.0.0.2", SeqNo: -1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no down nodes",
+			nodes: []NodeState{
+				{IP: "10.0.0.1", IsUp: true},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, method, err := selectBootstrapNode(&ClusterState{Nodes: tt.nodes})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got node %s", ip)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectBootstrapNode: %v", err)
+			}
+			if ip != tt.wantIP {
+				t.Errorf("selected %s, want %s", ip, tt.wantIP)
+			}
+			if !strings.Contains(method, tt.wantMethod) {
+				t.Errorf("method %q does not mention %q", method, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestPerformClusterRecovery(t *testing.T) {
+	ips := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip, service string, seqno int64) *fakeNode {
+		return &fakeNode{
+			IP:        ip,
+			Service:   service,
+			LatestIBD: 1700000000,
+			Files: map[string]string{
+				"/etc/mysql/mariadb.conf.d/60-galera.cnf": galeraConfig("prod", ip, ips...),
+				"/var/lib/mysql/grastate.dat":             grastate(seqno),
+			},
+		}
+	}
+	failing := func(n *fakeNode) *fakeNode {
+		n.FailStart = true
+		return n
+	}
+
+	tests := []struct {
+		name         string
+		nodes        []*fakeNode
+		answers      []string
+		wantCommands []string
+		wantActive   []string
+		wantErr      string
+	}{
+		{
+			name:       "all nodes running",
+			nodes:      []*fakeNode{node("10.0.0.1", "active", -1), node("10.0.0.2", "active", -1), node("10.0.0.3", "active", -1)},
+			wantActive: ips,
+		},
+		{
+			name:         "one node down is started",
+			nodes:        []*fakeNode{node("10.0.0.1", "active", -1), node("10.0.0.2", "inactive", 40), node("10.0.0.3", "active", -1)},
+			answers:      []string{"y"},
+			wantCommands: []string{"10.0.0.2: systemctl start mariadb"},
+			wantActive:   ips,
+		},
+		{
+			name:         "declined start leaves node down",
+			nodes:        []*fakeNode{node("10.0.0.1", "active", -1), node("10.0.0.2", "inactive", 40), node("10.0.0.3", "active", -1)},
+			answers:      []string{"n"},
+			wantCommands: nil,
+			wantActive:   []string{"10.0.0.1", "10.0.0.3"},
+		},
+		{
+			name:    "all down bootstraps the most advanced node first",
+			nodes:   []*fakeNode{node("10.0.0.1", "inactive", 120), node("10.0.0.2", "inactive", 180), node("10.0.0.3", "inactive", 150)},
+			answers: []string{"y", "y", "y"},
+			wantCommands: []string{
+				"10.0.0.1: systemctl start mariadb",
+				"10.0.0.2: galera_new_cluster",
+				"10.0.0.3: systemctl start mariadb",
+			},
+			wantActive: ips,
+		},
+		{
+			name:    "declined bootstrap does nothing",
+			nodes:   []*fakeNode{node("10.0.0.1", "inactive", 120), node("10.0.0.2", "inactive", 180), node("10.0.0.3", "inactive", 150)},
+			answers: []string{"n"},
+			wantErr: "user declined cluster bootstrap",
+		},
+		{
+			name:         "failed join stops the recovery",
+			nodes:        []*fakeNode{failing(node("10.0.0.1", "inactive", 120)), node("10.0.0.2", "inactive", 180), node("10.0.0.3", "inactive", 150)},
+			answers:      []string{"y", "y", "y"},
+			wantCommands: []string{"10.0.0.1: systemctl start mariadb", "10.0.0.2: galera_new_cluster"},
+			wantActive:   []string{"10.0.0.2"},
+			wantErr:      "failed to start MySQL/MariaDB on node 10.0.0.1",
+		},
+		{
+			name:         "failed bootstrap",
+			nodes:        []*fakeNode{node("10.0.0.1", "inactive", 120), failing(node("10.0.0.2", "inactive", 180)), node("10.0.0.3", "inactive", 150)},
+			answers:      []string{"y"},
+			wantCommands: []string{"10.0.0.2: galera_new_cluster"},
+			wantErr:      "failed to bootstrap node 10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := newFakeCluster(t, tt.nodes...)
+			config := newTestConfig(t)
+			answerPrompts(t, tt.answers...)
+
+			state, err := analyzeClusterState(ips, config)
+			if err != nil {
+				t.Fatalf("analyzeClusterState: %v", err)
+			}
+
+			err = performClusterRecovery(state, config)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("performClusterRecovery: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("performClusterRecovery error = %v, want %q", err, tt.wantErr)
+			}
+
+			if commands := cluster.serviceCommands(); !reflect.DeepEqual(commands, tt.wantCommands) {
+				t.Errorf("service commands = %q, want %q", commands, tt.wantCommands)
+			}
+
+			var active []string
+			for _, ip := range ips {
+				if cluster.Nodes[ip].Service == "active" {
+					active = append(active, ip)
+				}
+			}
+			if !reflect.DeepEqual(active, tt.wantActive) {
+				t.Errorf("active nodes = %v, want %v", active, tt.wantActive)
+			}
+		})
+	}
+}
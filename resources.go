@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Host resource defaults and warning thresholds
+const (
+	defaultDataDir        = "/var/lib/mysql"
+	defaultTmpDir         = "/tmp"
+	defaultGcacheName     = "galera.cache"
+	defaultGcacheSize     = 128 << 20 // gcache.size when not configured
+	defaultBufferPoolSize = 128 << 20 // innodb_buffer_pool_size when not configured
+
+	diskUsageWarnPercent     = 90 // Datadir filesystem usage
+	inodeUsageWarnPercent    = 90 // Datadir filesystem inode usage
+	memoryAvailableWarnPct   = 10 // MemAvailable below this share of MemTotal
+	swapUsageWarnPercent     = 25 // Swap used above this share of SwapTotal
+	bufferPoolMemoryWarnPct  = 80 // innodb_buffer_pool_size above this share of MemTotal
+	gcacheSizeTolerancePct   = 1  // Difference between the gcache file and gcache.size that is ignored
+	resourcesUnknown         = -1 // Value that could not be read
+	resourcesUnknownFragment = "unknown"
+
+	// du walks the whole datadir, which is too slow to repeat on every serve check
+	dataSizeCacheTTL = 15 * time.Minute
+)
+
+// dataSizeCache keeps the datadir sizes measured by du, keyed by node and datadir
+var (
+	dataSizeCache   = make(map[string]measuredDataSize)
+	dataSizeCacheMu sync.Mutex
+)
+
+// measuredDataSize is a datadir size and when it was measured
+type measuredDataSize struct {
+	size       int64
+	measuredAt time.Time
+}
+
+// HostResources are the host resources of a node that matter to Galera
+type HostResources struct {
+	DataDir          string `json:"datadir"`
+	TmpDir           string `json:"tmpdir"`
+	DataSize         int64  `json:"data_size"`         // du of the datadir
+	DataDirTotal     int64  `json:"datadir_total"`     // Size of the datadir filesystem
+	DataDirFree      int64  `json:"datadir_free"`      // Available space on the datadir filesystem
+	DataDirMount     string `json:"datadir_mount"`     // Mount point of the datadir filesystem
+	TmpDirFree       int64  `json:"tmpdir_free"`       // Available space on the tmpdir filesystem
+	TmpDirMount      string `json:"tmpdir_mount"`      // Mount point of the tmpdir filesystem
+	InodesUsedPct    int    `json:"inodes_used_pct"`   // Inode usage of the datadir filesystem
+	GcacheFile       int64  `json:"gcache_file"`       // Size of the gcache ring buffer file
+	GcacheConfigured int64  `json:"gcache_configured"` // gcache.size from wsrep_provider_options
+	MemTotal         int64  `json:"mem_total"`         // From /proc/meminfo
+	MemAvailable     int64  `json:"mem_available"`     // From /proc/meminfo
+	SwapTotal        int64  `json:"swap_total"`        // From /proc/meminfo
+	SwapFree         int64  `json:"swap_free"`         // From /proc/meminfo
+	BufferPoolSize   int64  `json:"buffer_pool_size"`  // innodb_buffer_pool_size
+}
+
+// parseSizeValue parses a MySQL-style size ("4G", "512M", "1024K", "134217728")
+func parseSizeValue(value string) (int64, error) {
+	value = strings.TrimSpace(strings.ToUpper(value))
+	value = strings.TrimSuffix(value, "B")
+	multiplier := int64(1)
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// measureDataSize returns the size in bytes of a datadir. du still prints a total when it can't read
+// some files, so a failed du is an error rather than an undersized result.
+func measureDataSize(executor NodeExecutor, dataDir string) (int64, error) {
+	output, err := runCommand(executor, "du -sb "+shellQuote(dataDir))
+	if err != nil {
+		return 0, fmt.Errorf("du %s failed: %v", dataDir, err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("du %s printed nothing", dataDir)
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected du output %q", strings.TrimSpace(output))
+	}
+	return size, nil
+}
+
+// cachedDataSize returns the datadir size of a node, measuring it again once dataSizeCacheTTL has passed
+func cachedDataSize(executor NodeExecutor, nodeIP, dataDir string) (int64, error) {
+	key := nodeIP + ":" + dataDir
+	dataSizeCacheMu.Lock()
+	cached, ok := dataSizeCache[key]
+	dataSizeCacheMu.Unlock()
+	if ok && time.Since(cached.measuredAt) < dataSizeCacheTTL {
+		return cached.size, nil
+	}
+
+	size, err := measureDataSize(executor, dataDir)
+	if err != nil {
+		return 0, err
+	}
+	dataSizeCacheMu.Lock()
+	dataSizeCache[key] = measuredDataSize{size: size, measuredAt: time.Now()}
+	dataSizeCacheMu.Unlock()
+	return size, nil
+}
+
+// collectHostResources reads the disk, gcache and memory figures of a node. Values that can't be
+// read are left at resourcesUnknown.
+func collectHostResources(executor NodeExecutor, info *GaleraClusterInfo) *HostResources {
+	r := &HostResources{
+		DataDir: info.DataDir, TmpDir: info.TmpDir,
+		DataSize: resourcesUnknown, DataDirTotal: resourcesUnknown, DataDirFree: resourcesUnknown, TmpDirFree: resourcesUnknown,
+		InodesUsedPct: resourcesUnknown, GcacheFile: resourcesUnknown,
+		MemTotal: resourcesUnknown, MemAvailable: resourcesUnknown, SwapTotal: resourcesUnknown, SwapFree: resourcesUnknown,
+		GcacheConfigured: defaultGcacheSize, BufferPoolSize: defaultBufferPoolSize,
+	}
+	if r.DataDir == "" {
+		r.DataDir = defaultDataDir
+	}
+	if r.TmpDir == "" {
+		r.TmpDir = defaultTmpDir
+	}
+
+	// Space: data size, free space on the datadir and tmpdir filesystems, inodes
+	if size, err := cachedDataSize(executor, info.NodeIP, r.DataDir); err == nil {
+		r.DataSize = size
+	} else {
+		logVerbose("      ⚠️  Could not measure the datadir size of %s: %v", info.NodeIP, err)
+	}
+	if output, err := runCommand(executor, fmt.Sprintf("df -PB1 %s %s 2>/dev/null", shellQuote(r.DataDir), shellQuote(r.TmpDir))); err == nil {
+		rows := dfRows(output)
+		if len(rows) > 0 && len(rows[0]) >= 6 {
+			r.DataDirTotal, _ = strconv.ParseInt(rows[0][1], 10, 64)
+			r.DataDirFree, _ = strconv.ParseInt(rows[0][3], 10, 64)
+			r.DataDirMount = rows[0][5]
+		}
+		if len(rows) > 1 && len(rows[1]) >= 6 {
+			r.TmpDirFree, _ = strconv.ParseInt(rows[1][3], 10, 64)
+			r.TmpDirMount = rows[1][5]
+		}
+	}
+	if output, err := runCommand(executor, fmt.Sprintf("df -Pi %s 2>/dev/null", shellQuote(r.DataDir))); err == nil {
+		if rows := dfRows(output); len(rows) > 0 && len(rows[0]) >= 5 {
+			if pct, err := strconv.Atoi(strings.TrimSuffix(rows[0][4], "%")); err == nil {
+				r.InodesUsedPct = pct
+			}
+		}
+	}
+
+	// gcache: the ring buffer file is preallocated at gcache.size when mysqld starts
+	options := parseProviderOptions(info.ProviderOptions)
+	if value := options["gcache.size"]; value != "" {
+		if size, err := parseSizeValue(value); err == nil {
+			r.GcacheConfigured = size
+		}
+	}
+	gcacheDir, gcacheName := r.DataDir, defaultGcacheName
+	if dir := options["gcache.dir"]; dir != "" {
+		gcacheDir = dir
+	}
+	if name := options["gcache.name"]; name != "" {
+		gcacheName = name
+	}
+	if !path.IsAbs(gcacheName) {
+		gcacheName = path.Join(gcacheDir, gcacheName)
+	}
+	if output, err := runCommand(executor, fmt.Sprintf("stat -c %%s %s 2>/dev/null", shellQuote(gcacheName))); err == nil {
+		if size, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64); err == nil {
+			r.GcacheFile = size
+		}
+	}
+
+	// Memory
+	if info.BufferPoolSize != "" {
+		if size, err := parseSizeValue(info.BufferPoolSize); err == nil {
+			r.BufferPoolSize = size
+		}
+	}
+	if output, err := runCommand(executor, "cat /proc/meminfo"); err == nil {
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			kib, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch fields[0] {
+			case "MemTotal:":
+				r.MemTotal = kib << 10
+			case "MemAvailable:":
+				r.MemAvailable = kib << 10
+			case "SwapTotal:":
+				r.SwapTotal = kib << 10
+			case "SwapFree:":
+				r.SwapFree = kib << 10
+			}
+		}
+	}
+	return r
+}
+
+// dfRows returns the fields of each df output line after the header
+func dfRows(output string) [][]string {
+	var rows [][]string
+	for i, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if i == 0 || strings.TrimSpace(line) == "" {
+			continue
+		}
+		rows = append(rows, strings.Fields(line))
+	}
+	return rows
+}
+
+// percent returns part as a percentage of total
+func percent(part, total int64) int {
+	if total <= 0 {
+		return 0
+	}
+	return int(part * 100 / total)
+}
+
+// warnings returns the threshold-based warnings for the node's resources
+func (r *HostResources) warnings(nodeIP string) []string {
+	if r == nil {
+		return nil
+	}
+	var warnings []string
+	add := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf("%s: ", nodeIP)+fmt.Sprintf(format, args...))
+	}
+
+	if r.DataDirTotal > 0 && r.DataDirFree >= 0 {
+		if used := 100 - percent(r.DataDirFree, r.DataDirTotal); used >= diskUsageWarnPercent {
+			add("datadir filesystem %s is %d%% full (%s free)", r.DataDirMount, used, formatBytes(r.DataDirFree))
+		}
+	}
+	if r.DataSize > 0 && r.DataDirFree >= 0 && r.DataDirFree < r.DataSize {
+		add("%s free on the datadir, less than the %s of data: an SST staging a copy would run out of disk",
+			formatBytes(r.DataDirFree), formatBytes(r.DataSize))
+	}
+	if r.DataSize > 0 && r.TmpDirFree >= 0 && r.TmpDirMount != "" && r.TmpDirMount != r.DataDirMount && r.TmpDirFree < r.DataSize {
+		add("%s free on tmpdir %s, less than the %s of data used by SST", formatBytes(r.TmpDirFree), r.TmpDir, formatBytes(r.DataSize))
+	}
+	if r.InodesUsedPct >= inodeUsageWarnPercent {
+		add("datadir filesystem uses %d%% of its inodes", r.InodesUsedPct)
+	}
+
+	if r.GcacheFile > 0 && r.GcacheConfigured > 0 {
+		difference := r.GcacheFile - r.GcacheConfigured
+		if percent(absInt64(difference), r.GcacheConfigured) >= gcacheSizeTolerancePct {
+			add("gcache file is %s but gcache.size is %s (takes effect at the next restart)", formatBytes(r.GcacheFile), formatBytes(r.GcacheConfigured))
+		}
+	}
+
+	if r.MemTotal > 0 {
+		if r.BufferPoolSize > 0 && percent(r.BufferPoolSize, r.MemTotal) > bufferPoolMemoryWarnPct {
+			add("innodb_buffer_pool_size %s is %d%% of the %s of memory", formatBytes(r.BufferPoolSize), percent(r.BufferPoolSize, r.MemTotal), formatBytes(r.MemTotal))
+		}
+		if r.MemAvailable >= 0 && percent(r.MemAvailable, r.MemTotal) < memoryAvailableWarnPct {
+			add("only %s of %s memory available", formatBytes(r.MemAvailable), formatBytes(r.MemTotal))
+		}
+	}
+	if r.SwapTotal > 0 && r.SwapFree >= 0 {
+		if used := percent(r.SwapTotal-r.SwapFree, r.SwapTotal); used > swapUsageWarnPercent {
+			add("%d%% of swap in use (%s)", used, formatBytes(r.SwapTotal-r.SwapFree))
+		}
+	}
+	return warnings
+}
+
+// describeDisk returns the disk figures for display
+func (r *HostResources) describeDisk() string {
+	if r.DataDirTotal < 0 || r.DataDirFree < 0 {
+		return fmt.Sprintf("datadir %s %s", r.DataDir, resourcesUnknownFragment)
+	}
+	description := fmt.Sprintf("datadir %s %s free of %s", r.DataDir, formatBytes(r.DataDirFree), formatBytes(r.DataDirTotal))
+	if r.DataSize >= 0 {
+		description += ", data " + formatBytes(r.DataSize)
+	}
+	if r.InodesUsedPct >= 0 {
+		description += fmt.Sprintf(", inodes %d%%", r.InodesUsedPct)
+	}
+	if r.GcacheFile >= 0 {
+		description += ", gcache " + formatBytes(r.GcacheFile)
+	}
+	return description
+}
+
+// describeMemory returns the memory figures for display
+func (r *HostResources) describeMemory() string {
+	if r.MemTotal < 0 {
+		return resourcesUnknownFragment
+	}
+	description := fmt.Sprintf("%s available of %s, buffer pool %s", formatBytes(r.MemAvailable), formatBytes(r.MemTotal), formatBytes(r.BufferPoolSize))
+	if r.SwapTotal > 0 {
+		description += fmt.Sprintf(", swap %s used", formatBytes(r.SwapTotal-r.SwapFree))
+	}
+	return description
+}
+
+// absInt64 returns the absolute value of n
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
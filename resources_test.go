@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSizeValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"134217728", 134217728},
+		{"512M", 512 << 20},
+		{"4G", 4 << 30},
+		{"2gb", 2 << 30},
+		{"1.5G", 3 << 29},
+		{"1024K", 1 << 20},
+	}
+	for _, tt := range tests {
+		if got, err := parseSizeValue(tt.value); err != nil || got != tt.want {
+			t.Errorf("parseSizeValue(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := parseSizeValue("lots"); err == nil {
+		t.Error("parseSizeValue(\"lots\") succeeded")
+	}
+}
+
+func TestHostResourceWarnings(t *testing.T) {
+	members := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip string) *fakeNode {
+		return &fakeNode{IP: ip, Service: "active",
+			Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...)}}
+	}
+	first, full, starved := node("10.0.0.1"), node("10.0.0.2"), node("10.0.0.3")
+	first.Files["/etc/mysql/my.cnf"] += "innodb_buffer_pool_size = 14G\n"
+	full.DiskFree = 50 << 20
+	full.InodesUsed = 93
+	starved.MemFree = 1 << 30
+	starved.SwapUsed = 1 << 30
+	starved.GcacheFile = 64 << 20
+	newFakeCluster(t, first, full, starved)
+	config := newTestConfig(t)
+
+	executor, err := getNodeExecutor("10.0.0.1", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialNode, err := getGaleraClusterInfo(executor, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, _, err := performClusterAnalysis(initialNode, &SSHConnectionInfo{Username: "root", UsedKeys: true}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := analysis.AllNodes[0].Resources
+	if resources == nil || resources.DataSize != 104857600 || resources.BufferPoolSize != 14<<30 || resources.GcacheFile != 128<<20 {
+		t.Fatalf("resources of 10.0.0.1 = %+v", resources)
+	}
+
+	health := evaluateClusterHealth(analysis)
+	assertErrors(t, health.Warnings, []string{
+		"10.0.0.1: innodb_buffer_pool_size 14",
+		"10.0.0.2: datadir filesystem / is 100% full",
+		"10.0.0.2: 50.0 MiB free on the datadir, less than the 100.0 MiB of data",
+		"10.0.0.2: datadir filesystem uses 93% of its inodes",
+		"10.0.0.3: only 1.0 GiB of 16.0 GiB memory available",
+		"10.0.0.3: 50% of swap in use",
+		"10.0.0.3: gcache file is 64.0 MiB but gcache.size is 128.0 MiB",
+	})
+	for _, warning := range health.Warnings {
+		if strings.HasPrefix(warning, "10.0.0.1: datadir") || strings.HasPrefix(warning, "10.0.0.1: gcache") {
+			t.Errorf("unexpected warning: %s", warning)
+		}
+	}
+}
+
+func TestDataSizeMeasurement(t *testing.T) {
+	readable, denied := &fakeNode{IP: "10.0.0.1", DataSize: 1 << 30}, &fakeNode{IP: "10.0.0.2", DataDenied: true}
+	newFakeCluster(t, readable, denied)
+	config := newTestConfig(t)
+	collect := func(ip string) *HostResources {
+		t.Helper()
+		executor, err := getNodeExecutor(ip, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return collectHostResources(executor, &GaleraClusterInfo{NodeIP: ip})
+	}
+
+	// A du that could not read everything leaves the size unknown instead of reporting a partial total
+	if got := collect("10.0.0.2").DataSize; got != resourcesUnknown {
+		t.Errorf("size of an unreadable datadir = %d", got)
+	}
+
+	// The size is measured once and reused by later checks
+	if got := collect("10.0.0.1").DataSize; got != 1<<30 {
+		t.Errorf("datadir size = %d", got)
+	}
+	readable.DataSize = 2 << 30
+	if got := collect("10.0.0.1").DataSize; got != 1<<30 {
+		t.Errorf("datadir size was measured again: %d", got)
+	}
+	executor, _ := getNodeExecutor("10.0.0.1", config)
+	if size, err := measureDataSize(executor, defaultDataDir); err != nil || size != 2<<30 {
+		t.Errorf("measureDataSize = %d, %v", size, err)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RollingRestartNode holds the pre-flight information about a node taking part in a rolling restart
+type RollingRestartNode struct {
+	IP       string
+	NodeName string
+	Status   *NodeSyncStatus
+	IsDonor  bool // Named in another node's wsrep_sst_donor
+}
+
+// queryNodeVariable reads a single global variable from a node
+func queryNodeVariable(ip, name string, config *Config) string {
+	mysqlCmd := buildMySQLClientCommand(getRecoveryMySQLCredentials(ip, config))
+	query := fmt.Sprintf("%s -N -B -e \"SHOW GLOBAL VARIABLES LIKE '%s';\" 2>/dev/null", mysqlCmd, name)
+	output, err := executeCommandOnNode(ip, query, config)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) >= 2 && parts[0] == name {
+			return strings.Join(parts[1:], " ")
+		}
+	}
+	return ""
+}
+
+// quorumSafeWithout reports whether the cluster keeps quorum while one of its members is down
+func quorumSafeWithout(clusterSize int) bool {
+	// The remaining members must be a strict majority of the current component
+	return clusterSize-1 > clusterSize/2
+}
+
+// orderRollingRestart returns the restart order: explicit order first, then the configured
+// order, with the nodes named in another node's wsrep_sst_donor moved to the end
+func orderRollingRestart(nodes []*RollingRestartNode, explicitOrder []string) ([]*RollingRestartNode, error) {
+	byIP := make(map[string]*RollingRestartNode)
+	for _, node := range nodes {
+		byIP[node.IP] = node
+	}
+
+	var ordered []*RollingRestartNode
+	if len(explicitOrder) > 0 {
+		seen := make(map[string]bool)
+		for _, ip := range explicitOrder {
+			node, ok := byIP[ip]
+			if !ok {
+				return nil, fmt.Errorf("node %s in --order is not part of the cluster", ip)
+			}
+			if seen[ip] {
+				return nil, fmt.Errorf("node %s appears more than once in --order", ip)
+			}
+			seen[ip] = true
+			ordered = append(ordered, node)
+		}
+		if len(ordered) != len(nodes) {
+			return nil, fmt.Errorf("--order lists %d nodes but the cluster has %d", len(ordered), len(nodes))
+		}
+		return ordered, nil
+	}
+
+	var donors []*RollingRestartNode
+	for _, node := range nodes {
+		if node.IsDonor {
+			donors = append(donors, node)
+		} else {
+			ordered = append(ordered, node)
+		}
+	}
+	return append(ordered, donors...), nil
+}
+
+// runRollingRestart restarts every cluster node one at a time, waiting for each to rejoin and sync
+func runRollingRestart(config *Config, explicitOrder []string) error {
+	clusterIPs, err := discoverClusterNodes(config)
+	if err != nil {
+		return err
+	}
+
+	clusterLock, err := acquireClusterLock(clusterIPs, "rolling-restart", config)
+	if err != nil {
+		return err
+	}
+	defer clusterLock.release(config)
+
+	logMinimal("")
+	logMinimal("🔍 Checking that all %d nodes are Synced before starting...", len(clusterIPs))
+
+	// Pre-flight: every node must be Synced in a Primary component that contains all nodes
+	var nodes []*RollingRestartNode
+	var problems []string
+	for _, ip := range clusterIPs {
+		status := queryNodeSyncStatus(ip, config)
+		node := &RollingRestartNode{
+			IP:       ip,
+			NodeName: queryNodeVariable(ip, "wsrep_node_name", config),
+			Status:   status,
+		}
+		nodes = append(nodes, node)
+
+		if !status.isSynced(len(clusterIPs)) {
+			problems = append(problems, fmt.Sprintf("%s: service=%s state=%s cluster_size=%d status=%s",
+				ip, status.ServiceState, status.LocalStateComment, status.ClusterSize, status.ClusterStatus))
+			logMinimal("   ❌ %s is not ready (%s, size %d)", ip, describeTransferState(status), status.ClusterSize)
+		} else {
+			logMinimal("   ✅ %s Synced (size %d)", ip, status.ClusterSize)
+		}
+	}
+
+	if len(problems) > 0 {
+		logReport("❌ Rolling restart refused: the cluster is not fully Synced")
+		for _, problem := range problems {
+			logReport("   - %s", problem)
+		}
+		return fmt.Errorf("cluster is not healthy, refusing to restart nodes")
+	}
+
+	// Nodes named as preferred SST donors by other nodes are restarted last. A node that is
+	// currently a donor is not Synced, so the pre-flight has already refused the restart.
+	for _, node := range nodes {
+		preferred := queryNodeVariable(node.IP, "wsrep_sst_donor", config)
+		for _, name := range strings.Split(preferred, ",") {
+			name = strings.TrimSpace(name)
+			for _, other := range nodes {
+				if name != "" && other != node && other.NodeName == name {
+					other.IsDonor = true
+				}
+			}
+		}
+	}
+
+	if !quorumSafeWithout(len(nodes)) {
+		logReport("❌ Rolling restart refused: a %d-node cluster loses quorum when one node is down", len(nodes))
+		return fmt.Errorf("quorum would be at risk")
+	}
+
+	ordered, err := orderRollingRestart(nodes, explicitOrder)
+	if err != nil {
+		return err
+	}
+
+	logReport("")
+	logReport("📋 Rolling restart order:")
+	for i, node := range ordered {
+		suffix := ""
+		if node.IsDonor {
+			suffix = " (preferred donor - restarted last)"
+		}
+		logReport("   %d. %s%s", i+1, node.IP, suffix)
+	}
+	logReport("")
+
+	var completed []string
+	for i, node := range ordered {
+		// Re-check quorum right before each restart: every other node must still be Synced
+		for _, other := range ordered {
+			if other == node {
+				continue
+			}
+			status := queryNodeSyncStatus(other.IP, config)
+			if !status.isSynced(len(ordered)) {
+				logReport("❌ Stopping rolling restart: node %s is not Synced (%s, size %d)",
+					other.IP, describeTransferState(status), status.ClusterSize)
+				reportRollingRestartProgress(completed, ordered[i:])
+				return fmt.Errorf("quorum would be at risk while restarting %s", node.IP)
+			}
+		}
+
+		if !askUserPermission(fmt.Sprintf("Restart MySQL/MariaDB on node %s (%d/%d)", node.IP, i+1, len(ordered))) {
+			logReport("⏹️ Rolling restart stopped by user before node %s", node.IP)
+			reportRollingRestartProgress(completed, ordered[i:])
+			return nil
+		}
+
+		logReport("🔄 Restarting MySQL/MariaDB on node %s...", node.IP)
+		evidence := rollingRestartEvidence(ordered, i)
+		if err := stopMySQLService(node.IP, evidence, config); err != nil {
+			reportRollingRestartProgress(completed, ordered[i:])
+			return fmt.Errorf("failed to stop MySQL/MariaDB on node %s: %v", node.IP, err)
+		}
+		if err := startMySQLService(node.IP, evidence, config); err != nil {
+			reportJoinFailure(node.IP, nil, err, completed, rollingRestartIPs(ordered[i+1:]), config)
+			return fmt.Errorf("failed to start MySQL/MariaDB on node %s: %v", node.IP, err)
+		}
+
+		// Any other node can act as donor; use the first one for progress reporting
+		donorIP := ordered[0].IP
+		if donorIP == node.IP && len(ordered) > 1 {
+			donorIP = ordered[1].IP
+		}
+
+		status, err := waitForNodeSynced(node.IP, len(ordered), getJoinTimeout(config), donorIP, config)
+		if err != nil {
+			reportJoinFailure(node.IP, status, err, completed, rollingRestartIPs(ordered[i+1:]), config)
+			return fmt.Errorf("node %s did not rejoin the cluster: %v", node.IP, err)
+		}
+
+		completed = append(completed, node.IP)
+	}
+
+	logReport("")
+	logReport("🎉 Rolling restart completed: %d/%d nodes restarted and Synced", len(completed), len(ordered))
+	return nil
+}
+
+// rollingRestartIPs returns the IPs of the given nodes
+func rollingRestartIPs(nodes []*RollingRestartNode) []string {
+	var ips []string
+	for _, node := range nodes {
+		ips = append(ips, node.IP)
+	}
+	return ips
+}
+
+// reportRollingRestartProgress prints which nodes were restarted and which were not
+func reportRollingRestartProgress(completed []string, pending []*RollingRestartNode) {
+	if len(completed) > 0 {
+		logReport("   Nodes restarted and Synced: %s", strings.Join(completed, ", "))
+	}
+	if len(pending) > 0 {
+		logReport("   Nodes NOT restarted: %s", strings.Join(rollingRestartIPs(pending), ", "))
+	}
+}
+
+// rollingRestartEvidence records the pre-flight state that allowed a node to be restarted
+func rollingRestartEvidence(ordered []*RollingRestartNode, index int) map[string]string {
+	evidence := map[string]string{
+		"reason":   "rolling-restart",
+		"position": fmt.Sprintf("%d/%d", index+1, len(ordered)),
+	}
+	for _, node := range ordered {
+		if node.Status != nil {
+			evidence["state."+node.IP] = fmt.Sprintf("%s size=%d", node.Status.LocalStateComment, node.Status.ClusterSize)
+		}
+	}
+	return evidence
+}
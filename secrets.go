@@ -0,0 +1,628 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// Supported secrets backends
+const (
+	SecretsBackendLegacy     = "legacy"     // Key derived from the node IP (no secret, only read to migrate old configs)
+	SecretsBackendPassphrase = "passphrase" // Key derived from a master passphrase with Argon2id or scrypt
+	SecretsBackendKeyring    = "keyring"    // Random key stored in the Secret Service or kernel keyring
+	SecretsBackendEnv        = "env"        // Key read from GALERAHEALTH_SECRET_KEY
+	SecretsBackendFile       = "file"       // Key read from a key file
+)
+
+// Supported key derivation functions for the passphrase backend
+const (
+	KDFArgon2id = "argon2id"
+	KDFScrypt   = "scrypt"
+)
+
+const (
+	secretsPrefix         = "v2:"                    // Marks values encrypted with the master key
+	secretsKeyCheckValue  = "galerahealth-key-check" // Encrypted into SecretsKeyCheck to detect a wrong key
+	secretsKeyringService = "galerahealth"
+	secretsKeyringName    = "galerahealth:master"
+	secretKeyEnv          = "GALERAHEALTH_SECRET_KEY"
+	passphraseEnv         = "GALERAHEALTH_PASSPHRASE"
+)
+
+// SecretsKDF holds the parameters used to derive the master key from the passphrase
+type SecretsKDF struct {
+	Algorithm string `json:"algorithm" yaml:"algorithm"`                       // argon2id or scrypt
+	Salt      string `json:"salt" yaml:"salt"`                                 // Base64 random salt
+	Time      uint32 `json:"time,omitempty" yaml:"time,omitempty"`             // Argon2id passes
+	MemoryKiB uint32 `json:"memory_kib,omitempty" yaml:"memory_kib,omitempty"` // Argon2id memory
+	Threads   uint8  `json:"threads,omitempty" yaml:"threads,omitempty"`       // Argon2id parallelism
+	N         int    `json:"n,omitempty" yaml:"n,omitempty"`                   // scrypt cost
+	R         int    `json:"r,omitempty" yaml:"r,omitempty"`                   // scrypt block size
+	P         int    `json:"p,omitempty" yaml:"p,omitempty"`                   // scrypt parallelism
+}
+
+// sessionMasterKey caches the master key for the rest of the run once it has been unlocked
+var sessionMasterKey []byte
+
+// newSecretsKDF returns default parameters with a fresh salt for the given algorithm
+func newSecretsKDF(algorithm string) (*SecretsKDF, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %v", err)
+	}
+	kdf := &SecretsKDF{Algorithm: algorithm, Salt: base64.StdEncoding.EncodeToString(salt)}
+	switch algorithm {
+	case KDFArgon2id:
+		kdf.Time, kdf.MemoryKiB, kdf.Threads = 3, 64*1024, 4
+	case KDFScrypt:
+		kdf.N, kdf.R, kdf.P = 32768, 8, 1
+	default:
+		return nil, fmt.Errorf("unknown key derivation function %q (use %s or %s)", algorithm, KDFArgon2id, KDFScrypt)
+	}
+	return kdf, nil
+}
+
+// deriveKey derives a 32-byte key from the passphrase
+func (k *SecretsKDF) deriveKey(passphrase string) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(k.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %v", err)
+	}
+	switch k.Algorithm {
+	case KDFArgon2id:
+		return argon2.IDKey([]byte(passphrase), salt, k.Time, k.MemoryKiB, k.Threads, 32), nil
+	case KDFScrypt:
+		return scrypt.Key([]byte(passphrase), salt, k.N, k.R, k.P, 32)
+	default:
+		return nil, fmt.Errorf("unknown key derivation function %q", k.Algorithm)
+	}
+}
+
+// getSecretsKeyFile returns the key file used by the file backend
+func (c *Config) getSecretsKeyFile() string {
+	if c.SecretsKeyFile != "" {
+		return c.SecretsKeyFile
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".galerahealth.key")
+}
+
+// masterKey returns the master key of the configured backend, unlocking it once per run
+func (c *Config) masterKey() ([]byte, error) {
+	if sessionMasterKey != nil {
+		return sessionMasterKey, nil
+	}
+
+	var key []byte
+	var err error
+	switch c.SecretsBackend {
+	case SecretsBackendPassphrase:
+		if c.SecretsKDF == nil {
+			return nil, fmt.Errorf("passphrase backend configured without key derivation parameters")
+		}
+		var passphrase string
+		if passphrase, err = readPassphrase("Enter galerahealth master passphrase: "); err == nil {
+			key, err = c.SecretsKDF.deriveKey(passphrase)
+		}
+	case SecretsBackendKeyring:
+		key, err = loadKeyringKey()
+	case SecretsBackendEnv:
+		key, err = decodeSecretKey(os.Getenv(secretKeyEnv), secretKeyEnv)
+	case SecretsBackendFile:
+		key, err = readSecretKeyFile(c.getSecretsKeyFile())
+	case "", SecretsBackendLegacy:
+		return nil, noSecretsBackendError()
+	default:
+		return nil, fmt.Errorf("unknown secrets backend %q", c.SecretsBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.SecretsKeyCheck != "" {
+		if _, err := openSecret(key, c.SecretsKeyCheck, ""); err != nil {
+			return nil, fmt.Errorf("wrong master passphrase or key for the %s secrets backend", c.SecretsBackend)
+		}
+	}
+
+	sessionMasterKey = key
+	return key, nil
+}
+
+// encryptSecret encrypts a password for a node with the configured secrets backend
+func (c *Config) encryptSecret(plaintext, nodeIP string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if c.SecretsBackend == "" || c.SecretsBackend == SecretsBackendLegacy {
+		// New passwords are never saved with the legacy key: the user picks a backend, and the
+		// existing entries are moved to it first
+		backend, err := chooseSecretsBackend()
+		if err != nil {
+			return "", err
+		}
+		if err := c.migrateSecrets(backend, KDFArgon2id, ""); err != nil {
+			return "", fmt.Errorf("could not set up the %s secrets backend: %v", backend, err)
+		}
+		logMinimal("🔐 Saved passwords are now encrypted with the %s secrets backend", backend)
+	}
+	key, err := c.masterKey()
+	if err != nil {
+		return "", err
+	}
+	return sealSecret(key, plaintext, nodeIP)
+}
+
+// noSecretsBackendError explains how to set up a backend before passwords can be saved
+func noSecretsBackendError() error {
+	return fmt.Errorf("no secrets backend configured, passwords are not saved until one is set up " +
+		"(run: galerahealth secrets migrate --backend passphrase|keyring|env|file)")
+}
+
+// chooseSecretsBackend asks which backend protects the first saved password. None is picked
+// implicitly: -y runs and an empty answer leave the password unsaved.
+func chooseSecretsBackend() (string, error) {
+	if useDefaults {
+		return "", noSecretsBackendError()
+	}
+	logMinimal("🔐 Saved passwords are encrypted with a master key kept out of the configuration file")
+	answer := promptForInputWithDefault("Protect it with a passphrase or the keyring? (passphrase/keyring, empty to not save)", "")
+	switch backend := strings.ToLower(strings.TrimSpace(answer)); backend {
+	case SecretsBackendPassphrase, SecretsBackendKeyring:
+		return backend, nil
+	default:
+		return "", noSecretsBackendError()
+	}
+}
+
+// decryptSecret decrypts a saved password; values saved before a backend was configured are
+// still read with the legacy scheme until they are migrated
+func (c *Config) decryptSecret(value, nodeIP string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(value, secretsPrefix) {
+		return decryptPassword(value, nodeIP)
+	}
+	key, err := c.masterKey()
+	if err != nil {
+		return "", err
+	}
+	return openSecret(key, value, nodeIP)
+}
+
+// sealSecret encrypts plaintext with AES-256-GCM; the node IP is bound as additional data so a
+// value cannot be copied to another node's entry
+func sealSecret(key []byte, plaintext, nodeIP string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(nodeIP))
+	return secretsPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// openSecret decrypts a value produced by sealSecret
+func openSecret(key []byte, value, nodeIP string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretsPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64: %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(nodeIP))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// newGCM creates an AES-GCM cipher for a 32-byte key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %v", err)
+	}
+	return gcm, nil
+}
+
+// generateSecretKey returns a new random 32-byte key
+func generateSecretKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	return key, nil
+}
+
+// decodeSecretKey decodes a base64 32-byte key
+func decodeSecretKey(encoded, source string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, fmt.Errorf("no key found in %s (generate one with: galerahealth secrets generate-key)", source)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s must contain a base64-encoded 32-byte key", source)
+	}
+	return key, nil
+}
+
+// readSecretKeyFile reads the key of the file backend, refusing files readable by other users
+func readSecretKeyFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file: %v", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("key file %s is accessible by other users, run: chmod 600 %s", path, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file: %v", err)
+	}
+	return decodeSecretKey(string(data), path)
+}
+
+// writeSecretKeyFile creates the key file of the file backend
+func writeSecretKeyFile(path string, key []byte) error {
+	data := base64.StdEncoding.EncodeToString(key) + "\n"
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create key file: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		return fmt.Errorf("could not write key file: %v", err)
+	}
+	return nil
+}
+
+// readPassphrase reads the master passphrase from GALERAHEALTH_PASSPHRASE or the terminal
+func readPassphrase(prompt string) (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if useDefaults || !term.IsTerminal(int(syscall.Stdin)) {
+		return "", fmt.Errorf("master passphrase required: set %s for non-interactive runs", passphraseEnv)
+	}
+	fmt.Print(prompt)
+	passphrase, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase: %v", err)
+	}
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("empty master passphrase")
+	}
+	return string(passphrase), nil
+}
+
+// loadKeyringKey reads the master key from the Secret Service, falling back to the kernel keyring
+func loadKeyringKey() ([]byte, error) {
+	local := &LocalExecutor{}
+	lookup := fmt.Sprintf("secret-tool lookup service %s key master 2>/dev/null", secretsKeyringService)
+	if output, err := runCommand(local, lookup); err == nil && strings.TrimSpace(output) != "" {
+		return decodeSecretKey(output, "Secret Service")
+	}
+	search := fmt.Sprintf("keyctl pipe $(keyctl search $(keyctl get_persistent @u) user %s 2>/dev/null) 2>/dev/null", secretsKeyringName)
+	if output, err := runCommand(local, search); err == nil && strings.TrimSpace(output) != "" {
+		return decodeSecretKey(output, "kernel keyring")
+	}
+	return nil, fmt.Errorf("no galerahealth key found in the Secret Service or kernel keyring (needs secret-tool or keyctl)")
+}
+
+// storeKeyringKey saves the master key in the Secret Service, falling back to the kernel keyring
+func storeKeyringKey(key []byte) error {
+	local := &LocalExecutor{}
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	store := fmt.Sprintf("command -v secret-tool >/dev/null && secret-tool store --label='galerahealth master key' service %s key master", secretsKeyringService)
+	if result, err := local.Run(context.Background(), store, strings.NewReader(encoded)); err == nil && result.ExitCode == 0 {
+		logNormal("🔑 Master key stored in the Secret Service")
+		return nil
+	}
+
+	padd := fmt.Sprintf("command -v keyctl >/dev/null && keyctl padd user %s $(keyctl get_persistent @u)", secretsKeyringName)
+	if result, err := local.Run(context.Background(), padd, strings.NewReader(encoded)); err == nil && result.ExitCode == 0 {
+		logNormal("🔑 Master key stored in the persistent kernel keyring")
+		return nil
+	}
+
+	return fmt.Errorf("could not store the key: neither secret-tool nor keyctl is available")
+}
+
+// newMasterKey sets up the key for a backend and returns it
+func (c *Config) newMasterKey(backend, kdfAlgorithm string) ([]byte, *SecretsKDF, error) {
+	switch backend {
+	case SecretsBackendLegacy:
+		return nil, nil, fmt.Errorf("the legacy backend can only read old entries, choose %s, %s, %s or %s",
+			SecretsBackendPassphrase, SecretsBackendKeyring, SecretsBackendEnv, SecretsBackendFile)
+	case SecretsBackendPassphrase:
+		kdf, err := newSecretsKDF(kdfAlgorithm)
+		if err != nil {
+			return nil, nil, err
+		}
+		passphrase, err := readPassphrase("Enter new master passphrase: ")
+		if err != nil {
+			return nil, nil, err
+		}
+		if os.Getenv(passphraseEnv) == "" {
+			confirm, err := readPassphrase("Confirm master passphrase: ")
+			if err != nil {
+				return nil, nil, err
+			}
+			if confirm != passphrase {
+				return nil, nil, fmt.Errorf("passphrases do not match")
+			}
+		}
+		key, err := kdf.deriveKey(passphrase)
+		return key, kdf, err
+	case SecretsBackendKeyring:
+		if key, err := loadKeyringKey(); err == nil {
+			return key, nil, nil
+		}
+		key, err := generateSecretKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, nil, storeKeyringKey(key)
+	case SecretsBackendEnv:
+		key, err := decodeSecretKey(os.Getenv(secretKeyEnv), secretKeyEnv)
+		return key, nil, err
+	case SecretsBackendFile:
+		path := c.getSecretsKeyFile()
+		if _, err := os.Stat(path); err == nil {
+			key, err := readSecretKeyFile(path)
+			return key, nil, err
+		}
+		key, err := generateSecretKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := writeSecretKeyFile(path, key); err != nil {
+			return nil, nil, err
+		}
+		logMinimal("🔑 Master key written to %s; anyone who can read it can decrypt the saved passwords", path)
+		return key, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown secrets backend %q (use %s, %s, %s or %s)", backend,
+			SecretsBackendPassphrase, SecretsBackendKeyring, SecretsBackendEnv, SecretsBackendFile)
+	}
+}
+
+// migrateSecrets re-encrypts every saved password with a new secrets backend. Nothing is changed
+// if any password cannot be decrypted or the new key cannot be set up.
+func (c *Config) migrateSecrets(backend, kdfAlgorithm, keyFile string) error {
+	// Decrypt everything with the current backend first
+	type secret struct {
+		target    *string
+		nodeIP    string
+		plaintext string
+	}
+	var secrets []secret
+	for _, scope := range c.secretScopes() {
+		for i := range scope.nodeCredentials {
+			creds := &scope.nodeCredentials[i]
+			for _, target := range []*string{&creds.EncryptedSSHPassword, &creds.EncryptedMySQLPassword} {
+				if *target == "" {
+					continue
+				}
+				plaintext, err := c.decryptSecret(*target, creds.NodeIP)
+				if err != nil {
+					return fmt.Errorf("could not decrypt saved password for node %s: %v", creds.NodeIP, err)
+				}
+				secrets = append(secrets, secret{target, creds.NodeIP, plaintext})
+			}
+		}
+		if *scope.hasGlobalPassword && *scope.globalPassword != "" {
+			// The deprecated global password was encrypted for the node it was entered for
+			if plaintext, err := c.decryptSecret(*scope.globalPassword, scope.globalNodeIP); err == nil {
+				secrets = append(secrets, secret{scope.globalPassword, scope.globalNodeIP, plaintext})
+			} else {
+				logNormal("⚠️  Could not decrypt the deprecated global MySQL password, it will be dropped: %v", err)
+				*scope.globalPassword = ""
+				*scope.hasGlobalPassword = false
+			}
+		}
+	}
+
+	// Set up the new key, then re-encrypt into a copy so a failure leaves the config untouched
+	previousKeyFile := c.SecretsKeyFile
+	if keyFile != "" {
+		c.SecretsKeyFile = keyFile
+	}
+	key, kdf, err := c.newMasterKey(backend, kdfAlgorithm)
+	if err != nil {
+		c.SecretsKeyFile = previousKeyFile
+		return err
+	}
+	keyCheck, err := sealSecret(key, secretsKeyCheckValue, "")
+	if err != nil {
+		c.SecretsKeyFile = previousKeyFile
+		return err
+	}
+	reencrypted := make([]string, len(secrets))
+	for i, s := range secrets {
+		if reencrypted[i], err = sealSecret(key, s.plaintext, s.nodeIP); err != nil {
+			c.SecretsKeyFile = previousKeyFile
+			return fmt.Errorf("could not encrypt password for node %s: %v", s.nodeIP, err)
+		}
+	}
+
+	for i, s := range secrets {
+		*s.target = reencrypted[i]
+	}
+	c.SecretsBackend = backend
+	c.SecretsKDF = kdf
+	c.SecretsKeyCheck = keyCheck
+	sessionMasterKey = key
+
+	if len(secrets) > 0 {
+		logMinimal("✅ Re-encrypted %d saved passwords with the %s secrets backend", len(secrets), backend)
+	}
+	return nil
+}
+
+// resolveSecretRef reads a password referenced from the configuration file as env:NAME or file:/path
+func resolveSecretRef(ref string) (string, error) {
+	kind, target, ok := strings.Cut(ref, ":")
+	if !ok || target == "" {
+		return "", fmt.Errorf("invalid password reference %q (use env:NAME or file:/path)", ref)
+	}
+
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(target)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s referenced by the configuration is not set", target)
+		}
+		return value, nil
+	case "file":
+		data, err := os.ReadFile(target)
+		if err != nil {
+			return "", fmt.Errorf("could not read password file: %v", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("invalid password reference %q (use env:NAME or file:/path)", ref)
+	}
+}
+
+// countLegacySecrets returns the number of saved passwords still using the node IP derived key
+func (c *Config) countLegacySecrets() int {
+	count := 0
+	for _, scope := range c.secretScopes() {
+		for _, creds := range scope.nodeCredentials {
+			for _, value := range []string{creds.EncryptedSSHPassword, creds.EncryptedMySQLPassword} {
+				if value != "" && !strings.HasPrefix(value, secretsPrefix) {
+					count++
+				}
+			}
+		}
+		if *scope.hasGlobalPassword && *scope.globalPassword != "" && !strings.HasPrefix(*scope.globalPassword, secretsPrefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// secretScope is a set of saved passwords: the top-level settings or one cluster profile
+type secretScope struct {
+	nodeCredentials   []NodeCredentials
+	globalPassword    *string
+	hasGlobalPassword *bool
+	globalNodeIP      string
+}
+
+// secretScopes returns every set of saved passwords in the config
+func (c *Config) secretScopes() []secretScope {
+	scopes := []secretScope{{c.NodeCredentials, &c.EncryptedMySQLPassword, &c.HasSavedPassword, c.LastNodeIP}}
+	for i := range c.Clusters {
+		profile := &c.Clusters[i]
+		globalNodeIP := ""
+		if len(profile.SeedNodes) > 0 {
+			globalNodeIP = profile.SeedNodes[0]
+		}
+		scopes = append(scopes, secretScope{profile.NodeCredentials, &profile.EncryptedMySQLPassword, &profile.HasSavedPassword, globalNodeIP})
+	}
+	return scopes
+}
+
+// runSecretsCommand implements the "secrets" subcommand
+func runSecretsCommand(args []string) error {
+	if len(args) == 0 {
+		args = []string{"status"}
+	}
+
+	switch args[0] {
+	case "status":
+		config := loadConfig()
+		if config.SecretsBackend == "" || config.SecretsBackend == SecretsBackendLegacy {
+			logMinimal("🔐 Secrets backend: none (chosen when the first password is saved, or with secrets migrate)")
+		} else {
+			logMinimal("🔐 Secrets backend: %s", config.SecretsBackend)
+		}
+		if config.SecretsKDF != nil {
+			logMinimal("   Key derivation: %s", config.SecretsKDF.Algorithm)
+		}
+		if config.SecretsBackend == SecretsBackendFile {
+			logMinimal("   Key file: %s", config.getSecretsKeyFile())
+		}
+		if legacy := config.countLegacySecrets(); legacy > 0 {
+			logMinimal("⚠️  %d saved passwords use the legacy node IP derived key, anyone who can read the config can decrypt them", legacy)
+			logMinimal("   Run: galerahealth secrets migrate --backend passphrase|keyring|env|file")
+		}
+		return nil
+
+	case "generate-key":
+		key, err := generateSecretKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return nil
+
+	case "migrate":
+		backend := ""
+		keyFile := ""
+		kdfAlgorithm := KDFArgon2id
+		for i := 1; i < len(args); i++ {
+			switch {
+			case args[i] == "--backend" && i+1 < len(args):
+				i++
+				backend = args[i]
+			case args[i] == "--kdf" && i+1 < len(args):
+				i++
+				kdfAlgorithm = args[i]
+			case args[i] == "--key-file" && i+1 < len(args):
+				i++
+				keyFile = args[i]
+			default:
+				return fmt.Errorf("unknown secrets migrate option: %s", args[i])
+			}
+		}
+		if backend == "" {
+			return fmt.Errorf("--backend is required (%s, %s, %s or %s)",
+				SecretsBackendPassphrase, SecretsBackendKeyring, SecretsBackendEnv, SecretsBackendFile)
+		}
+		config := loadConfig()
+		if err := config.migrateSecrets(backend, kdfAlgorithm, keyFile); err != nil {
+			return err
+		}
+		return saveConfig(config)
+
+	default:
+		return fmt.Errorf("unknown secrets command: %s (use status, migrate or generate-key)", args[0])
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newLegacySecretsConfig returns a config with passwords saved by older versions
+func newLegacySecretsConfig(t *testing.T) *Config {
+	t.Helper()
+	sessionMasterKey = nil
+	t.Cleanup(func() { sessionMasterKey = nil })
+
+	legacy := func(plaintext, nodeIP string) string {
+		t.Helper()
+		value, err := encryptPassword(plaintext, nodeIP)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	return &Config{
+		LastNodeIP: "10.0.0.1",
+		NodeCredentials: []NodeCredentials{
+			{NodeIP: "10.0.0.1", SSHUsername: "root", MySQLUsername: "admin",
+				EncryptedSSHPassword: legacy("ssh-one", "10.0.0.1"), HasSSHPassword: true,
+				EncryptedMySQLPassword: legacy("mysql-one", "10.0.0.1"), HasMySQLPassword: true},
+			{NodeIP: "10.0.0.2", SSHUsername: "root", MySQLUsername: "admin",
+				EncryptedSSHPassword: legacy("ssh-two", "10.0.0.2"), HasSSHPassword: true},
+		},
+		EncryptedMySQLPassword: legacy("global-mysql", "10.0.0.1"),
+		HasSavedPassword:       true,
+		SecretsKeyFile:         filepath.Join(t.TempDir(), "galerahealth.key"),
+	}
+}
+
+// assertSecrets checks that every saved password decrypts to its original value
+func assertSecrets(t *testing.T, config *Config) {
+	t.Helper()
+	want := map[string]string{"10.0.0.1/ssh": "ssh-one", "10.0.0.1/mysql": "mysql-one", "10.0.0.2/ssh": "ssh-two"}
+	for key, expected := range want {
+		ip, kind, _ := strings.Cut(key, "/")
+		var got string
+		var err error
+		if kind == "ssh" {
+			got, err = config.getNodeSSHPassword(ip)
+		} else {
+			got, err = config.getNodeMySQLPassword(ip)
+		}
+		if err != nil || got != expected {
+			t.Errorf("%s password = %q (%v), want %q", key, got, err, expected)
+		}
+	}
+	if got, err := config.decryptSecret(config.EncryptedMySQLPassword, config.LastNodeIP); err != nil || got != "global-mysql" {
+		t.Errorf("global password = %q (%v), want global-mysql", got, err)
+	}
+}
+
+func TestMigrateSecrets(t *testing.T) {
+	envKey, err := generateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		backend string
+		kdf     string
+		setup   func(t *testing.T, config *Config) string // Returns the key file, if any
+	}{
+		{
+			name:    "passphrase with argon2id",
+			backend: SecretsBackendPassphrase,
+			kdf:     KDFArgon2id,
+			setup:   func(t *testing.T, config *Config) string { t.Setenv(passphraseEnv, "correct horse"); return "" },
+		},
+		{
+			name:    "passphrase with scrypt",
+			backend: SecretsBackendPassphrase,
+			kdf:     KDFScrypt,
+			setup:   func(t *testing.T, config *Config) string { t.Setenv(passphraseEnv, "battery staple"); return "" },
+		},
+		{
+			name:    "environment key",
+			backend: SecretsBackendEnv,
+			setup: func(t *testing.T, config *Config) string {
+				t.Setenv(secretKeyEnv, base64.StdEncoding.EncodeToString(envKey))
+				return ""
+			},
+		},
+		{
+			name:    "key file",
+			backend: SecretsBackendFile,
+			setup: func(t *testing.T, config *Config) string {
+				return filepath.Join(t.TempDir(), "galerahealth.key")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newLegacySecretsConfig(t)
+			keyFile := tt.setup(t, config)
+			if config.countLegacySecrets() != 4 {
+				t.Fatalf("expected 4 legacy secrets, got %d", config.countLegacySecrets())
+			}
+
+			if err := config.migrateSecrets(tt.backend, tt.kdf, keyFile); err != nil {
+				t.Fatalf("migrateSecrets: %v", err)
+			}
+			if config.countLegacySecrets() != 0 {
+				t.Errorf("%d secrets still use the legacy key", config.countLegacySecrets())
+			}
+			if _, err := decryptPassword(config.NodeCredentials[0].EncryptedSSHPassword, "10.0.0.1"); err == nil {
+				t.Error("migrated password can still be decrypted with the node IP derived key")
+			}
+
+			// A new run has to unlock the key again
+			sessionMasterKey = nil
+			assertSecrets(t, config)
+
+			// New passwords are saved with the new backend
+			if err := config.setNodeCredentials("10.0.0.3", "root", "", "ssh-three", "", false); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(config.getNodeCredentials("10.0.0.3").EncryptedSSHPassword, secretsPrefix) {
+				t.Error("new password was not encrypted with the master key")
+			}
+
+			// Passwords cannot be moved back to the legacy key
+			if err := config.migrateSecrets(SecretsBackendLegacy, "", ""); err == nil {
+				t.Error("migrated back to the legacy backend")
+			}
+		})
+	}
+}
+
+func TestFirstSavedPasswordNeedsBackend(t *testing.T) {
+	config := newLegacySecretsConfig(t)
+	previous := useDefaults
+	t.Cleanup(func() { useDefaults = previous })
+
+	// No backend is picked implicitly: -y runs and an empty answer leave everything as it was
+	for _, unattended := range []bool{true, false} {
+		useDefaults = unattended
+		answerPrompts(t, "")
+		if err := config.setNodeCredentials("10.0.0.3", "root", "", "ssh-three", "", false); err == nil || !strings.Contains(err.Error(), "secrets migrate") {
+			t.Errorf("saving without a backend (-y %v): %v", unattended, err)
+		}
+		if config.SecretsBackend != "" || config.countLegacySecrets() != 4 || config.getNodeCredentials("10.0.0.3").HasSSHPassword {
+			t.Errorf("config changed without a backend (-y %v): %+v", unattended, config)
+		}
+		if _, err := os.Stat(config.SecretsKeyFile); err == nil {
+			t.Errorf("key file created without a backend (-y %v)", unattended)
+		}
+	}
+
+	// The chosen backend protects the new password and the old entries
+	useDefaults = false
+	answerPrompts(t, "passphrase")
+	t.Setenv(passphraseEnv, "correct horse")
+	if err := config.setNodeCredentials("10.0.0.3", "root", "", "ssh-three", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if config.SecretsBackend != SecretsBackendPassphrase || config.SecretsKDF == nil {
+		t.Errorf("secrets backend = %q (%+v)", config.SecretsBackend, config.SecretsKDF)
+	}
+	if config.countLegacySecrets() != 0 {
+		t.Errorf("%d secrets still use the legacy key", config.countLegacySecrets())
+	}
+
+	sessionMasterKey = nil
+	assertSecrets(t, config)
+	if got, err := config.getNodeSSHPassword("10.0.0.3"); err != nil || got != "ssh-three" {
+		t.Errorf("new password = %q (%v), want ssh-three", got, err)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	config := newLegacySecretsConfig(t)
+	t.Setenv(passphraseEnv, "right")
+	if err := config.migrateSecrets(SecretsBackendPassphrase, KDFArgon2id, ""); err != nil {
+		t.Fatalf("migrateSecrets: %v", err)
+	}
+
+	sessionMasterKey = nil
+	t.Setenv(passphraseEnv, "wrong")
+	if _, err := config.getNodeSSHPassword("10.0.0.1"); err == nil || !strings.Contains(err.Error(), "wrong master passphrase") {
+		t.Errorf("expected a wrong passphrase error, got %v", err)
+	}
+}
+
+func TestSecretBoundToNode(t *testing.T) {
+	key, err := generateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealSecret(key, "secret", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openSecret(key, sealed, "10.0.0.2"); err == nil {
+		t.Error("a password copied to another node's entry was decrypted")
+	}
+}
+
+func TestKeyFilePermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	key, _ := generateSecretKey()
+	if err := writeSecretKeyFile(path, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSecretKeyFile(path); err != nil {
+		t.Fatalf("readSecretKeyFile: %v", err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSecretKeyFile(path); err == nil {
+		t.Error("a world-readable key file was accepted")
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// Loopback only: the reports hold node addresses, errors and membership, without authentication
+	defaultServeListen   = "127.0.0.1:19567"
+	defaultServeInterval = 60 * time.Second
+)
+
+// Health states reported by serve
+const (
+	HealthPending     = "pending"
+	HealthHealthy     = "healthy"
+	HealthWarning     = "warning"
+	HealthCritical    = "critical"
+	HealthUnreachable = "unreachable"
+)
+
+// NodeReport is the status of one node in a HealthReport
+type NodeReport struct {
+	IP              string   `json:"ip"`
+	Aliases         []string `json:"aliases,omitempty"`
+	MySQLResponding bool     `json:"mysql_responding"`
+	Ready           bool     `json:"ready"`
+	ClusterStatus   string   `json:"cluster_status,omitempty"`
+	State           string   `json:"state,omitempty"`
+	Error           string   `json:"error,omitempty"`
+	ClockOffsetMs   *float64 `json:"clock_offset_ms,omitempty"`
+	TimeSync        string   `json:"time_sync,omitempty"`
+
+	Resources *HostResources `json:"resources,omitempty"`
+	Versions  *NodeVersions  `json:"versions,omitempty"`
+}
+
+// HealthReport is the result of the latest check, served as JSON on /health
+type HealthReport struct {
+	Cluster   string       `json:"cluster,omitempty"`
+	Status    string       `json:"status"`
+	CheckedAt time.Time    `json:"checked_at"`
+	Duration  float64      `json:"duration_seconds"`
+	Error     string       `json:"error,omitempty"`
+	Issues    []string     `json:"issues,omitempty"`
+	Warnings  []string     `json:"warnings,omitempty"`
+	Nodes     []NodeReport `json:"nodes,omitempty"`
+
+	Membership *MembershipDiff `json:"membership,omitempty"`
+	Transfers  []StateTransfer `json:"transfers,omitempty"`
+}
+
+// healthServer checks the cluster periodically and serves the latest result
+type healthServer struct {
+	config *Config
+	mu     sync.Mutex
+	report HealthReport
+}
+
+// newHealthReport builds a report from a check result
+func newHealthReport(cluster string, analysis *ClusterAnalysis, err error) HealthReport {
+	report := HealthReport{Cluster: cluster, CheckedAt: time.Now()}
+	if err != nil {
+		report.Status = HealthUnreachable
+		report.Error = err.Error()
+		return report
+	}
+
+	health := evaluateClusterHealth(analysis)
+	report.Issues = health.Issues
+	report.Warnings = health.Warnings
+	report.Membership = analysis.Membership
+	report.Transfers = analysis.Transfers
+	switch {
+	case len(health.Issues) > 0:
+		report.Status = HealthCritical
+	case len(health.Warnings) > 0:
+		report.Status = HealthWarning
+	default:
+		report.Status = HealthHealthy
+	}
+	for _, node := range analysis.AllNodes {
+		report.Nodes = append(report.Nodes, NodeReport{
+			IP:              node.NodeIP,
+			Aliases:         node.Aliases,
+			MySQLResponding: node.MySQLResponding,
+			Ready:           node.IsReady,
+			ClusterStatus:   node.ClusterStatus,
+			State:           node.LocalStateComment,
+			Error:           node.StatusError,
+			Resources:       node.Resources,
+			Versions:        node.Versions,
+		})
+		if clock := node.Clock; clock != nil {
+			nodeReport := &report.Nodes[len(report.Nodes)-1]
+			if clock.Measured {
+				offset := float64(clock.Offset) / float64(time.Millisecond)
+				nodeReport.ClockOffsetMs = &offset
+			}
+			nodeReport.TimeSync = strings.TrimSpace(clock.SyncDaemon + " " + clock.SyncState)
+		}
+	}
+	return report
+}
+
+// check runs one health check and stores its result
+func (s *healthServer) check() {
+	start := time.Now()
+
+	// Per-node progress output would repeat on every interval
+	analysis, err := checkCluster(s.config, true)
+
+	report := newHealthReport(s.config.activeCluster, analysis, err)
+	report.Duration = time.Since(start).Seconds()
+
+	s.mu.Lock()
+	s.report = report
+	s.mu.Unlock()
+
+	switch report.Status {
+	case HealthUnreachable:
+		logMinimal("❌ Check failed: %s", report.Error)
+	case HealthCritical:
+		logMinimal("❌ Cluster has critical issues: %s", strings.Join(report.Issues, "; "))
+	case HealthWarning:
+		logMinimal("⚠️  Cluster has warnings: %s", strings.Join(report.Warnings, "; "))
+	default:
+		logNormal("✅ Cluster healthy (%d nodes, %.1fs)", len(report.Nodes), report.Duration)
+	}
+}
+
+// latest returns the result of the latest check
+func (s *healthServer) latest() HealthReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.report
+}
+
+// handleHealth serves the latest report as JSON; the status code is 503 unless the cluster is usable
+func (s *healthServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	report := s.latest()
+	status := http.StatusOK
+	if report.Status != HealthHealthy && report.Status != HealthWarning {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// checkServeListen refuses a listen address reachable from other hosts unless exposing it was requested
+func checkServeListen(listen string, expose bool) error {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return fmt.Errorf("invalid value for --listen: %s", listen)
+	}
+	if expose || host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("--listen %s is reachable from other hosts and /health shows node addresses, errors and membership "+
+		"without authentication; add --expose to serve it anyway", listen)
+}
+
+// runServeCommand implements the "serve" subcommand
+func runServeCommand(args []string) error {
+	listen := defaultServeListen
+	interval := defaultServeInterval
+	expose := false
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--expose" {
+			expose = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return fmt.Errorf("unknown serve option: %s", args[i])
+		}
+		switch args[i] {
+		case "--listen":
+			listen = args[i+1]
+		case "--interval":
+			seconds, err := strconv.Atoi(args[i+1])
+			if err != nil || seconds <= 0 {
+				return fmt.Errorf("invalid value for --interval: %s", args[i+1])
+			}
+			interval = time.Duration(seconds) * time.Second
+		default:
+			return fmt.Errorf("unknown serve option: %s", args[i])
+		}
+		i++
+	}
+	if err := checkServeListen(listen, expose); err != nil {
+		return err
+	}
+
+	// Nobody is there to answer prompts
+	useDefaults = true
+	config := loadClusterConfig()
+	if len(config.seedNodes()) == 0 {
+		return fmt.Errorf("no node configured (use --node, --cluster or the configuration file)")
+	}
+
+	server := &healthServer{config: config, report: HealthReport{Cluster: config.activeCluster, Status: HealthPending}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", server.handleHealth)
+	httpServer := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		server.check()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				server.check()
+			}
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logMinimal("🌐 Serving /health on %s (checking every %s)", listen, interval)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logMinimal("👋 Stopped")
+	return nil
+}
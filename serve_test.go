@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthServer(t *testing.T) {
+	members := []string{"10.0.0.1", "10.0.0.2"}
+	node := func(ip string) *fakeNode {
+		return &fakeNode{IP: ip, Service: "active", Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...)}}
+	}
+	cluster := newFakeCluster(t, node("10.0.0.1"), node("10.0.0.2"))
+
+	config := newTestConfig(t)
+	config.LastNodeIP = "10.0.0.1"
+	server := &healthServer{config: config, report: HealthReport{Status: HealthPending}}
+
+	get := func(path string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	// Nothing checked yet
+	if code := get("/health", server.handleHealth).Code; code != http.StatusServiceUnavailable {
+		t.Errorf("pending /health = %d, want 503", code)
+	}
+
+	server.check()
+	response := get("/health", server.handleHealth)
+	var report HealthReport
+	if err := json.Unmarshal(response.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != http.StatusOK || report.Status != HealthHealthy || len(report.Nodes) != 2 {
+		t.Errorf("/health = %d %+v", response.Code, report)
+	}
+
+	// A node with a different configuration makes the cluster critical
+	cluster.Nodes["10.0.0.2"].Files["/etc/mysql/my.cnf"] = galeraConfig("staging", "10.0.0.2", members...)
+	closeNodeExecutors()
+	server.check()
+	if report := server.latest(); report.Status != HealthCritical || len(report.Issues) == 0 {
+		t.Errorf("report after configuration drift: %+v", report)
+	}
+
+	// The cluster becomes unreachable
+	cluster.Nodes["10.0.0.1"].Unreachable = true
+	closeNodeExecutors()
+	server.check()
+	if code := get("/health", server.handleHealth).Code; code != http.StatusServiceUnavailable || server.latest().Status != HealthUnreachable {
+		t.Errorf("unreachable /health = %d (%s)", code, server.latest().Status)
+	}
+}
+
+func TestCheckServeListen(t *testing.T) {
+	tests := []struct {
+		listen string
+		expose bool
+		ok     bool
+	}{
+		{defaultServeListen, false, true},
+		{"localhost:9000", false, true},
+		{"[::1]:9000", false, true},
+		{":19567", false, false},
+		{"0.0.0.0:19567", false, false},
+		{"10.0.0.5:19567", false, false},
+		{":19567", true, true},
+		{"19567", true, false},
+	}
+	for _, tt := range tests {
+		if err := checkServeListen(tt.listen, tt.expose); (err == nil) != tt.ok {
+			t.Errorf("checkServeListen(%q, %t) = %v", tt.listen, tt.expose, err)
+		}
+	}
+}
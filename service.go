@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Supported service managers
+const (
+	ServiceManagerSystemd = "systemd"
+	ServiceManagerSysV    = "sysv"
+	ServiceManagerOpenRC  = "openrc"
+	ServiceManagerCustom  = "custom"
+	ServiceManagerDocker  = "docker"
+	ServiceManagerPodman  = "podman"
+)
+
+// ServiceBackend describes how the MySQL/MariaDB service is managed on a node
+type ServiceBackend struct {
+	Manager          string `json:"manager" yaml:"manager"`                                         // systemd, sysv, openrc, custom, docker or podman
+	Unit             string `json:"unit,omitempty" yaml:"unit,omitempty"`                           // systemd unit (mariadb, mariadb@node1), init script or container name
+	Runtime          string `json:"runtime,omitempty" yaml:"runtime,omitempty"`                     // Container runtime binary for docker/podman managers
+	StartCommand     string `json:"start_command,omitempty" yaml:"start_command,omitempty"`         // Overrides the default start command
+	StopCommand      string `json:"stop_command,omitempty" yaml:"stop_command,omitempty"`           // Overrides the default stop command
+	StatusCommand    string `json:"status_command,omitempty" yaml:"status_command,omitempty"`       // Overrides the default status command (exit 0 = running)
+	BootstrapCommand string `json:"bootstrap_command,omitempty" yaml:"bootstrap_command,omitempty"` // Overrides the default bootstrap command
+	AutoDetected     bool   `json:"auto_detected,omitempty" yaml:"auto_detected,omitempty"`         // Backend was detected automatically
+}
+
+// detectedServiceBackends caches backends detected for nodes without saved credentials (e.g. localhost);
+// nodes are checked concurrently, so access goes through detectedServiceBackendsMu
+var (
+	detectedServiceBackends   = make(map[string]*ServiceBackend)
+	detectedServiceBackendsMu sync.Mutex
+)
+
+// cachedServiceBackend returns the backend detected earlier for a node, if any
+func cachedServiceBackend(ip string) (*ServiceBackend, bool) {
+	detectedServiceBackendsMu.Lock()
+	defer detectedServiceBackendsMu.Unlock()
+	backend, ok := detectedServiceBackends[ip]
+	return backend, ok
+}
+
+// cacheServiceBackend records the backend detected for a node
+func cacheServiceBackend(ip string, backend *ServiceBackend) {
+	detectedServiceBackendsMu.Lock()
+	defer detectedServiceBackendsMu.Unlock()
+	detectedServiceBackends[ip] = backend
+}
+
+// serviceDetectionScript prints "<manager> <unit>" for the MySQL/MariaDB service found on the host
+const serviceDetectionScript = `if command -v systemctl >/dev/null 2>&1 && [ -d /run/systemd/system ]; then
+  units=$(systemctl list-units --all --type=service --no-legend --plain 'mariadb*' 'mysql*' 'mysqld*' 2>/dev/null)
+  u=$(echo "$units" | awk '$3=="active"{print $1; exit}')
+  [ -z "$u" ] && u=$(echo "$units" | awk '$2=="loaded"{print $1; exit}')
+  [ -z "$u" ] && u=$(systemctl list-unit-files --type=service --no-legend 'mariadb*' 'mysql*' 'mysqld*' 2>/dev/null | awk '$1 !~ /@\.service$/ {print $1; exit}')
+  [ -n "$u" ] && echo "systemd ${u%.service}" && exit 0
+fi
+if command -v rc-service >/dev/null 2>&1; then
+  for n in mariadb mysql; do [ -x /etc/init.d/$n ] && echo "openrc $n" && exit 0; done
+fi
+for n in mariadb mysql mysqld; do [ -x /etc/init.d/$n ] && echo "sysv $n" && exit 0; done
+true`
+
+// detectServiceBackend detects the service manager and unit name on the executor's host
+func detectServiceBackend(executor NodeExecutor) *ServiceBackend {
+	output, err := runCommand(executor, serviceDetectionScript)
+	if err == nil {
+		parts := strings.Fields(strings.TrimSpace(output))
+		if len(parts) == 2 {
+			logVerbose("   Detected service backend: %s (%s)", parts[0], parts[1])
+			return &ServiceBackend{Manager: parts[0], Unit: parts[1], AutoDetected: true}
+		}
+	}
+
+	// Nothing detected: keep the historical behaviour of assuming systemd with the mariadb unit
+	logVerbose("   Could not detect service backend, assuming systemd unit mariadb")
+	return &ServiceBackend{Manager: ServiceManagerSystemd, Unit: "mariadb", AutoDetected: true}
+}
+
+// getNodeServiceBackend returns the service backend for a node, detecting and recording it if needed
+func getNodeServiceBackend(ip string, config *Config) *ServiceBackend {
+	return resolveServiceBackend(ip, config, nil)
+}
+
+// resolveServiceBackend returns the configured backend for a node or detects it with the given
+// executor (the node's shared executor when nil)
+func resolveServiceBackend(ip string, config *Config, executor NodeExecutor) *ServiceBackend {
+	if config != nil {
+		if creds := config.getNodeCredentials(ip); creds != nil && creds.Service != nil && creds.Service.Manager != "" {
+			return creds.Service
+		}
+	}
+	if backend, ok := cachedServiceBackend(ip); ok {
+		return backend
+	}
+	if transport := getNodeTransport(ip, config); transport != nil {
+		// Containerized nodes are started and stopped through the container runtime
+		backend := containerServiceBackend(transport)
+		cacheServiceBackend(ip, backend)
+		return backend
+	}
+
+	if executor == nil {
+		var err error
+		if executor, err = getNodeExecutor(ip, config); err != nil {
+			logVerbose("   Could not connect to %s to detect service backend: %v", ip, err)
+			return &ServiceBackend{Manager: ServiceManagerSystemd, Unit: "mariadb", AutoDetected: true}
+		}
+	}
+	backend := detectServiceBackend(executor)
+	if config != nil {
+		if creds := config.getNodeCredentials(ip); creds != nil {
+			creds.Service = backend
+			return backend
+		}
+	}
+	cacheServiceBackend(ip, backend)
+	return backend
+}
+
+// String returns a short description of the backend
+func (b *ServiceBackend) String() string {
+	if b.Unit != "" {
+		return fmt.Sprintf("%s:%s", b.Manager, b.Unit)
+	}
+	return b.Manager
+}
+
+// runsOnHost reports whether service commands run on the container host rather than inside the node
+func (b *ServiceBackend) runsOnHost() bool {
+	return b.Manager == ServiceManagerDocker || b.Manager == ServiceManagerPodman
+}
+
+// runtimeBinary returns the container runtime command for docker/podman managers
+func (b *ServiceBackend) runtimeBinary() string {
+	if b.Runtime != "" {
+		return b.Runtime
+	}
+	return b.Manager
+}
+
+// statusCommand returns a command that prints active, activating, inactive or failed
+func (b *ServiceBackend) statusCommand() string {
+	if b.StatusCommand != "" {
+		return fmt.Sprintf("if %s >/dev/null 2>&1; then echo active; else echo inactive; fi", b.StatusCommand)
+	}
+	switch b.Manager {
+	case ServiceManagerSysV:
+		return fmt.Sprintf("if service %s status >/dev/null 2>&1; then echo active; else echo inactive; fi", b.Unit)
+	case ServiceManagerOpenRC:
+		return fmt.Sprintf("s=$(rc-service %s status 2>/dev/null); case \"$s\" in *started*) echo active;; *starting*) echo activating;; *crashed*) echo failed;; *) echo inactive;; esac", b.Unit)
+	case ServiceManagerCustom:
+		return "if pgrep -x 'mysqld|mariadbd' >/dev/null 2>&1; then echo active; else echo inactive; fi"
+	case ServiceManagerDocker, ServiceManagerPodman:
+		return containerStatusCommand(b.runtimeBinary(), b.Unit)
+	default:
+		return fmt.Sprintf("systemctl is-active %s 2>/dev/null; true", b.Unit)
+	}
+}
+
+// statusDetailCommand returns a command that prints a human readable service status
+func (b *ServiceBackend) statusDetailCommand() string {
+	switch b.Manager {
+	case ServiceManagerSysV:
+		return fmt.Sprintf("service %s status 2>&1 | head -10", b.Unit)
+	case ServiceManagerOpenRC:
+		return fmt.Sprintf("rc-service %s status 2>&1 | head -10", b.Unit)
+	case ServiceManagerCustom:
+		if b.StatusCommand != "" {
+			return fmt.Sprintf("%s 2>&1 | head -10", b.StatusCommand)
+		}
+		return "true"
+	case ServiceManagerDocker, ServiceManagerPodman:
+		return fmt.Sprintf("%s ps -a --filter name=%s 2>&1 | head -10", b.runtimeBinary(), shellQuote(b.Unit))
+	default:
+		return fmt.Sprintf("systemctl status %s 2>/dev/null | head -10", b.Unit)
+	}
+}
+
+// startCommand returns the command that starts the service
+func (b *ServiceBackend) startCommand() (string, error) {
+	if b.StartCommand != "" {
+		return b.StartCommand, nil
+	}
+	switch b.Manager {
+	case ServiceManagerSysV:
+		return fmt.Sprintf("service %s start", b.Unit), nil
+	case ServiceManagerOpenRC:
+		return fmt.Sprintf("rc-service %s start", b.Unit), nil
+	case ServiceManagerDocker, ServiceManagerPodman:
+		return fmt.Sprintf("%s start %s", b.runtimeBinary(), shellQuote(b.Unit)), nil
+	case ServiceManagerCustom:
+		return "", fmt.Errorf("custom service manager has no start_command, set start_command for this node")
+	default:
+		return fmt.Sprintf("systemctl start %s", b.Unit), nil
+	}
+}
+
+// stopCommand returns the command that stops the service
+func (b *ServiceBackend) stopCommand() (string, error) {
+	if b.StopCommand != "" {
+		return b.StopCommand, nil
+	}
+	switch b.Manager {
+	case ServiceManagerSysV:
+		return fmt.Sprintf("service %s stop", b.Unit), nil
+	case ServiceManagerOpenRC:
+		return fmt.Sprintf("rc-service %s stop", b.Unit), nil
+	case ServiceManagerDocker, ServiceManagerPodman:
+		return fmt.Sprintf("%s stop %s", b.runtimeBinary(), shellQuote(b.Unit)), nil
+	case ServiceManagerCustom:
+		return "", fmt.Errorf("custom service manager has no stop_command, set stop_command for this node")
+	default:
+		return fmt.Sprintf("systemctl stop %s", b.Unit), nil
+	}
+}
+
+// bootstrapCommand returns the command that starts the service as a new cluster
+func (b *ServiceBackend) bootstrapCommand() (string, error) {
+	if b.BootstrapCommand != "" {
+		return b.BootstrapCommand, nil
+	}
+	switch b.Manager {
+	case ServiceManagerSystemd:
+		// galera_new_cluster takes the instance name for templated units (mariadb@node1 -> node1)
+		if at := strings.Index(b.Unit, "@"); at != -1 {
+			return fmt.Sprintf("galera_new_cluster %s", b.Unit[at+1:]), nil
+		}
+		return "galera_new_cluster", nil
+	case ServiceManagerSysV:
+		return fmt.Sprintf("service %s bootstrap", b.Unit), nil
+	case ServiceManagerDocker, ServiceManagerPodman:
+		return "", fmt.Errorf("container %s can't be bootstrapped without a bootstrap_command in the node's transport "+
+			"(the container entrypoint decides how --wsrep-new-cluster is passed)", b.Unit)
+	default:
+		return "", fmt.Errorf("no bootstrap command known for %s service manager, set bootstrap_command for this node", b.Manager)
+	}
+}
+
+// logCommand returns a command that prints recent service log lines
+func (b *ServiceBackend) logCommand(since string, lines int) string {
+	if b.Manager == ServiceManagerSystemd {
+		return fmt.Sprintf("journalctl -u %s --no-pager -n %d --since '%s' 2>/dev/null", b.Unit, lines, since)
+	}
+	if b.runsOnHost() {
+		return containerLogCommand(b.runtimeBinary(), b.Unit, since, lines)
+	}
+	return fmt.Sprintf("tail -n %d /var/log/mysql/error.log /var/log/mariadb/mariadb.log /var/log/mysqld.log 2>/dev/null", lines)
+}
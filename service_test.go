@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestCustomServiceCommands(t *testing.T) {
+	backend := &ServiceBackend{Manager: ServiceManagerCustom}
+	if command, err := backend.startCommand(); err == nil {
+		t.Errorf("custom manager without start_command runs %q", command)
+	}
+	if command, err := backend.stopCommand(); err == nil {
+		t.Errorf("custom manager without stop_command runs %q", command)
+	}
+
+	backend.StartCommand = "/opt/galera/start.sh"
+	backend.StopCommand = "/opt/galera/stop.sh"
+	if command, err := backend.startCommand(); err != nil || command != backend.StartCommand {
+		t.Errorf("start command = %q, %v", command, err)
+	}
+	if command, err := backend.stopCommand(); err != nil || command != backend.StopCommand {
+		t.Errorf("stop command = %q, %v", command, err)
+	}
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNode simulates one Galera node: its filesystem, MySQL service state and wsrep status
+type fakeNode struct {
+	IP          string
+	Files       map[string]string // Path -> content (my.cnf tree, grastate.dat, ...)
+	Service     string            // active, inactive or failed
+	LatestIBD   int64             // Unix time of the newest .ibd file (0 = none)
+	Unreachable bool              // Connecting to the node fails
+	FailStart   bool              // Start and bootstrap commands fail
+	Synced      bool              // Node is part of the Primary component
+	Commands    []string          // State-changing service commands run on the node
+	MySQLUsers  map[string]string // Accepted MySQL user -> password (nil accepts any login)
+	MyCnf       bool              // ~/.my.cnf and login paths on the node hold a valid login
+	Aliases     []string          // Other addresses (hostname, VIP, second interface) reaching the node
+	MachineID   string            // Content of /etc/machine-id
+	Hostname    string            // Output of hostname (default "node-" and the IP)
+	Blocked     []int             // Inbound ports whose packets the node's firewall drops
+	ClockOffset time.Duration     // Node clock minus the real time
+	Zone        *time.Location    // Time zone of the node (nil = UTC)
+	TimeSync    string            // chrony Leap status ("Normal", "Not synchronised"), "" without a daemon
+	DiskFree    int64             // Free bytes on the 100GiB datadir filesystem (0 = 60GiB)
+	InodesUsed  int               // Inode usage percentage of the datadir filesystem (0 = 5%)
+	MemFree     int64             // MemAvailable of the 16GiB of memory (0 = 8GiB)
+	SwapUsed    int64             // Used bytes of the 2GiB of swap
+	GcacheFile  int64             // Size of galera.cache (0 = 128MiB)
+	Version     string            // version (default 10.6.16-MariaDB-log)
+	Provider    string            // wsrep_provider_version (default 26.4.16(r7dce5149))
+	Packages    string            // Output of the package query
+	State       string            // wsrep_local_state_comment overriding Synced ("Donor/Desynced", "Joining")
+	SSTProcess  string            // Arguments of the wsrep_sst_* helper running on the node
+	SSTAge      int               // Seconds the SST helper has been running
+	DataSize    int64             // Size of the datadir (0 = 100MiB)
+	DataDenied  bool              // du can't read part of the datadir
+	Log         string            // Service log lines
+	StatusTail  string            // Process tree and journal lines ending systemctl status
+
+	cluster *fakeCluster
+}
+
+// fakeCluster simulates a set of Galera nodes behind the NodeExecutor layer
+type fakeCluster struct {
+	Nodes map[string]*fakeNode
+}
+
+// newFakeCluster installs a simulated cluster behind connectNodeExecutor for the duration of a test
+func newFakeCluster(t *testing.T, nodes ...*fakeNode) *fakeCluster {
+	t.Helper()
+
+	cluster := &fakeCluster{Nodes: make(map[string]*fakeNode)}
+	for _, node := range nodes {
+		node.cluster = cluster
+		if node.Service == "" {
+			node.Service = "inactive"
+		}
+		if node.Service == "active" {
+			node.Synced = true
+		}
+		cluster.Nodes[node.IP] = node
+	}
+
+	previousConnect := connectNodeExecutor
+	connectNodeExecutor = func(ip string, config *Config) (NodeExecutor, *SSHConnectionInfo, error) {
+		node := cluster.lookup(ip)
+		if node == nil || node.Unreachable {
+			return nil, nil, fmt.Errorf("dial tcp %s:22: connect: no route to host", ip)
+		}
+		return &FakeExecutor{Handler: node.handle}, nil, nil
+	}
+	closeNodeExecutors()
+	detectedServiceBackends = make(map[string]*ServiceBackend)
+	workingMySQLCredentials = make(map[string]*MySQLConnectionInfo)
+	dataSizeCache = make(map[string]measuredDataSize)
+
+	t.Cleanup(func() {
+		connectNodeExecutor = previousConnect
+		closeNodeExecutors()
+		detectedServiceBackends = make(map[string]*ServiceBackend)
+		workingMySQLCredentials = make(map[string]*MySQLConnectionInfo)
+	})
+
+	return cluster
+}
+
+// newTestConfig returns a configuration whose audit log and secrets key file are in temporary directories
+func newTestConfig(t *testing.T) *Config {
+	t.Helper()
+	sessionMasterKey = nil
+	t.Cleanup(func() { sessionMasterKey = nil })
+	key, err := generateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "galerahealth.key")
+	if err := writeSecretKeyFile(keyFile, key); err != nil {
+		t.Fatal(err)
+	}
+	return &Config{
+		LastMySQLUsername: "root",
+		AuditLogPath:      filepath.Join(t.TempDir(), "audit.jsonl"),
+		SecretsBackend:    SecretsBackendFile,
+		SecretsKeyFile:    keyFile,
+	}
+}
+
+// answerPrompts feeds the given answers to interactive prompts for the duration of a test
+func answerPrompts(t *testing.T, answers ...string) {
+	t.Helper()
+	previous := stdinScanner
+	stdinScanner = bufio.NewScanner(strings.NewReader(strings.Join(answers, "\n") + "\n"))
+	t.Cleanup(func() { stdinScanner = previous })
+}
+
+// galeraConfig returns a my.cnf [galera] section for a node of the given cluster
+func galeraConfig(clusterName, nodeIP string, members ...string) string {
+	return fmt.Sprintf("[galera]\nwsrep_on = ON\nwsrep_cluster_name = %s\nwsrep_cluster_address = gcomm://%s\nwsrep_node_address = %s\n",
+		clusterName, strings.Join(members, ","), nodeIP)
+}
+
+// grastate returns the content of a grastate.dat file with the given seqno
+func grastate(seqno int64) string {
+	return fmt.Sprintf("# GALERA saved state\nversion: 2.1\nuuid:    6f2c4b1e-0000-0000-0000-000000000000\nseqno:   %d\nsafe_to_bootstrap: 0\n", seqno)
+}
+
+// lookup returns the node reached through an address or alias, or nil
+func (c *fakeCluster) lookup(address string) *fakeNode {
+	if node, ok := c.Nodes[address]; ok {
+		return node
+	}
+	for _, node := range c.Nodes {
+		if containsString(node.Aliases, address) {
+			return node
+		}
+	}
+	return nil
+}
+
+// primarySize returns the number of nodes in the Primary component
+func (c *fakeCluster) primarySize() int {
+	size := 0
+	for _, node := range c.Nodes {
+		if node.Service == "active" && node.Synced {
+			size++
+		}
+	}
+	return size
+}
+
+// incomingAddresses returns wsrep_incoming_addresses: the client address of every Primary component
+// member sharing the node's wsrep_cluster_name
+func (n *fakeNode) incomingAddresses() string {
+	var addresses []string
+	for ip, node := range n.cluster.Nodes {
+		if node.Service == "active" && node.Synced && node.clusterName() == n.clusterName() {
+			addresses = append(addresses, ip+":3306")
+		}
+	}
+	sort.Strings(addresses)
+	return strings.Join(addresses, ",")
+}
+
+// gcommUUID returns a wsrep_gcomm_uuid that is stable for the node
+func (n *fakeNode) gcommUUID() string {
+	var sum uint32
+	for _, c := range n.IP {
+		sum = sum*31 + uint32(c)
+	}
+	return fmt.Sprintf("%08x-0000-11ee-8000-000000000000", sum)
+}
+
+// listeningPorts returns the node's listening TCP ports: SSH, plus the client, gmcast and IST
+// ports while mysqld runs
+func (n *fakeNode) listeningPorts() []int {
+	if n.Service != "active" {
+		return []int{22}
+	}
+	var options, sst string
+	for _, content := range n.Files {
+		if value := extractConfigValue(content, "wsrep_provider_options"); value != "" {
+			options = value
+		}
+		if value := extractConfigValue(content, "wsrep_sst_receive_address"); value != "" {
+			sst = value
+		}
+	}
+	ports := parseGaleraPorts(options, sst)
+	// The IST receiver only listens on a joiner while the transfer runs
+	if isJoinerState(n.State) {
+		return []int{22, 3306, ports.Gmcast, ports.IST}
+	}
+	return []int{22, 3306, ports.Gmcast}
+}
+
+// orDefault returns value, or fallback when value is zero
+func orDefault(value, fallback int64) int64 {
+	if value == 0 {
+		return fallback
+	}
+	return value
+}
+
+// containsInt reports whether list contains value
+func containsInt(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterName returns the wsrep_cluster_name configured on the node
+func (n *fakeNode) clusterName() string {
+	for _, content := range n.Files {
+		if name := extractConfigValue(content, "wsrep_cluster_name"); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// serviceCommands returns the state-changing commands run on every node as "ip: command"
+func (c *fakeCluster) serviceCommands() []string {
+	var ips []string
+	for ip := range c.Nodes {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	var commands []string
+	for _, ip := range ips {
+		for _, cmd := range c.Nodes[ip].Commands {
+			commands = append(commands, ip+": "+cmd)
+		}
+	}
+	return commands
+}
+
+var (
+	fakeCatPattern      = regexp.MustCompile(`^cat '?([^'\s]+)'?$`)
+	fakeVariablePattern = regexp.MustCompile(`SHOW VARIABLES LIKE '(\w+)'`)
+	fakeStatusPattern   = regexp.MustCompile(`SHOW STATUS LIKE '(\w+)'`)
+	fakeLoginPattern    = regexp.MustCompile(`^mysql(?: -u (\S+))?(?: -p'([^']*)')?`)
+	fakeProbePattern    = regexp.MustCompile(`</dev/tcp/([^/]+)/(\d+)`)
+	fakeLockGuard       = regexp.MustCompile(`^if grep -q '([^']*)' \S+ 2>/dev/null; then (.*); fi$`)
+	fakeLockWrite       = regexp.MustCompile(`printf '%s\\n' '(.*)' > \S+`)
+)
+
+// handle answers a shell command the way the node would
+func (n *fakeNode) handle(command string, stdin string) *CommandResult {
+	switch {
+	case strings.Contains(command, "systemctl list-units"):
+		return &CommandResult{Stdout: "systemd mariadb\n"}
+	case strings.HasPrefix(command, "systemctl is-active"):
+		return &CommandResult{Stdout: n.Service + "\n"}
+	case strings.HasPrefix(command, "systemctl status"):
+		return &CommandResult{Stdout: fmt.Sprintf("● mariadb.service - MariaDB database server\n   Active: %s\n%s", n.Service, n.StatusTail)}
+	case command == "galera_new_cluster":
+		return n.startService(command, true)
+	case command == "systemctl start mariadb":
+		return n.startService(command, false)
+	case command == "systemctl stop mariadb":
+		n.Commands = append(n.Commands, command)
+		n.Service = "inactive"
+		n.Synced = false
+		return &CommandResult{}
+	case strings.HasPrefix(command, "journalctl"):
+		return &CommandResult{Stdout: n.Log}
+	case command == sstProcessQuery:
+		if n.SSTProcess == "" {
+			return &CommandResult{ExitCode: 1}
+		}
+		return &CommandResult{Stdout: fmt.Sprintf("%6d %s\n", n.SSTAge, n.SSTProcess)}
+	case strings.HasPrefix(command, "find /etc/mysql -name '*.cnf'"):
+		return &CommandResult{Stdout: n.listFiles("/etc/mysql/")}
+	case strings.HasPrefix(command, "test -f /etc/my.cnf"):
+		if _, ok := n.Files["/etc/my.cnf"]; ok {
+			return &CommandResult{Stdout: "/etc/my.cnf\n"}
+		}
+		return &CommandResult{Stdout: "\n"}
+	case strings.Contains(command, "grastate.dat"):
+		return &CommandResult{Stdout: n.grastateSeqno()}
+	case strings.Contains(command, "-name '*.ibd'"):
+		if n.LatestIBD == 0 {
+			return &CommandResult{}
+		}
+		return &CommandResult{Stdout: fmt.Sprintf("%d.0000000000\n", n.LatestIBD)}
+	case strings.HasPrefix(command, "ss -ltnH"):
+		var lines []string
+		for _, port := range n.listeningPorts() {
+			lines = append(lines, fmt.Sprintf("LISTEN 0 80 0.0.0.0:%d 0.0.0.0:*", port))
+		}
+		return &CommandResult{Stdout: strings.Join(lines, "\n") + "\n"}
+	case fakeProbePattern.MatchString(command):
+		matches := fakeProbePattern.FindStringSubmatch(command)
+		port, _ := strconv.Atoi(matches[2])
+		target := n.cluster.lookup(matches[1])
+		switch {
+		case target == nil || target.Unreachable || containsInt(target.Blocked, port):
+			return &CommandResult{Stdout: "timeout\n"}
+		case containsInt(target.listeningPorts(), port):
+			return &CommandResult{Stdout: "open 850\n"}
+		}
+		return &CommandResult{Stdout: "closed\n"}
+	case command == "date +%z":
+		zone := n.Zone
+		if zone == nil {
+			zone = time.UTC
+		}
+		return &CommandResult{Stdout: time.Now().In(zone).Format("-0700") + "\n"}
+	case command == "date +%s%N":
+		return &CommandResult{Stdout: fmt.Sprintf("%d\n", time.Now().Add(n.ClockOffset).UnixNano())}
+	case strings.HasPrefix(command, "chronyc tracking"):
+		if n.TimeSync == "" {
+			return &CommandResult{Stdout: "ntpstat 127\n"}
+		}
+		return &CommandResult{Stdout: fmt.Sprintf("Leap status     : %s\nntpstat 127\n", n.TimeSync)}
+	case strings.Contains(command, "/etc/machine-id"):
+		if n.MachineID == "" {
+			return &CommandResult{ExitCode: 1}
+		}
+		return &CommandResult{Stdout: n.MachineID + "\n"}
+	case strings.HasPrefix(command, "hostname"):
+		if n.Hostname == "" {
+			return &CommandResult{Stdout: "node-" + n.IP + "\n"}
+		}
+		return &CommandResult{Stdout: n.Hostname + "\n"}
+	case strings.HasPrefix(command, "du -sb "):
+		if n.DataDenied {
+			// du prints the total of what it could read and fails
+			return &CommandResult{Stdout: "4096\t/var/lib/mysql\n", Stderr: "du: cannot read directory '/var/lib/mysql/mysql': Permission denied\n", ExitCode: 1}
+		}
+		return &CommandResult{Stdout: fmt.Sprintf("%d\t/var/lib/mysql\n", orDefault(n.DataSize, 104857600))}
+	case command == packageQuery:
+		return &CommandResult{Stdout: n.Packages}
+	case strings.HasPrefix(command, "df -PB1 "):
+		free := orDefault(n.DiskFree, 60<<30)
+		row := fmt.Sprintf("/dev/sda1 %d %d %d %d%% /", int64(100<<30), int64(100<<30)-free, free, 100-free*100/(100<<30))
+		return &CommandResult{Stdout: "Filesystem 1-blocks Used Available Capacity Mounted on\n" + row + "\n" + row + "\n"}
+	case strings.HasPrefix(command, "df -Pi "):
+		used := n.InodesUsed
+		if used == 0 {
+			used = 5
+		}
+		return &CommandResult{Stdout: fmt.Sprintf("Filesystem Inodes IUsed IFree IUse%% Mounted on\n/dev/sda1 6553600 %d %d %d%% /\n",
+			6553600*used/100, 6553600-6553600*used/100, used)}
+	case strings.HasPrefix(command, "stat -c %s ") && strings.Contains(command, "galera.cache"):
+		return &CommandResult{Stdout: fmt.Sprintf("%d\n", orDefault(n.GcacheFile, 128<<20))}
+	case command == "cat /proc/meminfo":
+		return &CommandResult{Stdout: fmt.Sprintf("MemTotal: %d kB\nMemFree: 1024 kB\nMemAvailable: %d kB\nSwapTotal: %d kB\nSwapFree: %d kB\n",
+			16<<20, orDefault(n.MemFree, 8<<30)>>10, 2<<20, (int64(2<<30)-n.SwapUsed)>>10)}
+	case strings.HasPrefix(command, "mysql"):
+		return n.handleMySQL(command)
+	case strings.Contains(command, recoveryLockPath):
+		return n.handleLockFile(command)
+	}
+
+	if matches := fakeCatPattern.FindStringSubmatch(command); matches != nil {
+		content, ok := n.Files[matches[1]]
+		if !ok {
+			return &CommandResult{Stderr: fmt.Sprintf("cat: %s: No such file or directory\n", matches[1]), ExitCode: 1}
+		}
+		return &CommandResult{Stdout: content}
+	}
+
+	return nil
+}
+
+// startService starts MariaDB, either bootstrapping a new Primary component or joining the existing one
+func (n *fakeNode) startService(command string, bootstrap bool) *CommandResult {
+	n.Commands = append(n.Commands, command)
+	if n.FailStart {
+		n.Service = "failed"
+		return &CommandResult{Stderr: "Job for mariadb.service failed because the control process exited with error code.\n", ExitCode: 1}
+	}
+	if !bootstrap && n.cluster.primarySize() == 0 {
+		// Without a Primary component to join, mysqld gives up after wsrep_provider timeout
+		n.Service = "failed"
+		return &CommandResult{Stderr: "WSREP: failed to open gcomm backend connection: 110: failed to reach primary view\n", ExitCode: 1}
+	}
+	n.Service = "active"
+	n.Synced = true
+	return &CommandResult{}
+}
+
+// handleLockFile answers the commands that create, read, renew and remove the recovery lock file
+func (n *fakeNode) handleLockFile(command string) *CommandResult {
+	content, exists := n.Files[recoveryLockPath]
+	if matches := fakeLockGuard.FindStringSubmatch(command); matches != nil {
+		// Only act on a lock holding the token
+		if !strings.Contains(content, matches[1]) {
+			return &CommandResult{}
+		}
+		command = matches[2]
+	}
+	switch {
+	case strings.HasPrefix(command, "cat "):
+		return &CommandResult{Stdout: content}
+	case strings.HasPrefix(command, "rm -f "):
+		delete(n.Files, recoveryLockPath)
+		return &CommandResult{}
+	case strings.HasPrefix(command, "(set -C;") && exists:
+		return &CommandResult{ExitCode: 1}
+	}
+	if matches := fakeLockWrite.FindStringSubmatch(command); matches != nil {
+		if n.Files == nil {
+			n.Files = make(map[string]string)
+		}
+		n.Files[recoveryLockPath] = matches[1] + "\n"
+		return &CommandResult{}
+	}
+	return nil
+}
+
+// handleMySQL answers mysql client invocations while the service is running
+func (n *fakeNode) handleMySQL(command string) *CommandResult {
+	if n.Service != "active" {
+		return &CommandResult{Stdout: "ERROR 2002 (HY000): Can't connect to local server through socket '/run/mysqld/mysqld.sock' (2)\n", ExitCode: 1}
+	}
+
+	if n.MySQLUsers != nil {
+		matches := fakeLoginPattern.FindStringSubmatch(command)
+		user, password := matches[1], matches[2]
+		accepted := n.MyCnf && user == ""
+		if expected, ok := n.MySQLUsers[user]; ok && user != "" && expected == password {
+			accepted = true
+		}
+		if !accepted {
+			return &CommandResult{Stdout: fmt.Sprintf("ERROR 1045 (28000): Access denied for user '%s'@'localhost'\n", user), ExitCode: 1}
+		}
+	}
+
+	if strings.Contains(command, "'version_comment'") {
+		version, provider := n.Version, n.Provider
+		if version == "" {
+			version = "10.6.16-MariaDB-log"
+		}
+		if provider == "" {
+			provider = "26.4.16(r7dce5149)"
+		}
+		return &CommandResult{Stdout: fmt.Sprintf("version\t%s\nversion_comment\tmariadb.org binary distribution\nwsrep_provider_version\t%s\nwsrep_protocol_version\t10\n",
+			version, provider)}
+	}
+	if strings.Contains(command, "SHOW GLOBAL STATUS WHERE") {
+		return &CommandResult{Stdout: fmt.Sprintf("wsrep_cluster_size\t%d\nwsrep_cluster_status\t%s\nwsrep_local_state_comment\t%s\nwsrep_incoming_addresses\t%s\nwsrep_gcomm_uuid\t%s\n",
+			n.cluster.primarySize(), n.clusterStatus(), n.stateComment(), n.incomingAddresses(), n.gcommUUID())}
+	}
+	if matches := fakeVariablePattern.FindStringSubmatch(command); matches != nil {
+		value := ""
+		for _, content := range n.Files {
+			if v := extractConfigValue(content, matches[1]); v != "" {
+				value = v
+			}
+		}
+		return &CommandResult{Stdout: fmt.Sprintf("Variable_name\tValue\n%s\t%s\n", matches[1], value)}
+	}
+	if matches := fakeStatusPattern.FindStringSubmatch(command); matches != nil {
+		values := map[string]string{
+			"wsrep_cluster_size":        fmt.Sprint(n.cluster.primarySize()),
+			"wsrep_cluster_status":      n.clusterStatus(),
+			"wsrep_ready":               "ON",
+			"wsrep_local_state_comment": n.stateComment(),
+		}
+		return &CommandResult{Stdout: fmt.Sprintf("Variable_name\tValue\n%s\t%s\n", matches[1], values[matches[1]])}
+	}
+	if strings.Contains(command, "SELECT 1") {
+		return &CommandResult{Stdout: "1\n1\n"}
+	}
+	return &CommandResult{Stdout: "\n"}
+}
+
+// clusterStatus returns wsrep_cluster_status for the node
+func (n *fakeNode) clusterStatus() string {
+	if n.Synced {
+		return "Primary"
+	}
+	return "non-Primary"
+}
+
+// stateComment returns wsrep_local_state_comment for the node
+func (n *fakeNode) stateComment() string {
+	if n.State != "" {
+		return n.State
+	}
+	if n.Synced {
+		return "Synced"
+	}
+	return "Initialized"
+}
+
+// listFiles returns the paths under a directory, one per line
+func (n *fakeNode) listFiles(dir string) string {
+	var paths []string
+	for path := range n.Files {
+		if strings.HasPrefix(path, dir) && strings.HasSuffix(path, ".cnf") {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		return ""
+	}
+	return strings.Join(paths, "\n") + "\n"
+}
+
+// grastateSeqno returns the seqno stored in grastate.dat, or nothing if the file is missing
+func (n *fakeNode) grastateSeqno() string {
+	content, ok := n.Files["/var/lib/mysql/grastate.dat"]
+	if !ok {
+		return ""
+	}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "seqno:" {
+			return fields[1] + "\n"
+		}
+	}
+	return ""
+}
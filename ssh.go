@@ -2,8 +2,10 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -11,21 +13,126 @@ import (
 	"golang.org/x/term"
 )
 
-// Close closes the SSH connection
-func (s *SSHClient) Close() error {
-	return s.client.Close()
+// Keepalive settings for pooled SSH connections
+const (
+	sshKeepaliveInterval = 30 * time.Second
+	sshKeepaliveTimeout  = 15 * time.Second
+)
+
+// sshHandshakes counts the SSH connections established during this run
+var sshHandshakes int64
+
+// dialSSH establishes an SSH connection and starts its keepalive loop
+func dialSSH(host string, config *ssh.ClientConfig) (*SSHClient, error) {
+	// Add default port if not specified
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+
+	client, err := ssh.Dial("tcp", host, config)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&sshHandshakes, 1)
+
+	s := &SSHClient{client: client, address: host, config: config, done: make(chan struct{})}
+	go s.keepalive()
+	return s, nil
 }
 
-// executeCommand executes a command on the remote server via SSH
-func (s *SSHClient) executeCommand(command string) (string, error) {
-	session, err := s.client.NewSession()
+// newSession opens a session on the shared connection, reconnecting once if the connection was lost
+func (s *SSHClient) newSession() (*ssh.Session, error) {
+	s.mu.Lock()
+	client := s.client
+	s.mu.Unlock()
+
+	session, err := client.NewSession()
+	if err == nil {
+		return session, nil
+	}
+
+	logVerbose("SSH connection to %s lost (%v), reconnecting...", s.address, err)
+	if err := s.reconnect(client); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	client = s.client
+	s.mu.Unlock()
+	return client.NewSession()
+}
+
+// reconnect replaces a broken connection with a new one, unless another caller already did
+func (s *SSHClient) reconnect(stale *ssh.Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("SSH connection to %s is closed", s.address)
+	}
+	if s.client != stale {
+		return nil
+	}
+
+	stale.Close()
+	client, err := ssh.Dial("tcp", s.address, s.config)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("error re-establishing SSH connection to %s: %v", s.address, err)
 	}
-	defer session.Close()
+	atomic.AddInt64(&sshHandshakes, 1)
+	s.client = client
+	return nil
+}
 
-	output, err := session.CombinedOutput(command)
-	return string(output), err
+// keepalive periodically checks the connection; a dead connection is closed so the next
+// command reconnects instead of hanging
+func (s *SSHClient) keepalive() {
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		client := s.client
+		s.mu.Unlock()
+
+		result := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			result <- err
+		}()
+
+		select {
+		case <-s.done:
+			return
+		case err := <-result:
+			if err != nil {
+				logDebug("SSH keepalive to %s failed: %v", s.address, err)
+				client.Close()
+			}
+		case <-time.After(sshKeepaliveTimeout):
+			logDebug("SSH keepalive to %s timed out", s.address)
+			client.Close()
+		}
+	}
+}
+
+// Close stops the keepalive loop and closes the SSH connection
+func (s *SSHClient) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.client == nil {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	return s.client.Close()
 }
 
 // createSSHConnectionWithNodeCredentials creates SSH connection using node-specific credentials
@@ -52,7 +159,7 @@ func createSSHConnectionWithNodeCredentials(host string, config *Config) (*SSHCl
 			logVerbose("      ⚠️  SSH keys failed for %s: %v", host, err)
 		}
 
-		if creds.HasSSHPassword {
+		if creds.HasSSHPassword || creds.SSHPasswordRef != "" {
 			// Try saved password
 			logVerbose("      🔐 Trying saved password for %s...", host)
 			password, err := config.getNodeSSHPassword(host)
@@ -63,10 +170,11 @@ func createSSHConnectionWithNodeCredentials(host string, config *Config) (*SSHCl
 				if err == nil {
 					logVerbose("      ✓ Connected to %s using saved password", host)
 					return client, &SSHConnectionInfo{
-						Username:    creds.SSHUsername,
-						Password:    password,
-						HasPassword: true,
-						UsedKeys:    false,
+						Username:        creds.SSHUsername,
+						Password:        password,
+						HasPassword:     true,
+						PasswordFromRef: creds.SSHPasswordRef != "",
+						UsedKeys:        false,
 					}, nil
 				}
 				logVerbose("      ⚠️  Saved password failed for %s: %v", host, err)
@@ -78,9 +186,13 @@ func createSSHConnectionWithNodeCredentials(host string, config *Config) (*SSHCl
 		return createSSHConnectionWithFallbackAndUsername(host, creds.SSHUsername)
 	}
 
-	// No saved credentials, use fallback
+	// No saved credentials, use fallback with the username chosen for this run
 	logVerbose("      🆕 No saved credentials for %s, using fallback authentication", host)
-	return createSSHConnectionWithFallbackAndUsername(host, "root")
+	username := config.LastSSHUsername
+	if username == "" || username == "local" {
+		username = "root"
+	}
+	return createSSHConnectionWithFallbackAndUsername(host, username)
 }
 
 // createSSHConnectionWithFallbackAndUsername creates SSH connection with specific username
@@ -103,6 +215,20 @@ func createSSHConnectionWithFallbackAndUsername(host, username string) (*SSHClie
 
 	logNormal("⚠️  Connection with keys failed: %v", err)
 
+	// A password file given on the command line replaces the prompt
+	if cliOptions.SSHPasswordFile != "" {
+		password, err := readPasswordFile(cliOptions.SSHPasswordFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		logNormal("🔐 Attempting connection with the password from %s...", cliOptions.SSHPasswordFile)
+		connInfo.Password = password
+		connInfo.HasPassword = true
+		connInfo.PasswordFromRef = true
+		client, err := createSSHConnectionWithPassword(host, username, password)
+		return client, connInfo, err
+	}
+
 	// If using -y flag, don't prompt for password - skip this node
 	if useDefaults {
 		logNormal("⚠️  -y flag active: skipping password prompt for node %s", host)
@@ -157,17 +283,12 @@ func createSSHConnectionWithKeys(host, username string) (*SSHClient, error) {
 		Timeout:         5 * time.Second, // Shorter timeout for key attempt
 	}
 
-	// Add default port if not specified
-	if !strings.Contains(host, ":") {
-		host += ":22"
-	}
-
-	client, err := ssh.Dial("tcp", host, config)
+	client, err := dialSSH(host, config)
 	if err != nil {
 		return nil, fmt.Errorf("error establishing SSH connection with keys: %v", err)
 	}
 
-	return &SSHClient{client: client}, nil
+	return client, nil
 }
 
 // loadPrivateKey loads a private key from file
@@ -196,15 +317,10 @@ func createSSHConnectionWithPassword(host, username, password string) (*SSHClien
 		Timeout:         10 * time.Second,
 	}
 
-	// Add default port if not specified
-	if !strings.Contains(host, ":") {
-		host += ":22"
-	}
-
-	client, err := ssh.Dial("tcp", host, config)
+	client, err := dialSSH(host, config)
 	if err != nil {
 		return nil, fmt.Errorf("error establishing SSH connection: %v", err)
 	}
 
-	return &SSHClient{client: client}, nil
+	return client, nil
 }
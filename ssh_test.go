@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHServer is a minimal SSH server that answers every exec request with the command itself
+type testSSHServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	mu       sync.Mutex
+	conns    []net.Conn
+}
+
+// newTestSSHServer starts an SSH server on a random local port accepting password "secret"
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, ssh.ErrNoAuth
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &testSSHServer{listener: listener, config: config}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *testSSHServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *testSSHServer) handle(conn net.Conn) {
+	_, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer channel.Close()
+			for request := range channelRequests {
+				if request.Type != "exec" {
+					request.Reply(false, nil)
+					continue
+				}
+				request.Reply(true, nil)
+				// The payload is the command as an SSH string: 4-byte length followed by the bytes
+				command := request.Payload[4:]
+				channel.Write(command)
+				status := make([]byte, 4)
+				binary.BigEndian.PutUint32(status, 0)
+				channel.SendRequest("exit-status", false, status)
+				return
+			}
+		}()
+	}
+}
+
+// dropConnections closes every client connection, as a network failure would
+func (s *testSSHServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func TestSSHClientReusesAndReconnects(t *testing.T) {
+	server := newTestSSHServer(t)
+	start := atomic.LoadInt64(&sshHandshakes)
+
+	client, err := createSSHConnectionWithPassword(server.listener.Addr().String(), "root", "secret")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Close()
+
+	for _, command := range []string{"first", "second", "third"} {
+		result, err := client.Run(context.Background(), command, nil)
+		if err != nil {
+			t.Fatalf("run %s: %v", command, err)
+		}
+		if result.Stdout != command || result.ExitCode != 0 {
+			t.Errorf("run %s: got %q (exit %d)", command, result.Stdout, result.ExitCode)
+		}
+	}
+	if handshakes := atomic.LoadInt64(&sshHandshakes) - start; handshakes != 1 {
+		t.Errorf("%d handshakes for three commands, want 1", handshakes)
+	}
+
+	server.dropConnections()
+
+	result, err := client.Run(context.Background(), "after-drop", nil)
+	if err != nil {
+		t.Fatalf("run after dropped connection: %v", err)
+	}
+	if result.Stdout != "after-drop" {
+		t.Errorf("run after dropped connection: got %q", result.Stdout)
+	}
+	if handshakes := atomic.LoadInt64(&sshHandshakes) - start; handshakes != 2 {
+		t.Errorf("%d handshakes after reconnect, want 2", handshakes)
+	}
+
+	client.Close()
+	if _, err := client.Run(context.Background(), "closed", nil); err == nil {
+		t.Error("expected an error running a command on a closed client")
+	}
+}
+
+func TestSSHPasswordRefIsNotSaved(t *testing.T) {
+	server := newTestSSHServer(t)
+	host := server.listener.Addr().String()
+	useTempHome(t)
+	t.Setenv("GALERAHEALTH_TEST_SSH_PASSWORD", "secret")
+	closeNodeExecutors()
+	t.Cleanup(closeNodeExecutors)
+
+	config := newTestConfig(t)
+	config.NodeCredentials = []NodeCredentials{{NodeIP: host, SSHUsername: "root", SSHPasswordRef: "env:GALERAHEALTH_TEST_SSH_PASSWORD"}}
+	_, connInfo, err := openNodeExecutor(host, config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if connInfo == nil || !connInfo.HasPassword || !connInfo.PasswordFromRef {
+		t.Fatalf("connection info = %+v", connInfo)
+	}
+	if saved, err := config.saveSSHConnection(host, connInfo); err != nil || saved {
+		t.Errorf("saveSSHConnection = %v, %v", saved, err)
+	}
+	if err := saveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	creds := loadConfig().getNodeCredentials(host)
+	if creds == nil || creds.EncryptedSSHPassword != "" || creds.HasSSHPassword || creds.SSHPasswordRef == "" {
+		t.Errorf("saved credentials = %+v", creds)
+	}
+
+	// A password typed at the prompt is saved
+	typed := &SSHConnectionInfo{Username: "root", Password: "typed", HasPassword: true}
+	if saved, err := config.saveSSHConnection("10.0.0.9", typed); err != nil || !saved || !config.getNodeCredentials("10.0.0.9").HasSSHPassword {
+		t.Errorf("typed password not saved: %v, %v", saved, err)
+	}
+}
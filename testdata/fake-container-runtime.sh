@@ -0,0 +1,67 @@
+#!/bin/bash
+
+# Stand-in for docker/podman used to exercise the container transport locally
+#
+# Usage: set "runtime": "/path/to/fake-container-runtime.sh" in a node's transport.
+# Container state is kept in $FAKE_RUNTIME_DIR (default /tmp/fake-container-runtime),
+# one "<status> <exit code>" file per container. "exec" runs the command on this host.
+
+STATE_DIR="${FAKE_RUNTIME_DIR:-/tmp/fake-container-runtime}"
+mkdir -p "$STATE_DIR"
+
+state_file() {
+    echo "$STATE_DIR/$1.state"
+}
+
+get_state() {
+    if [ -f "$(state_file "$1")" ]; then
+        cat "$(state_file "$1")"
+    else
+        echo "running 0"
+    fi
+}
+
+command="$1"
+shift
+
+case "$command" in
+    exec)
+        name="$1"
+        shift
+        if [ "$(get_state "$name" | awk '{print $1}')" != "running" ]; then
+            echo "Error: container $name is not running" >&2
+            exit 1
+        fi
+        CONTAINER_NAME="$name" exec "$@"
+        ;;
+    inspect)
+        # Only the -f '{{.State.Status}} {{.State.ExitCode}}' form is supported
+        name="${!#}"
+        get_state "$name"
+        ;;
+    start|restart)
+        echo "running 0" > "$(state_file "$1")"
+        echo "$1"
+        ;;
+    stop)
+        echo "exited 0" > "$(state_file "$1")"
+        echo "$1"
+        ;;
+    kill)
+        echo "exited 137" > "$(state_file "$1")"
+        echo "$1"
+        ;;
+    logs)
+        name="${!#}"
+        echo "[Note] WSREP: fake log line for $name"
+        ;;
+    ps)
+        for f in "$STATE_DIR"/*.state; do
+            [ -f "$f" ] && echo "$(basename "$f" .state) $(cat "$f")"
+        done
+        ;;
+    *)
+        echo "fake-container-runtime: unsupported command $command" >&2
+        exit 1
+        ;;
+esac
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MembershipDiff compares the nodes configured in wsrep_cluster_address with the members of the running cluster
+type MembershipDiff struct {
+	Configured    []string `json:"configured"`               // Nodes in wsrep_cluster_address
+	Running       []string `json:"running"`                  // Nodes in wsrep_incoming_addresses on any reachable node
+	ClusterSize   int      `json:"cluster_size"`             // Largest wsrep_cluster_size reported
+	NotInCluster  []string `json:"not_in_cluster,omitempty"` // Configured, reachable, but not a member (stale or stopped)
+	NotConfigured []string `json:"not_configured,omitempty"` // Members missing from wsrep_cluster_address
+	Unreachable   []string `json:"unreachable,omitempty"`    // Configured but could not be analyzed
+	SizeMismatch  string   `json:"size_mismatch,omitempty"`  // Nodes disagree on the cluster size
+}
+
+// queryRuntimeMembership reads wsrep_incoming_addresses and wsrep_cluster_size, and the node's
+// wsrep_gcomm_uuid and server_uuid, with the saved MySQL credentials, without prompting. Nodes where
+// MySQL can't be queried are left without runtime data.
+func queryRuntimeMembership(executor NodeExecutor, nodeIP string, config *Config, info *GaleraClusterInfo) {
+	candidates := mysqlCredentialCandidates(nodeIP, config, nil)
+	if creds, ok := workingMySQLCredentials[nodeIP]; ok {
+		candidates = []*MySQLConnectionInfo{creds}
+	}
+
+	query := "SHOW GLOBAL STATUS WHERE Variable_name IN ('wsrep_incoming_addresses', 'wsrep_cluster_size', 'wsrep_gcomm_uuid'); " +
+		"SHOW GLOBAL VARIABLES WHERE Variable_name = 'server_uuid';"
+	for _, creds := range candidates {
+		output, err := runCommand(executor, fmt.Sprintf("%s -N -e \"%s\" 2>&1", buildMySQLClientCommand(creds), query))
+		if err != nil || strings.Contains(output, "ERROR") {
+			continue
+		}
+		workingMySQLCredentials[nodeIP] = creds
+
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[0] {
+			case "wsrep_incoming_addresses":
+				info.IncomingAddresses = parseIncomingAddresses(fields[1])
+			case "wsrep_cluster_size":
+				info.RuntimeClusterSize, _ = strconv.Atoi(fields[1])
+			case "wsrep_gcomm_uuid":
+				info.GcommUUID = fields[1]
+			case "server_uuid":
+				info.ServerUUID = fields[1]
+			}
+		}
+		logVerbose("      🔗 Running cluster seen by %s: %d members %v", nodeIP, info.RuntimeClusterSize, info.IncomingAddresses)
+		return
+	}
+	logVerbose("      ⚠️  Could not read the running cluster membership from %s", nodeIP)
+}
+
+// parseIncomingAddresses returns the hosts in a wsrep_incoming_addresses value ("10.0.0.1:3306,[fd00::2]:3306")
+func parseIncomingAddresses(value string) []string {
+	var hosts []string
+	for _, address := range strings.Split(value, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" || address == "AUTO" {
+			continue
+		}
+		member, err := parseGcommMember(address)
+		if err != nil {
+			logDebug("Ignoring incoming address %q: %v", address, err)
+			continue
+		}
+		hosts = append(hosts, member.Host)
+	}
+	return hosts
+}
+
+// runningMembers returns the union of the members seen by every analyzed node, in discovery order
+func (a *ClusterAnalysis) runningMembers() []string {
+	var members []string
+	seen := make(map[string]bool)
+	for _, node := range a.AllNodes {
+		for _, host := range node.IncomingAddresses {
+			if !seen[host] {
+				seen[host] = true
+				members = append(members, host)
+			}
+		}
+	}
+	return members
+}
+
+// unconfiguredMembers returns the running members that are not in wsrep_cluster_address and were not analyzed yet
+func (a *ClusterAnalysis) unconfiguredMembers(skip []string) []string {
+	var extra []string
+	for _, host := range a.runningMembers() {
+		if containsHost(a.ClusterNodes, host) || containsHost(skip, host) || isLocalhost(host) || a.findNode(host) != nil {
+			continue
+		}
+		extra = append(extra, host)
+	}
+	return extra
+}
+
+// compareMembership builds the membership diff; skip lists addresses that refer to the initial node
+func (a *ClusterAnalysis) compareMembership(skip []string) *MembershipDiff {
+	running := a.runningMembers()
+	if len(running) == 0 {
+		return nil
+	}
+
+	diff := &MembershipDiff{Configured: a.ClusterNodes, Running: running}
+	isInitial := func(host string) bool {
+		return containsHost(a.InitialNode.hosts(), host) || containsHost(skip, host) || isLocalhost(host)
+	}
+	// A node is in the running cluster, or configured, under any of its addresses
+	anyHostIn := func(list []string, host string) bool {
+		if node := a.findNode(host); node != nil {
+			for _, h := range node.hosts() {
+				if containsHost(list, h) {
+					return true
+				}
+			}
+		}
+		return containsHost(list, host)
+	}
+	initialRunning := false
+	for _, host := range running {
+		if isInitial(host) {
+			initialRunning = true
+		}
+	}
+
+	sizes := make(map[int][]string)
+	for _, node := range a.AllNodes {
+		if node.RuntimeClusterSize > 0 {
+			sizes[node.RuntimeClusterSize] = append(sizes[node.RuntimeClusterSize], node.NodeIP)
+			if node.RuntimeClusterSize > diff.ClusterSize {
+				diff.ClusterSize = node.RuntimeClusterSize
+			}
+		}
+	}
+	if len(sizes) > 1 {
+		var parts []string
+		for size, nodes := range sizes {
+			parts = append(parts, fmt.Sprintf("%s see %d", strings.Join(nodes, ","), size))
+		}
+		sort.Strings(parts)
+		diff.SizeMismatch = strings.Join(parts, "; ")
+	}
+
+	compared := make(map[*GaleraClusterInfo]bool)
+	for _, host := range a.ClusterNodes {
+		node := a.findNode(host)
+		if node != nil {
+			// A node listed under several addresses is reported once
+			if compared[node] {
+				continue
+			}
+			compared[node] = true
+		}
+		if isInitial(host) {
+			if !initialRunning {
+				diff.NotInCluster = append(diff.NotInCluster, host)
+			}
+			continue
+		}
+		if node == nil || node.StatusError != "" {
+			diff.Unreachable = append(diff.Unreachable, host)
+			continue
+		}
+		if !anyHostIn(running, host) {
+			diff.NotInCluster = append(diff.NotInCluster, host)
+		}
+	}
+	for _, host := range running {
+		if !anyHostIn(a.ClusterNodes, host) && !isInitial(host) {
+			diff.NotConfigured = append(diff.NotConfigured, host)
+		}
+	}
+	return diff
+}
+
+// findNode returns the analyzed node with the given address or alias, or nil
+func (a *ClusterAnalysis) findNode(nodeIP string) *GaleraClusterInfo {
+	for _, node := range a.AllNodes {
+		if containsHost(node.hosts(), nodeIP) {
+			return node
+		}
+	}
+	return nil
+}
+
+// displayMembership prints the membership diff
+func displayMembership(diff *MembershipDiff) {
+	if diff == nil {
+		fmt.Println("🔗 Running cluster membership: unknown (MySQL could not be queried with saved credentials)")
+		return
+	}
+
+	fmt.Printf("🔗 Running cluster: %d members (%s)\n", diff.ClusterSize, strings.Join(diff.Running, ", "))
+	if len(diff.NotInCluster) == 0 && len(diff.NotConfigured) == 0 && len(diff.Unreachable) == 0 && diff.SizeMismatch == "" {
+		fmt.Println("   ✅ Matches wsrep_cluster_address")
+		return
+	}
+	if len(diff.NotInCluster) > 0 {
+		fmt.Printf("   ⚠️  Configured but not in the cluster: %s\n", strings.Join(diff.NotInCluster, ", "))
+	}
+	if len(diff.NotConfigured) > 0 {
+		fmt.Printf("   ⚠️  In the cluster but not configured: %s\n", strings.Join(diff.NotConfigured, ", "))
+	}
+	if len(diff.Unreachable) > 0 {
+		fmt.Printf("   ❌ Configured but unreachable: %s\n", strings.Join(diff.Unreachable, ", "))
+	}
+	if diff.SizeMismatch != "" {
+		fmt.Printf("   ⚠️  Nodes disagree on the cluster size: %s\n", diff.SizeMismatch)
+	}
+}
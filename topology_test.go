@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIncomingAddresses(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.1:3306,10.0.0.2:3306":      "10.0.0.1,10.0.0.2",
+		"AUTO,10.0.0.2:3306":               "10.0.0.2",
+		"[fd00::1]:3306, db3.example:3306": "fd00::1,db3.example",
+		"10.0.0.5":                         "10.0.0.5",
+		"":                                 "",
+	}
+	for value, want := range tests {
+		if got := strings.Join(parseIncomingAddresses(value), ","); got != want {
+			t.Errorf("parseIncomingAddresses(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestTopologyDiscovery(t *testing.T) {
+	configured := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip, service string, members ...string) *fakeNode {
+		return &fakeNode{IP: ip, Service: service, Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...)}}
+	}
+	newFakeCluster(t,
+		node("10.0.0.1", "active", configured...),
+		node("10.0.0.2", "active", configured...),
+		// Stale entry: reachable but no longer running
+		node("10.0.0.3", "inactive", configured...),
+		// Joined later through another address list
+		node("10.0.0.4", "active", "10.0.0.1", "10.0.0.2", "10.0.0.4"),
+	)
+	config := newTestConfig(t)
+
+	executor, err := getNodeExecutor("10.0.0.1", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialNode, err := getGaleraClusterInfo(executor, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, _, err := performClusterAnalysis(initialNode, &SSHConnectionInfo{Username: "root", UsedKeys: true}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if extra := analysis.findNode("10.0.0.4"); extra == nil || extra.RuntimeClusterSize != 3 {
+		t.Fatalf("unconfigured member not analyzed: %+v", extra)
+	}
+	diff := analysis.Membership
+	if diff == nil {
+		t.Fatal("no membership diff")
+	}
+	if diff.ClusterSize != 3 || strings.Join(diff.Running, ",") != "10.0.0.1,10.0.0.2,10.0.0.4" {
+		t.Errorf("running cluster: size %d, members %v", diff.ClusterSize, diff.Running)
+	}
+	if strings.Join(diff.NotInCluster, ",") != "10.0.0.3" || strings.Join(diff.NotConfigured, ",") != "10.0.0.4" {
+		t.Errorf("diff: not in cluster %v, not configured %v", diff.NotInCluster, diff.NotConfigured)
+	}
+	if len(diff.Unreachable) != 0 || diff.SizeMismatch != "" {
+		t.Errorf("diff: unreachable %v, size mismatch %q", diff.Unreachable, diff.SizeMismatch)
+	}
+
+	health := evaluateClusterHealth(analysis)
+	assertErrors(t, health.Warnings, []string{"not in the running cluster: 10.0.0.3", "missing from wsrep_cluster_address: 10.0.0.4"})
+}
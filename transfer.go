@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sstProcessQuery lists the running SST helper scripts (wsrep_sst_rsync, wsrep_sst_mariabackup, ...)
+// with the seconds they have been running
+const sstProcessQuery = "ps -eo etimes=,args= 2>/dev/null | grep '[w]srep_sst_'"
+
+// Service log window searched for state transfer events
+const (
+	transferLogSince = "6 hour ago"
+	transferLogLines = 2000
+)
+
+// Transfer kinds and roles
+const (
+	transferSST    = "SST"
+	transferIST    = "IST"
+	transferDonor  = "donor"
+	transferJoiner = "joiner"
+)
+
+var (
+	sstHelperPattern  = regexp.MustCompile(`wsrep_sst_([\w-]+)`)
+	sstRolePattern    = regexp.MustCompile(`--role\s+'?(\w+)`)
+	sstAddressPattern = regexp.MustCompile(`--address\s+'?([^'\s]+)`)
+	// "Member 2.0 (node3) requested state transfer from '*any*'. Selected 0.0 (node1)(SYNCED) as donor."
+	transferRequestPattern = regexp.MustCompile(`Member \S+ \(([^)]*)\) requested state transfer from '[^']*'\. Selected \S+ \(([^)]*)\)`)
+	istPattern             = regexp.MustCompile(`IST receiver|IST sender|Receiving IST|async IST`)
+)
+
+// NodeTransfer is the part a node plays in a running state transfer
+type NodeTransfer struct {
+	Role      string        // donor or joiner
+	Kind      string        // SST or IST ("" if unknown)
+	Method    string        // rsync, mariabackup, mysqldump, xtrabackup-v2, ...
+	Peer      string        // Joiner address from the donor's SST helper
+	DonorName string        // wsrep_node_name of the donor selected for this joiner, from the log
+	Elapsed   time.Duration // Run time of the SST helper (0 if unknown)
+	DataBytes int64         // Datadir size of a joiner
+}
+
+// StateTransfer is a running SST or IST between a donor and a joiner
+type StateTransfer struct {
+	Joiner     string  `json:"joiner"`
+	Donor      string  `json:"donor,omitempty"`
+	Kind       string  `json:"kind,omitempty"`
+	Method     string  `json:"method,omitempty"`
+	Elapsed    float64 `json:"elapsed_seconds,omitempty"`
+	Received   int64   `json:"received_bytes,omitempty"` // Joiner datadir size
+	Expected   int64   `json:"expected_bytes,omitempty"` // Donor datadir size
+	ProgressPc float64 `json:"progress_percent"`         // -1 if it can't be estimated
+}
+
+// isJoinerState reports whether wsrep_local_state_comment is one of a node receiving a state transfer
+func isJoinerState(state string) bool {
+	state = strings.ToLower(strings.TrimSuffix(state, ":"))
+	return strings.HasPrefix(state, "joining") || state == "joiner" || state == "joined"
+}
+
+// isDonorState reports whether wsrep_local_state_comment is the one of a donor
+func isDonorState(state string) bool {
+	return strings.HasPrefix(strings.ToLower(state), "donor")
+}
+
+// parseSSTProcesses returns the role, method, joiner address and run time of the SST helper in
+// sstProcessQuery output; the longest running process is the helper script itself
+func parseSSTProcesses(output string) (role, method, address string, elapsed time.Duration, found bool) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		seconds, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		helper := sstHelperPattern.FindStringSubmatch(line)
+		if helper == nil {
+			continue
+		}
+		if found && time.Duration(seconds)*time.Second <= elapsed {
+			continue
+		}
+		found = true
+		method, elapsed = helper[1], time.Duration(seconds)*time.Second
+		role, address = "", ""
+		if matches := sstRolePattern.FindStringSubmatch(line); matches != nil {
+			role = matches[1]
+		}
+		if matches := sstAddressPattern.FindStringSubmatch(line); matches != nil {
+			address = matches[1]
+		}
+	}
+	return role, method, address, elapsed, found
+}
+
+// parseTransferLog returns the donor selected for the joiner named nodeName, the SST method run and
+// whether the latest transfer is an IST, from service log lines
+func parseTransferLog(output, nodeName string) (donorName, method string, ist bool) {
+	// Lines after a request for another joiner are about that transfer
+	following := true
+	for _, line := range strings.Split(output, "\n") {
+		if matches := transferRequestPattern.FindStringSubmatch(line); matches != nil {
+			following = nodeName == "" || matches[1] == nodeName
+			if following {
+				donorName, method, ist = matches[2], "", false
+			}
+			continue
+		}
+		if !following {
+			continue
+		}
+		if strings.Contains(line, "Running: 'wsrep_sst_") || strings.Contains(line, "Running: wsrep_sst_") {
+			if helper := sstHelperPattern.FindStringSubmatch(line); helper != nil {
+				method = helper[1]
+			}
+		}
+		if istPattern.MatchString(line) {
+			ist = true
+		}
+	}
+	return donorName, method, ist
+}
+
+// detectNodeTransfer finds out whether a node takes part in a state transfer, from its
+// wsrep state, the SST helper processes and the service log. It returns nil if it doesn't.
+func detectNodeTransfer(executor NodeExecutor, service *ServiceBackend, node *GaleraClusterInfo) *NodeTransfer {
+	output, _ := runCommand(executor, sstProcessQuery)
+	role, method, address, elapsed, running := parseSSTProcesses(output)
+	activating := !node.MySQLResponding && strings.Contains(node.StatusError, "activating")
+	if !running && !isJoinerState(node.LocalStateComment) && !isDonorState(node.LocalStateComment) && !activating {
+		return nil
+	}
+
+	transfer := &NodeTransfer{Role: role, Method: method, Elapsed: elapsed}
+	if running {
+		transfer.Kind = transferSST
+		if role == transferDonor {
+			// The donor sends to --address host:port/module
+			target, _, _ := strings.Cut(address, "/")
+			if member, err := parseGcommMember(target); err == nil {
+				transfer.Peer = member.Host
+			}
+		}
+	}
+	if transfer.Role == "" {
+		transfer.Role = transferJoiner
+		if isDonorState(node.LocalStateComment) {
+			transfer.Role = transferDonor
+		}
+	}
+
+	if service != nil {
+		logOutput, _ := runCommand(serviceExecutor(executor, service),
+			service.logCommand(transferLogSince, transferLogLines)+" | grep -E 'state transfer|IST|SST|wsrep_sst_' | tail -50")
+		// A donor's log names other nodes as joiners: follow the latest request
+		joinerName := node.NodeName
+		if transfer.Role == transferDonor {
+			joinerName = ""
+		}
+		donorName, logMethod, ist := parseTransferLog(logOutput, joinerName)
+		if transfer.Role == transferJoiner {
+			transfer.DonorName = donorName
+		}
+		if transfer.Method == "" {
+			transfer.Method = logMethod
+		}
+		if transfer.Kind == "" && ist {
+			transfer.Kind = transferIST
+		}
+	}
+	if transfer.Method == "" && transfer.Kind != transferIST {
+		transfer.Method = node.SSTMethod
+	}
+
+	if transfer.Role == transferJoiner {
+		dataDir := defaultDataDir
+		if node.DataDir != "" {
+			dataDir = node.DataDir
+		}
+		transfer.DataBytes = resourcesUnknown
+		// The joiner's datadir grows during the transfer, so it is measured on every check
+		if size, err := measureDataSize(executor, dataDir); err == nil {
+			transfer.DataBytes = size
+		} else {
+			logVerbose("      ⚠️  Could not measure the datadir of joiner %s: %v", node.NodeIP, err)
+		}
+	}
+	logVerbose("      🔄 %s takes part in a state transfer as %s (%s)", node.NodeIP, transfer.Role, transfer.describe())
+	return transfer
+}
+
+// describe returns the kind and method of the transfer for display
+func (t *NodeTransfer) describe() string {
+	kind := t.Kind
+	if kind == "" {
+		kind = "SST/IST"
+	}
+	if t.Method != "" && t.Kind != transferIST {
+		kind += " " + t.Method
+	}
+	if t.Elapsed > 0 {
+		kind += ", running " + t.Elapsed.String()
+	}
+	return kind
+}
+
+// pairStateTransfers matches the joiners with their donors and estimates the progress of each
+// transfer from the joiner's datadir growth against the donor's data size
+func (a *ClusterAnalysis) pairStateTransfers() []StateTransfer {
+	var transfers []StateTransfer
+	paired := make(map[*GaleraClusterInfo]bool)
+
+	findDonor := func(joiner *GaleraClusterInfo) *GaleraClusterInfo {
+		for _, node := range a.AllNodes {
+			if node.Transfer == nil || node.Transfer.Role != transferDonor || paired[node] {
+				continue
+			}
+			if node.Transfer.Peer != "" && containsHost(joiner.hosts(), node.Transfer.Peer) {
+				return node
+			}
+			if joiner.Transfer.DonorName != "" && node.NodeName == joiner.Transfer.DonorName {
+				return node
+			}
+		}
+		return nil
+	}
+
+	for _, joiner := range a.AllNodes {
+		if joiner.Transfer == nil || joiner.Transfer.Role != transferJoiner {
+			continue
+		}
+		transfer := StateTransfer{Joiner: joiner.NodeIP, Kind: joiner.Transfer.Kind, Method: joiner.Transfer.Method,
+			Received: joiner.Transfer.DataBytes, ProgressPc: -1}
+		elapsed := joiner.Transfer.Elapsed
+		if donor := findDonor(joiner); donor != nil {
+			paired[donor] = true
+			transfer.Donor = donor.NodeIP
+			if transfer.Kind == "" {
+				transfer.Kind = donor.Transfer.Kind
+			}
+			if transfer.Method == "" || donor.Transfer.Kind == transferSST {
+				transfer.Method = donor.Transfer.Method
+			}
+			if donor.Transfer.Elapsed > elapsed {
+				elapsed = donor.Transfer.Elapsed
+			}
+			if donor.Resources != nil && donor.Resources.DataSize > 0 {
+				transfer.Expected = donor.Resources.DataSize
+			}
+		} else if joiner.Transfer.DonorName != "" {
+			transfer.Donor = joiner.Transfer.DonorName
+		}
+		transfer.Elapsed = elapsed.Seconds()
+
+		// mysqldump loads through SQL and an IST only appends to the existing data, so the datadir
+		// size says nothing about their progress
+		if transfer.Kind != transferIST && transfer.Method != "mysqldump" && transfer.Expected > 0 && transfer.Received >= 0 {
+			transfer.ProgressPc = float64(transfer.Received) * 100 / float64(transfer.Expected)
+			if transfer.ProgressPc > 100 {
+				transfer.ProgressPc = 100
+			}
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	// Donors whose joiner could not be analyzed
+	for _, donor := range a.AllNodes {
+		if donor.Transfer == nil || donor.Transfer.Role != transferDonor || paired[donor] {
+			continue
+		}
+		transfers = append(transfers, StateTransfer{Joiner: donor.Transfer.Peer, Donor: donor.NodeIP, Kind: donor.Transfer.Kind,
+			Method: donor.Transfer.Method, Elapsed: donor.Transfer.Elapsed.Seconds(), ProgressPc: -1})
+	}
+	return transfers
+}
+
+// String describes the transfer for display and health warnings
+func (t StateTransfer) String() string {
+	kind := t.Kind
+	if kind == "" {
+		kind = "State transfer"
+	}
+	if t.Method != "" && t.Kind != transferIST {
+		kind += " (" + t.Method + ")"
+	}
+	joiner, donor := t.Joiner, t.Donor
+	if joiner == "" {
+		joiner = "unknown joiner"
+	}
+	if donor == "" {
+		donor = "unknown donor"
+	}
+	description := fmt.Sprintf("%s from %s to %s", kind, donor, joiner)
+	if t.Elapsed > 0 {
+		description += ", running " + (time.Duration(t.Elapsed) * time.Second).String()
+	}
+	switch {
+	case t.ProgressPc >= 0:
+		description += fmt.Sprintf(", about %.0f%% (%s of %s)", t.ProgressPc, formatBytes(t.Received), formatBytes(t.Expected))
+	case t.Received > 0:
+		description += ", joiner datadir " + formatBytes(t.Received)
+	}
+	return description
+}
+
+// displayStateTransfers prints the running state transfers
+func displayStateTransfers(transfers []StateTransfer) {
+	if len(transfers) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("🔄 State transfers in progress:")
+	for _, transfer := range transfers {
+		fmt.Printf("   %s\n", transfer)
+	}
+}
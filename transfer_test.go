@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSSTProcesses(t *testing.T) {
+	output := "   300 /bin/bash -ue /usr/bin/wsrep_sst_mariabackup --role 'donor' --address '10.0.0.3:4444/xtrabackup_sst//1' --socket '/run/mysqld/mysqld.sock'\n" +
+		"    12 /bin/bash -ue /usr/bin/wsrep_sst_mariabackup --role 'donor' --address '10.0.0.3:4444/xtrabackup_sst//1'\n"
+	role, method, address, elapsed, found := parseSSTProcesses(output)
+	if !found || role != "donor" || method != "mariabackup" || address != "10.0.0.3:4444/xtrabackup_sst//1" || elapsed != 5*time.Minute {
+		t.Errorf("parseSSTProcesses = %s %s %s %s %t", role, method, address, elapsed, found)
+	}
+
+	log := "WSREP: Member 1.0 (node2) requested state transfer from '*any*'. Selected 0.0 (node1)(SYNCED) as donor.\n" +
+		"WSREP: Running: 'wsrep_sst_rsync --role 'joiner' --address '10.0.0.2' --datadir '/var/lib/mysql/'\n" +
+		"WSREP: Member 2.0 (node3) requested state transfer from '*any*'. Selected 1.0 (node2)(SYNCED) as donor.\n" +
+		"WSREP: Prepared IST receiver for 100-200, listening at: tcp://10.0.0.3:4568\n"
+	if donor, method, ist := parseTransferLog(log, "node2"); donor != "node1" || method != "rsync" || ist {
+		t.Errorf("parseTransferLog(node2) = %s %s %t", donor, method, ist)
+	}
+	if donor, method, ist := parseTransferLog(log, "node3"); donor != "node2" || method != "" || !ist {
+		t.Errorf("parseTransferLog(node3) = %s %s %t", donor, method, ist)
+	}
+}
+
+func TestStateTransferDetection(t *testing.T) {
+	previous := useDefaults
+	useDefaults = true
+	t.Cleanup(func() { useDefaults = previous })
+
+	members := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	node := func(ip string, name string) *fakeNode {
+		return &fakeNode{IP: ip, Service: "active", Synced: true,
+			Files: map[string]string{"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...) + fmt.Sprintf("wsrep_node_name = %s\n", name)}}
+	}
+	analyze := func(t *testing.T, nodes ...*fakeNode) *ClusterAnalysis {
+		newFakeCluster(t, nodes...)
+		config := newTestConfig(t)
+		executor, err := getNodeExecutor("10.0.0.1", config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		initialNode, err := getGaleraClusterInfo(executor, "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		analysis, _, err := performClusterAnalysis(initialNode, &SSHConnectionInfo{Username: "root", UsedKeys: true}, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkMySQLStatusOnAllNodes(analysis, &SSHConnectionInfo{Username: "root"}, &MySQLConnectionInfo{Username: "root"}, config, ""); err != nil {
+			t.Fatal(err)
+		}
+		return analysis
+	}
+
+	t.Run("SST", func(t *testing.T) {
+		donor, joiner := node("10.0.0.2", "node2"), node("10.0.0.3", "node3")
+		donor.State = "Donor/Desynced"
+		donor.DataSize = 1 << 30
+		donor.SSTProcess = "/bin/bash -ue /usr/bin/wsrep_sst_mariabackup --role 'donor' --address '10.0.0.3:4444/xtrabackup_sst//1'"
+		donor.SSTAge = 300
+		joiner.Service, joiner.Synced = "activating", false
+		joiner.DataSize = 256 << 20
+		joiner.SSTProcess = "/bin/bash -ue /usr/bin/wsrep_sst_mariabackup --role 'joiner' --address '10.0.0.3' --datadir '/var/lib/mysql/'"
+		joiner.SSTAge = 290
+
+		analysis := analyze(t, node("10.0.0.1", "node1"), donor, joiner)
+		if len(analysis.Transfers) != 1 {
+			t.Fatalf("transfers = %+v", analysis.Transfers)
+		}
+		want := "SST (mariabackup) from 10.0.0.2 to 10.0.0.3, running 5m0s, about 25% (256.0 MiB of 1.0 GiB)"
+		if got := analysis.Transfers[0].String(); got != want {
+			t.Errorf("transfer = %q, want %q", got, want)
+		}
+		assertErrors(t, evaluateClusterHealth(analysis).Warnings, []string{"State transfer in progress: " + want})
+	})
+
+	t.Run("IST", func(t *testing.T) {
+		donor, joiner := node("10.0.0.2", "node2"), node("10.0.0.3", "node3")
+		donor.State = "Donor/Desynced"
+		joiner.State = "Joining: receiving State Transfer"
+		joiner.Log = "WSREP: Member 2.0 (node3) requested state transfer from '*any*'. Selected 1.0 (node2)(SYNCED) as donor.\n" +
+			"WSREP: Prepared IST receiver for 100-200, listening at: tcp://10.0.0.3:4568\n"
+
+		analysis := analyze(t, node("10.0.0.1", "node1"), donor, joiner)
+		if len(analysis.Transfers) != 1 || analysis.Transfers[0].String() != "IST from 10.0.0.2 to 10.0.0.3, joiner datadir 100.0 MiB" {
+			t.Fatalf("transfers = %+v", analysis.Transfers)
+		}
+		for _, node := range analysis.AllNodes {
+			if node.NodeIP == "10.0.0.1" && node.Transfer != nil {
+				t.Errorf("10.0.0.1 takes part in a transfer: %+v", node.Transfer)
+			}
+		}
+		if strings.Contains(analysis.Transfers[0].String(), "%") {
+			t.Errorf("IST progress estimated from the datadir: %s", analysis.Transfers[0])
+		}
+	})
+}
@@ -1,6 +1,11 @@
 package main
 
-import "golang.org/x/crypto/ssh"
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
 
 // GaleraClusterInfo contains information about a Galera cluster node
 type GaleraClusterInfo struct {
@@ -9,6 +14,14 @@ type GaleraClusterInfo struct {
 	NodeName       string
 	NodeAddress    string
 	NodeIP         string
+	// Network settings from the configuration files
+	ProviderOptions   string // wsrep_provider_options
+	SSTReceiveAddress string // wsrep_sst_receive_address
+	SSTMethod         string // wsrep_sst_method
+	// Storage and memory settings from the configuration files
+	DataDir        string // datadir
+	TmpDir         string // tmpdir
+	BufferPoolSize string // innodb_buffer_pool_size
 	// MySQL/MariaDB status information
 	ClusterSize       int
 	ClusterStatus     string
@@ -16,6 +29,23 @@ type GaleraClusterInfo struct {
 	LocalStateComment string
 	MySQLResponding   bool
 	StatusError       string
+	// Runtime membership seen by the node during discovery
+	IncomingAddresses  []string // Hosts in wsrep_incoming_addresses
+	RuntimeClusterSize int      // wsrep_cluster_size (0 if MySQL could not be queried)
+	// Identity of the server, to recognize a node reached through several addresses
+	GcommUUID  string   // wsrep_gcomm_uuid
+	ServerUUID string   // server_uuid (MySQL only)
+	MachineID  string   // /etc/machine-id
+	Hostname   string   // hostname of the host
+	Aliases    []string // Other addresses the same node was reached through
+	// Clock compared with the machine running galerahealth (nil if not measured)
+	Clock *ClockStatus
+	// Disk, gcache and memory of the host (nil if not collected)
+	Resources *HostResources
+	// Server, provider, OS and package versions (nil if not collected)
+	Versions *NodeVersions
+	// Part played in a running SST/IST (nil if none)
+	Transfer *NodeTransfer
 }
 
 // ClusterAnalysis contains the results of analyzing cluster coherence
@@ -25,23 +55,39 @@ type ClusterAnalysis struct {
 	ClusterNodes []string
 	ConfigErrors []string
 	IsCoherent   bool
+	Membership   *MembershipDiff // Configured vs running cluster members (nil if no node could be queried)
+	MaxClockSkew time.Duration   // Clock offset reported as a warning
+	Transfers    []StateTransfer // Running SST/IST, found while checking MySQL status
+
+	quiet bool // No per-node progress output (background and bundle checks)
 }
 
 // SSHConnectionInfo holds information about SSH connection credentials and methods
 type SSHConnectionInfo struct {
-	Username    string
-	Password    string
-	HasPassword bool
-	UsedKeys    bool
+	Username        string
+	Password        string
+	HasPassword     bool
+	PasswordFromRef bool // Password read from ssh_password_ref or --ssh-password-file, never saved
+	UsedKeys        bool
 }
 
-// MySQLConnectionInfo holds MySQL/MariaDB connection credentials
+// MySQLConnectionInfo holds MySQL/MariaDB connection credentials. With LoginPath or OptionFile the
+// mysql client on the node reads the credentials itself and no password is handled here.
 type MySQLConnectionInfo struct {
-	Username string
-	Password string
+	Username   string
+	Password   string
+	LoginPath  string // --login-path stored with mysql_config_editor on the node
+	OptionFile bool   // Use the node's ~/.my.cnf (or socket authentication) instead of -u/-p
+	Source     string // Where the credentials come from, for messages (node, cluster, ...)
 }
 
-// SSHClient wraps the SSH client connection
+// SSHClient wraps the SSH connection shared by every command run on a node. Commands run in
+// sessions multiplexed on the single connection, which is kept alive and re-established if it drops.
 type SSHClient struct {
-	client *ssh.Client
+	client  *ssh.Client
+	address string
+	config  *ssh.ClientConfig
+	mu      sync.Mutex
+	done    chan struct{}
+	closed  bool
 }
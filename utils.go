@@ -1,7 +1,6 @@
 package main
 
 import (
-	"os/exec"
 	"strings"
 )
 
@@ -25,8 +24,10 @@ func isLocalhost(ip string) bool {
 
 // executeLocalCommand executes a command locally without SSH
 func executeLocalCommand(command string) (string, error) {
-	logDebug("Executing local command: %s", command)
-	cmd := exec.Command("bash", "-c", command)
-	output, err := cmd.Output()
-	return string(output), err
+	return runCommand(&LocalExecutor{}, command)
+}
+
+// shellQuote quotes a string for safe use as a single shell argument
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
 }
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default timeouts used while waiting for nodes after a recovery step
+const (
+	defaultBootstrapTimeout = 120 * time.Second
+	defaultJoinTimeout      = 30 * time.Minute
+	syncPollInterval        = 3 * time.Second
+)
+
+// NodeSyncStatus holds the live Galera state of a node while it is being verified
+type NodeSyncStatus struct {
+	ServiceState      string
+	MySQLResponding   bool
+	LocalStateComment string
+	ClusterSize       int
+	ClusterStatus     string
+	DataDirBytes      int64
+}
+
+// isSynced reports whether the node is Synced in a Primary component of at least the expected size
+func (s *NodeSyncStatus) isSynced(expectedSize int) bool {
+	return s.MySQLResponding &&
+		s.LocalStateComment == "Synced" &&
+		s.ClusterStatus == "Primary" &&
+		s.ClusterSize >= expectedSize
+}
+
+// isTransferring reports whether the node is currently receiving a state transfer (SST/IST)
+func (s *NodeSyncStatus) isTransferring() bool {
+	if s.ServiceState == "activating" {
+		return true
+	}
+	state := strings.ToLower(s.LocalStateComment)
+	return strings.HasPrefix(state, "joining") || state == "joiner"
+}
+
+// hasFailed reports whether the service on the node stopped while we were waiting for it
+func (s *NodeSyncStatus) hasFailed() bool {
+	return s.ServiceState == "failed" || s.ServiceState == "inactive"
+}
+
+// getBootstrapTimeout returns the configured bootstrap wait timeout
+func getBootstrapTimeout(config *Config) time.Duration {
+	if config != nil && config.BootstrapTimeoutSeconds > 0 {
+		return time.Duration(config.BootstrapTimeoutSeconds) * time.Second
+	}
+	return defaultBootstrapTimeout
+}
+
+// getJoinTimeout returns the configured joiner wait timeout
+func getJoinTimeout(config *Config) time.Duration {
+	if config != nil && config.JoinTimeoutSeconds > 0 {
+		return time.Duration(config.JoinTimeoutSeconds) * time.Second
+	}
+	return defaultJoinTimeout
+}
+
+// getRecoveryMySQLCredentials returns the MySQL credentials used to query a node during recovery:
+// those that worked during the status check, or the first saved ones
+func getRecoveryMySQLCredentials(ip string, config *Config) *MySQLConnectionInfo {
+	if creds, ok := workingMySQLCredentials[ip]; ok {
+		return creds
+	}
+	return mysqlCredentialCandidates(ip, config, nil)[0]
+}
+
+// buildMySQLClientCommand builds the mysql client invocation for the given credentials
+func buildMySQLClientCommand(creds *MySQLConnectionInfo) string {
+	switch {
+	case creds == nil || creds.OptionFile:
+		return "mysql"
+	case creds.LoginPath != "":
+		// --login-path must be the first option
+		return "mysql --login-path=" + shellQuote(creds.LoginPath)
+	case creds.Username == "":
+		return "mysql"
+	case creds.Password != "":
+		return fmt.Sprintf("mysql -u %s -p'%s'", creds.Username, creds.Password)
+	}
+	return fmt.Sprintf("mysql -u %s", creds.Username)
+}
+
+// queryNodeSyncStatus reads the service state and wsrep status of a node
+func queryNodeSyncStatus(ip string, config *Config) *NodeSyncStatus {
+	status := &NodeSyncStatus{}
+
+	service := getNodeServiceBackend(ip, config)
+	output, _ := executeServiceCommandOnNode(ip, service, service.statusCommand(), config)
+	status.ServiceState = strings.TrimSpace(output)
+	if status.ServiceState == "" {
+		status.ServiceState = "inactive"
+	}
+
+	mysqlCmd := buildMySQLClientCommand(getRecoveryMySQLCredentials(ip, config))
+	query := fmt.Sprintf("%s -N -B -e \"SHOW GLOBAL STATUS WHERE Variable_name IN ('wsrep_local_state_comment','wsrep_cluster_size','wsrep_cluster_status');\" 2>&1", mysqlCmd)
+	output, err := executeCommandOnNode(ip, query, config)
+	if err == nil && !strings.Contains(output, "ERROR") {
+		for _, line := range strings.Split(output, "\n") {
+			parts := strings.Fields(line)
+			if len(parts) < 2 {
+				continue
+			}
+			switch parts[0] {
+			case "wsrep_local_state_comment":
+				status.LocalStateComment = strings.Join(parts[1:], " ")
+				status.MySQLResponding = true
+			case "wsrep_cluster_size":
+				if size, err := strconv.Atoi(parts[1]); err == nil {
+					status.ClusterSize = size
+				}
+			case "wsrep_cluster_status":
+				status.ClusterStatus = parts[1]
+			}
+		}
+	}
+
+	return status
+}
+
+// getDataDirSize returns the size in bytes of the MySQL data directory on a node
+func getDataDirSize(ip string, config *Config) int64 {
+	executor, err := getNodeExecutor(ip, config)
+	if err != nil {
+		return 0
+	}
+	size, err := measureDataSize(executor, defaultDataDir)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// getRecentServiceErrors returns the last error lines logged by the MySQL/MariaDB service
+func getRecentServiceErrors(ip string, config *Config) string {
+	service := getNodeServiceBackend(ip, config)
+	cmd := service.logCommand("30 min ago", 200) + " | grep -iE 'error|abort|fail' | tail -5"
+	output, _ := executeServiceCommandOnNode(ip, service, cmd, config)
+	return strings.TrimSpace(output)
+}
+
+// waitForNodeSynced polls a node until it is Synced and the cluster has reached the expected size.
+// While the node receives a state transfer, SST/IST progress is reported based on datadir growth
+// compared to donorIP's datadir (if known).
+func waitForNodeSynced(ip string, expectedSize int, timeout time.Duration, donorIP string, config *Config) (*NodeSyncStatus, error) {
+	logReport("⏳ Waiting up to %s for node %s to reach Synced (expected cluster size >= %d)...", timeout, ip, expectedSize)
+
+	var donorSize int64
+	if donorIP != "" {
+		donorSize = getDataDirSize(donorIP, config)
+	}
+
+	start := time.Now()
+	deadline := start.Add(timeout)
+	lastState := ""
+	var status *NodeSyncStatus
+
+	for {
+		// A join can outlast the lock TTL: keep the recovery lock while waiting
+		renewHeldClusterLock(config)
+		status = queryNodeSyncStatus(ip, config)
+
+		if status.isSynced(expectedSize) {
+			logReport("✅ Node %s is Synced (cluster size: %d, status: %s) after %s",
+				ip, status.ClusterSize, status.ClusterStatus, time.Since(start).Round(time.Second))
+			return status, nil
+		}
+
+		if status.hasFailed() {
+			return status, fmt.Errorf("service on node %s is %s", ip, status.ServiceState)
+		}
+
+		currentState := fmt.Sprintf("%s/%s/%d", status.ServiceState, status.LocalStateComment, status.ClusterSize)
+		if status.isTransferring() {
+			status.DataDirBytes = getDataDirSize(ip, config)
+			elapsed := time.Since(start).Round(time.Second)
+			if donorSize > 0 {
+				percent := float64(status.DataDirBytes) * 100 / float64(donorSize)
+				if percent > 100 {
+					percent = 100
+				}
+				logReport("   🔄 Node %s: state transfer in progress (%s) - %s / %s (%.0f%%), elapsed %s",
+					ip, describeTransferState(status), formatBytes(status.DataDirBytes), formatBytes(donorSize), percent, elapsed)
+			} else {
+				logReport("   🔄 Node %s: state transfer in progress (%s) - datadir %s, elapsed %s",
+					ip, describeTransferState(status), formatBytes(status.DataDirBytes), elapsed)
+			}
+		} else if currentState != lastState {
+			logNormal("   Node %s: service=%s state=%s cluster_size=%d status=%s",
+				ip, status.ServiceState, status.LocalStateComment, status.ClusterSize, status.ClusterStatus)
+		}
+		lastState = currentState
+
+		if time.Now().After(deadline) {
+			return status, fmt.Errorf("timed out after %s waiting for node %s to reach Synced (last state: %s, cluster size: %d)",
+				timeout, ip, describeTransferState(status), status.ClusterSize)
+		}
+
+		time.Sleep(syncPollInterval)
+	}
+}
+
+// describeTransferState returns a human readable description of the node's sync state
+func describeTransferState(status *NodeSyncStatus) string {
+	if status.LocalStateComment != "" {
+		return status.LocalStateComment
+	}
+	if status.ServiceState == "activating" {
+		return "service starting, SST likely running"
+	}
+	return "service " + status.ServiceState
+}
+
+// formatBytes formats a byte count using binary units
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// reportJoinFailure prints a clear report when a node fails to join during recovery
+func reportJoinFailure(ip string, status *NodeSyncStatus, err error, completed, pending []string, config *Config) {
+	logReport("")
+	logReport("❌ RECOVERY STOPPED: node %s did not reach Synced", ip)
+	logReport("   Reason: %v", err)
+	if status != nil {
+		logReport("   Last observed: service=%s state=%s cluster_size=%d status=%s",
+			status.ServiceState, status.LocalStateComment, status.ClusterSize, status.ClusterStatus)
+	}
+	if errors := getRecentServiceErrors(ip, config); errors != "" {
+		logReport("   Recent service errors on %s:", ip)
+		for _, line := range strings.Split(errors, "\n") {
+			logReport("      %s", line)
+		}
+	}
+	if len(completed) > 0 {
+		logReport("   Nodes recovered and Synced: %s", strings.Join(completed, ", "))
+	}
+	if len(pending) > 0 {
+		logReport("   Nodes NOT started: %s", strings.Join(pending, ", "))
+	}
+	logReport("   Fix the problem on %s before starting the remaining nodes.", ip)
+}
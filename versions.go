@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// packageQuery lists the installed server and Galera packages with dpkg, or rpm
+const packageQuery = `(dpkg-query -W -f='${Package} ${Version}\n' 2>/dev/null || rpm -qa --qf '%{NAME} %{VERSION}-%{RELEASE}\n' 2>/dev/null) | ` +
+	`grep -Ei '^(mariadb-server|galera|percona-xtradb-cluster-server|mysql-wsrep)' | sort`
+
+// Major releases in upgrade order. Rolling upgrades are supported between consecutive entries of a
+// list; MariaDB also supports going from one long-term release to the next.
+var (
+	mariaDBReleases    = []string{"10.0", "10.1", "10.2", "10.3", "10.4", "10.5", "10.6", "10.7", "10.8", "10.9", "10.10", "10.11", "11.0", "11.1", "11.2", "11.3", "11.4", "11.5", "11.6", "11.7", "11.8"}
+	mariaDBLTSReleases = []string{"10.2", "10.3", "10.4", "10.5", "10.6", "10.11", "11.4", "11.8"}
+	mysqlReleases      = []string{"5.5", "5.6", "5.7", "8.0", "8.4"}
+)
+
+// NodeVersions are the server, provider, OS and package versions of a node
+type NodeVersions struct {
+	Server   string   `json:"server,omitempty"`   // version
+	Comment  string   `json:"comment,omitempty"`  // version_comment
+	Provider string   `json:"provider,omitempty"` // wsrep_provider_version
+	Protocol string   `json:"protocol,omitempty"` // wsrep_protocol_version
+	OS       string   `json:"os,omitempty"`       // PRETTY_NAME from /etc/os-release
+	Packages []string `json:"packages,omitempty"` // Installed server and Galera packages ("name version")
+}
+
+// VersionMismatch is a component whose version differs between nodes
+type VersionMismatch struct {
+	Component string // "Server flavor", "Server version", ...
+	Values    string // "10.0.0.1,10.0.0.2: 10.6.16; 10.0.0.3: 10.11.6"
+	Supported bool   // Whether the mix is a supported rolling-upgrade state
+	Guidance  string
+}
+
+// collectNodeVersions reads the versions of a node. The server and provider versions are queried
+// with the credentials that worked during discovery, without prompting.
+func collectNodeVersions(executor NodeExecutor, nodeIP string) *NodeVersions {
+	versions := &NodeVersions{}
+
+	if creds, ok := workingMySQLCredentials[nodeIP]; ok {
+		query := "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('version', 'version_comment'); " +
+			"SHOW GLOBAL STATUS WHERE Variable_name IN ('wsrep_provider_version', 'wsrep_protocol_version');"
+		output, err := runCommand(executor, fmt.Sprintf("%s -N -e \"%s\" 2>&1", buildMySQLClientCommand(creds), query))
+		if err == nil && !strings.Contains(output, "ERROR") {
+			for _, line := range strings.Split(output, "\n") {
+				parts := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+				if len(parts) < 2 {
+					continue
+				}
+				switch parts[0] {
+				case "version":
+					versions.Server = parts[1]
+				case "version_comment":
+					versions.Comment = parts[1]
+				case "wsrep_provider_version":
+					versions.Provider = parts[1]
+				case "wsrep_protocol_version":
+					versions.Protocol = parts[1]
+				}
+			}
+		}
+	}
+
+	if output, err := runCommand(executor, "cat /etc/os-release"); err == nil {
+		for _, line := range strings.Split(output, "\n") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(line), "PRETTY_NAME="); ok {
+				versions.OS = strings.Trim(value, `"'`)
+			}
+		}
+	}
+	if output, err := runCommand(executor, packageQuery); err == nil {
+		for _, line := range strings.Split(output, "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 {
+				versions.Packages = append(versions.Packages, fields[0]+" "+fields[1])
+			}
+		}
+	}
+
+	logVerbose("      📦 Versions of %s: %s", nodeIP, versions.describe())
+	return versions
+}
+
+// flavor returns the server flavor: MariaDB, Percona XtraDB Cluster or MySQL
+func (v *NodeVersions) flavor() string {
+	switch {
+	case v.Server == "":
+		return ""
+	case strings.Contains(v.Server, "MariaDB"):
+		return "MariaDB"
+	case strings.Contains(v.Comment, "Percona XtraDB Cluster"):
+		return "Percona XtraDB Cluster"
+	}
+	return "MySQL"
+}
+
+// serverNumber returns the numeric server version ("10.6.16" for "10.6.16-MariaDB-log")
+func (v *NodeVersions) serverNumber() string {
+	number, _, _ := strings.Cut(v.Server, "-")
+	return number
+}
+
+// release returns the major release of the server ("10.6")
+func (v *NodeVersions) release() string {
+	parts := strings.Split(v.serverNumber(), ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// providerNumber returns the provider version without its revision ("26.4.16" for "26.4.16(r7dce5149)")
+func (v *NodeVersions) providerNumber() string {
+	number, _, _ := strings.Cut(v.Provider, "(")
+	return strings.TrimSpace(number)
+}
+
+// galeraMajor returns the Galera major version from the provider version (4 for "26.4.16"), or 0
+func (v *NodeVersions) galeraMajor() int {
+	parts := strings.Split(v.providerNumber(), ".")
+	if len(parts) < 2 {
+		return 0
+	}
+	major, _ := strconv.Atoi(parts[1])
+	return major
+}
+
+// describe returns the versions for display
+func (v *NodeVersions) describe() string {
+	var parts []string
+	if v.Server != "" {
+		parts = append(parts, fmt.Sprintf("%s %s", v.flavor(), v.serverNumber()))
+	}
+	if v.Provider != "" {
+		parts = append(parts, "Galera "+v.providerNumber())
+	}
+	if v.Protocol != "" {
+		parts = append(parts, "protocol "+v.Protocol)
+	}
+	if len(parts) == 0 {
+		return "unknown (MySQL could not be queried with saved credentials)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// releasesAdjacent reports whether a rolling upgrade between two major releases is supported
+func releasesAdjacent(flavor, a, b string) bool {
+	lists := [][]string{mysqlReleases}
+	if flavor == "MariaDB" {
+		lists = [][]string{mariaDBReleases, mariaDBLTSReleases}
+	}
+	for _, list := range lists {
+		ia, ib := -1, -1
+		for i, release := range list {
+			if release == a {
+				ia = i
+			}
+			if release == b {
+				ib = i
+			}
+		}
+		if ia >= 0 && ib >= 0 && (ia-ib == 1 || ib-ia == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseBefore reports whether release a ("10.6") is older than release b ("10.11")
+func releaseBefore(a, b string) bool {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			return na < nb
+		}
+	}
+	return len(pa) < len(pb)
+}
+
+// groupVersions groups the nodes by the value returned for them, ignoring empty values; the groups
+// are formatted as "ip,ip: value; ip: value"
+func groupVersions(nodes []*GaleraClusterInfo, value func(*NodeVersions) string) ([]string, string) {
+	var values []string
+	members := make(map[string][]string)
+	for _, node := range nodes {
+		if node.Versions == nil {
+			continue
+		}
+		v := value(node.Versions)
+		if v == "" {
+			continue
+		}
+		if _, ok := members[v]; !ok {
+			values = append(values, v)
+		}
+		members[v] = append(members[v], node.NodeIP)
+	}
+	var parts []string
+	for _, v := range values {
+		parts = append(parts, fmt.Sprintf("%s: %s", strings.Join(members[v], ","), v))
+	}
+	return values, strings.Join(parts, "; ")
+}
+
+// compareVersions returns the components whose versions differ between the nodes, with guidance on
+// whether the mix is a supported rolling-upgrade state
+func compareVersions(nodes []*GaleraClusterInfo) []VersionMismatch {
+	var mismatches []VersionMismatch
+
+	flavors, flavorValues := groupVersions(nodes, (*NodeVersions).flavor)
+	if len(flavors) > 1 {
+		return append(mismatches, VersionMismatch{Component: "Server flavor", Values: flavorValues,
+			Guidance: "replication between different server flavors is not supported; migrate by taking nodes out of the cluster one at a time"})
+	}
+	flavor := ""
+	if len(flavors) == 1 {
+		flavor = flavors[0]
+	}
+
+	serverUpgrade := false
+	servers, values := groupVersions(nodes, (*NodeVersions).serverNumber)
+	if len(servers) > 1 {
+		mismatch := VersionMismatch{Component: "Server version", Values: values}
+		releases, _ := groupVersions(nodes, (*NodeVersions).release)
+		sort.Slice(releases, func(i, j int) bool { return releaseBefore(releases[i], releases[j]) })
+		switch {
+		case len(releases) == 1:
+			mismatch.Supported = true
+			mismatch.Guidance = "minor releases of the same series can be mixed during a rolling upgrade; upgrade the remaining nodes"
+		case len(releases) == 2 && releasesAdjacent(flavor, releases[0], releases[1]):
+			mismatch.Supported = true
+			serverUpgrade = true
+			mismatch.Guidance = fmt.Sprintf("a rolling upgrade from %s to %s is supported; avoid DDL and finish upgrading every node soon", releases[0], releases[1])
+		default:
+			mismatch.Guidance = fmt.Sprintf("releases %s can't be mixed; upgrade one major release at a time", strings.Join(releases, ", "))
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+
+	providerMismatch := false
+	if providers, values := groupVersions(nodes, (*NodeVersions).providerNumber); len(providers) > 1 {
+		providerMismatch = true
+		mismatch := VersionMismatch{Component: "Galera provider", Values: values, Supported: true,
+			Guidance: "provider versions of the same Galera major version are compatible; upgrade the remaining nodes"}
+		majors, _ := groupVersions(nodes, func(v *NodeVersions) string {
+			if major := v.galeraMajor(); major > 0 {
+				return strconv.Itoa(major)
+			}
+			return ""
+		})
+		if len(majors) > 1 {
+			mismatch.Supported = serverUpgrade
+			mismatch.Guidance = "Galera 3 and 4 only mix while upgrading the server to the release that ships Galera 4 (MariaDB 10.4, Percona XtraDB Cluster 8.0)"
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+
+	if protocols, values := groupVersions(nodes, func(v *NodeVersions) string { return v.Protocol }); len(protocols) > 1 {
+		mismatches = append(mismatches, VersionMismatch{Component: "wsrep protocol", Values: values,
+			Guidance: "the members of a cluster negotiate one protocol version; the nodes are not in the same Primary component or one has not finished joining"})
+	}
+
+	if systems, values := groupVersions(nodes, func(v *NodeVersions) string { return v.OS }); len(systems) > 1 {
+		mismatches = append(mismatches, VersionMismatch{Component: "Operating system", Values: values, Supported: true,
+			Guidance: "mixed OS releases work while the server and provider versions match; keep them aligned to run the same package builds"})
+	}
+
+	// Package builds only matter when the versions reported by the server agree
+	if len(servers) <= 1 && !providerMismatch {
+		if packages, values := groupVersions(nodes, func(v *NodeVersions) string { return strings.Join(v.Packages, ", ") }); len(packages) > 1 {
+			mismatches = append(mismatches, VersionMismatch{Component: "Packages", Values: values, Supported: true,
+				Guidance: "package builds differ; check for pending package upgrades"})
+		}
+	}
+	return mismatches
+}
+
+// String formats the mismatch for the health summary
+func (m VersionMismatch) String() string {
+	state := "supported during a rolling upgrade"
+	if !m.Supported {
+		state = "NOT a supported combination"
+	}
+	return fmt.Sprintf("%s differs between nodes (%s): %s - %s", m.Component, m.Values, state, m.Guidance)
+}
+
+// displayVersions prints the version mismatches between nodes
+func displayVersions(nodes []*GaleraClusterInfo) {
+	mismatches := compareVersions(nodes)
+	if len(mismatches) == 0 {
+		fmt.Println("📦 Versions: consistent across nodes")
+		return
+	}
+	fmt.Println("📦 Versions:")
+	for _, mismatch := range mismatches {
+		icon := "⚠️ "
+		if !mismatch.Supported {
+			icon = "❌"
+		}
+		fmt.Printf("   %s %s differs: %s\n", icon, mismatch.Component, mismatch.Values)
+		fmt.Printf("      %s\n", mismatch.Guidance)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	nodes := func(versions ...NodeVersions) []*GaleraClusterInfo {
+		var nodes []*GaleraClusterInfo
+		for i := range versions {
+			nodes = append(nodes, &GaleraClusterInfo{NodeIP: "10.0.0." + string(rune('1'+i)), Versions: &versions[i]})
+		}
+		return nodes
+	}
+	mariadb := func(server, provider string) NodeVersions {
+		return NodeVersions{Server: server + "-MariaDB-log", Provider: provider, Protocol: "10"}
+	}
+
+	tests := []struct {
+		name  string
+		nodes []*GaleraClusterInfo
+		want  []string
+	}{
+		{"consistent", nodes(mariadb("10.6.16", "26.4.16(r1)"), mariadb("10.6.16", "26.4.16(r1)")), nil},
+		{"minor releases", nodes(mariadb("10.6.16", "26.4.16(r1)"), mariadb("10.6.18", "26.4.16(r1)")),
+			[]string{"Server version differs between nodes (10.0.0.1: 10.6.16; 10.0.0.2: 10.6.18): supported during a rolling upgrade"}},
+		{"long-term releases", nodes(mariadb("10.6.16", "26.4.16(r1)"), mariadb("10.11.6", "26.4.16(r1)")),
+			[]string{"Server version differs between nodes (10.0.0.1: 10.6.16; 10.0.0.2: 10.11.6): supported during a rolling upgrade - a rolling upgrade from 10.6 to 10.11"}},
+		{"skipped release", nodes(mariadb("10.4.32", "26.4.16(r1)"), mariadb("10.11.6", "26.4.16(r1)")),
+			[]string{"Server version differs between nodes (10.0.0.1: 10.4.32; 10.0.0.2: 10.11.6): NOT a supported combination"}},
+		{"galera 3 to 4 upgrade", nodes(mariadb("10.3.39", "25.3.37(r1)"), mariadb("10.4.32", "26.4.16(r1)")),
+			[]string{"Server version differs", "Galera provider differs between nodes (10.0.0.1: 25.3.37; 10.0.0.2: 26.4.16): supported during a rolling upgrade"}},
+		{"galera 3 and 4 without upgrade", nodes(mariadb("10.4.32", "25.3.37(r1)"), mariadb("10.4.32", "26.4.16(r1)")),
+			[]string{"Galera provider differs between nodes (10.0.0.1: 25.3.37; 10.0.0.2: 26.4.16): NOT a supported combination"}},
+		{"flavors", nodes(mariadb("10.6.16", "26.4.16(r1)"), NodeVersions{Server: "8.0.35-27", Comment: "Percona XtraDB Cluster (GPL)"}),
+			[]string{"Server flavor differs between nodes (10.0.0.1: MariaDB; 10.0.0.2: Percona XtraDB Cluster): NOT a supported combination"}},
+		{"packages", nodes(NodeVersions{Packages: []string{"galera-4 26.4.16"}}, NodeVersions{Packages: []string{"galera-4 26.4.18"}}),
+			[]string{"Packages differs between nodes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, mismatch := range compareVersions(tt.nodes) {
+				got = append(got, mismatch.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("mismatches = %q, want %d", got, len(tt.want))
+			}
+			assertErrors(t, got, tt.want)
+		})
+	}
+}
+
+func TestVersionsInAnalysis(t *testing.T) {
+	members := []string{"10.0.0.1", "10.0.0.2"}
+	node := func(ip string) *fakeNode {
+		return &fakeNode{IP: ip, Service: "active", Synced: true,
+			Files: map[string]string{
+				"/etc/mysql/my.cnf": galeraConfig("prod", ip, members...),
+				"/etc/os-release":   "NAME=\"Ubuntu\"\nPRETTY_NAME=\"Ubuntu 22.04.4 LTS\"\n",
+			}}
+	}
+	upgraded := node("10.0.0.2")
+	upgraded.Version = "10.11.6-MariaDB-log"
+	upgraded.Packages = "galera-4 26.4.16-ubu2204\nmariadb-server 1:10.11.6+maria~ubu2204\n"
+	newFakeCluster(t, node("10.0.0.1"), upgraded)
+	config := newTestConfig(t)
+
+	executor, err := getNodeExecutor("10.0.0.1", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialNode, err := getGaleraClusterInfo(executor, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, _, err := performClusterAnalysis(initialNode, &SSHConnectionInfo{Username: "root", UsedKeys: true}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions := analysis.AllNodes[1].Versions
+	if versions == nil || versions.describe() != "MariaDB 10.11.6, Galera 26.4.16, protocol 10" || versions.OS != "Ubuntu 22.04.4 LTS" || len(versions.Packages) != 2 {
+		t.Fatalf("versions of 10.0.0.2 = %+v", versions)
+	}
+	health := evaluateClusterHealth(analysis)
+	assertErrors(t, health.Warnings, []string{"Server version differs between nodes (10.0.0.1: 10.6.16; 10.0.0.2: 10.11.6)"})
+	for _, warning := range health.Warnings {
+		if strings.HasPrefix(warning, "Packages") {
+			t.Errorf("unexpected warning: %s", warning)
+		}
+	}
+	if len(health.Issues) != 0 {
+		t.Errorf("supported upgrade reported as issues: %q", health.Issues)
+	}
+
+	// A release skipped during the upgrade is an issue
+	analysis.AllNodes[1].Versions.Server = "11.4.2-MariaDB"
+	health = evaluateClusterHealth(analysis)
+	assertErrors(t, health.Issues, []string{"Server version differs between nodes (10.0.0.1: 10.6.16; 10.0.0.2: 11.4.2): NOT a supported combination"})
+	for _, warning := range health.Warnings {
+		if strings.HasPrefix(warning, "Server version") {
+			t.Errorf("unsupported mismatch reported as a warning: %s", warning)
+		}
+	}
+}